@@ -0,0 +1,42 @@
+// Command fakeproviders serves stand-ins for the agify, genderize and
+// nationalize APIs from a fixtures file, so that the service can be run and
+// tested without network access. Point the service at it with
+//
+//	AGIFY_URL=http://localhost:8081/agify
+//	GENDERIZE_URL=http://localhost:8081/genderize
+//	NATIONALIZE_URL=http://localhost:8081/nationalize
+//
+// See the enrichtest package for the format of the fixtures.
+package main
+
+import (
+	"NameEnricher/internal/enrich/enrichtest"
+	"NameEnricher/pkg/logger"
+	"flag"
+	"net/http"
+	"strings"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "address to listen on")
+	fixturesPath := flag.String("fixtures", "cmd/fakeproviders/fixtures.json", "path of the fixtures file")
+	flag.Parse()
+
+	fixtures, err := enrichtest.LoadFixtures(*fixturesPath)
+	if err != nil {
+		logger.Log.WithError(err).Fatal("Failed to load fixtures")
+	}
+
+	host := *addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	logger.Log.Infof("Serving fixtures from %s on %s", *fixturesPath, *addr)
+	for _, provider := range []string{enrichtest.Agify, enrichtest.Genderize, enrichtest.Nationalize} {
+		logger.Log.Infof("%s_URL=http://%s/%s", strings.ToUpper(provider), host, provider)
+	}
+
+	if err := http.ListenAndServe(*addr, enrichtest.NewHandler(fixtures)); err != nil {
+		logger.Log.WithError(err).Fatal("Server failed")
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	_ "NameEnricher/docs"
+	"NameEnricher/internal/enrich"
 	"NameEnricher/internal/handlers"
+	"NameEnricher/internal/models"
+	"NameEnricher/internal/worker"
 	"NameEnricher/pkg/logger"
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/gin-gonic/gin"
@@ -13,9 +17,17 @@ import (
 	"github.com/joho/godotenv"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take after a stop signal.
+const shutdownTimeout = 30 * time.Second
+
 // @title           Name Enricher API
 // @version         1.0
 // @description     API for enriching names
@@ -42,6 +54,64 @@ func main() {
 	}
 	logger.Log.Info("Database migrated")
 
+	if filled, err := models.FillPersonNames(db, context.Background(), 500); err != nil {
+		logger.Log.WithError(err).Fatal("Failed to compute person name keys and variants")
+	} else if filled > 0 {
+		logger.Log.Infof("Computed name keys and variants of %d persons", filled)
+	}
+
+	enrichConfig, err := enrich.LoadConfig()
+	if err != nil {
+		logger.Log.WithError(err).Fatal("Invalid enrichment provider configuration")
+	}
+	var cache enrich.Cache
+	if enrichConfig.CacheTTL > 0 {
+		cache = enrich.NewDBCache(db, enrichConfig.CacheTTL)
+	}
+	enrichConfig.Cache = cache
+	providers, err := enrich.NewProviders(enrichConfig)
+	if err != nil {
+		logger.Log.WithError(err).Fatal("Failed to set up enrichment providers")
+	}
+	enricher := enrich.NewEnricher(providers, cache)
+	enricher.Rules = enrich.NewRules(enrichConfig)
+	enricher.StripDiacritics = enrichConfig.StripDiacritics
+	enricher.Transliterate = enrichConfig.Transliterate
+	if enrichConfig.ResolveAliases {
+		enricher.Aliases = enrich.NewDBAliases(db)
+	}
+
+	workerConfig, err := worker.LoadConfig()
+	if err != nil {
+		logger.Log.WithError(err).Fatal("Invalid worker configuration")
+	}
+	jobWorker := worker.New(db, workerConfig)
+	jobWorker.HandleBatch(models.JobKindReEnrich, handlers.ReEnrichPersonsJob(db, enricher))
+	jobWorker.HandleBatch(models.JobKindCreatePerson, handlers.CreatePersonsJob(db, enricher))
+	jobWorker.Every("enqueue re-enrichment", workerConfig.SweepInterval, func(ctx context.Context) error {
+		var staleBefore time.Time
+		if workerConfig.StaleAfter > 0 {
+			staleBefore = time.Now().Add(-workerConfig.StaleAfter)
+		}
+		count, err := models.EnqueueReEnrichmentJobs(db, ctx, staleBefore, time.Now().Add(-workerConfig.RetryFailedAfter))
+		if count > 0 {
+			logger.Log.Infof("Queued %d persons for re-enrichment", count)
+		}
+		return err
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+	if workerConfig.Concurrency > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jobWorker.Run(ctx)
+		}()
+	}
+
 	router := gin.New()
 	router.Use(gin.LoggerWithWriter(logger.Log.Writer()), gin.Recovery())
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -58,19 +128,57 @@ func main() {
 	nationalitiesRouter.PUT("/:id", handlers.UpdateNationalityHandler(db))
 	nationalitiesRouter.DELETE("/:id", handlers.DeleteNationalityHandler(db))
 
+	nameAliasesRouter := router.Group("/name-aliases")
+	nameAliasesRouter.GET("", handlers.GetNameAliasesHandler(db))
+	nameAliasesRouter.POST("", handlers.CreateNameAliasHandler(db))
+	nameAliasesRouter.PUT("/:id", handlers.UpdateNameAliasHandler(db))
+	nameAliasesRouter.DELETE("/:id", handlers.DeleteNameAliasHandler(db))
+
 	personsRouter := router.Group("/persons")
 	personsRouter.GET("", handlers.GetPersonsHandler(db))
-	personsRouter.POST("", handlers.CreatePersonHandler(db))
+	personsRouter.GET("/:id", handlers.GetPersonHandler(db))
+	personsRouter.POST("", handlers.CreatePersonHandler(db, enricher, enrichConfig.AllowPartial))
+	personsRouter.POST("/parse", handlers.ParsePersonNameHandler())
 	personsRouter.PUT("/:id", handlers.UpdatePersonHandler(db))
 	personsRouter.PATCH("/:id", handlers.PatchPersonHandler(db))
 	personsRouter.DELETE("/:id", handlers.DeletePersonHandler(db))
+	router.POST("/persons:method", handlers.CustomMethodsHandler("method", map[string]gin.HandlerFunc{
+		":async": handlers.CreatePersonAsyncHandler(db, enrichConfig.AllowPartial),
+	}))
+
+	jobsRouter := router.Group("/jobs")
+	jobsRouter.GET("/:id", handlers.GetJobHandler(db))
+
+	adminRouter := router.Group("/admin")
+	adminRouter.DELETE("/enrichment-cache", handlers.PurgeEnrichmentCacheHandler(db))
+	adminRouter.GET("/providers", handlers.GetProvidersHandler(enricher))
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	logger.Log.Infof("Server running on port %s", port)
-	router.Run(":" + port)
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		logger.Log.Infof("Server running on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.WithError(err).Fatal("Server failed")
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Log.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Log.WithError(err).Error("Failed to shut down server gracefully")
+	}
+	wg.Wait()
+
+	if err := db.Close(); err != nil {
+		logger.Log.WithError(err).Error("Failed to close database")
+	}
+	logger.Log.Info("Server stopped")
 }
 
 func runMigrations(db *sql.DB) error {
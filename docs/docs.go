@@ -15,6 +15,84 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/enrichment-cache": {
+            "delete": {
+                "description": "Delete cached age, gender and nationality results so that the next request for a name calls the providers again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Purge the enrichment cache",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only delete entries whose TTL has passed",
+                        "name": "expired_only",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of purged entries",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expired_only value",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/providers": {
+            "get": {
+                "description": "Get the circuit breaker state of every enrichment provider. An open circuit fails requests without calling the provider until retry_at.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get provider health",
+                "responses": {
+                    "200": {
+                        "description": "Circuit breaker state per provider",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/enrich.BreakerStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/genders": {
             "get": {
                 "description": "Get a list of genders with optional filtering",
@@ -233,9 +311,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/nationalities": {
+        "/jobs/{id}": {
             "get": {
-                "description": "Get a list of nationalities with optional filtering",
+                "description": "Get the status of a background job. Once a create job is done, the created person is included.",
                 "consumes": [
                     "application/json"
                 ],
@@ -243,20 +321,85 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "nationalities"
+                    "jobs"
                 ],
-                "summary": "List nationalities",
+                "summary": "Get a job",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Nationality ID",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved job",
+                        "schema": {
+                            "$ref": "#/definitions/models.JobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request - Bad ID format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found - The specified ID does not exist",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - Database connection issues or query problems",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/name-aliases": {
+            "get": {
+                "description": "Get a list of name aliases with optional filtering",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "name-aliases"
+                ],
+                "summary": "List name aliases",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Alias ID",
                         "name": "id",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Nationality name",
-                        "name": "name",
+                        "description": "Part of the alias, ignoring case",
+                        "name": "alias",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Canonical name, ignoring case",
+                        "name": "canonical",
                         "in": "query"
                     },
                     {
@@ -274,11 +417,11 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully retrieved nationality list",
+                        "description": "Successfully retrieved alias list",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Nationality"
+                                "$ref": "#/definitions/models.NameAlias"
                             }
                         }
                     },
@@ -294,7 +437,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new nationality entry in the database",
+                "description": "Map a variant of a given name, such as the diminutive \"Sasha\", to its canonical form \"Aleksandr\"",
                 "consumes": [
                     "application/json"
                 ],
@@ -302,25 +445,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "nationalities"
+                    "name-aliases"
                 ],
-                "summary": "Create a new nationality",
+                "summary": "Create a new name alias",
                 "parameters": [
                     {
-                        "description": "Nationality data (name is required for enrichment)",
-                        "name": "nationality",
+                        "description": "Alias and its canonical name",
+                        "name": "alias",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.NationalityCreateRequest"
+                            "$ref": "#/definitions/models.NameAliasCreateRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successfully created nationality",
+                        "description": "Successfully created alias",
                         "schema": {
-                            "$ref": "#/definitions/models.Nationality"
+                            "$ref": "#/definitions/models.NameAlias"
                         }
                     },
                     "400": {
@@ -333,7 +476,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal server error - e.g. the alias already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -344,9 +487,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/nationalities/{id}": {
+        "/name-aliases/{id}": {
             "put": {
-                "description": "Update an existing nationality by ID",
+                "description": "Update an existing name alias by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -354,32 +497,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "nationalities"
+                    "name-aliases"
                 ],
-                "summary": "Update a nationality",
+                "summary": "Update a name alias",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Nationality ID",
+                        "description": "Alias ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Nationality update data",
-                        "name": "nationality",
+                        "description": "Alias update data",
+                        "name": "alias",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.PatchNationality"
+                            "$ref": "#/definitions/models.PatchNameAlias"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully updated nationality",
+                        "description": "Successfully updated alias",
                         "schema": {
-                            "$ref": "#/definitions/models.Nationality"
+                            "$ref": "#/definitions/models.NameAlias"
                         }
                     },
                     "400": {
@@ -403,7 +546,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a nationality by its ID",
+                "description": "Delete a name alias by its ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -411,13 +554,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "nationalities"
+                    "name-aliases"
                 ],
-                "summary": "Delete a nationality",
+                "summary": "Delete a name alias",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Nationality ID",
+                        "description": "Alias ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -425,9 +568,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully deleted nationality",
+                        "description": "Successfully deleted alias",
                         "schema": {
-                            "$ref": "#/definitions/models.Nationality"
+                            "$ref": "#/definitions/models.NameAlias"
                         }
                     },
                     "400": {
@@ -451,9 +594,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/persons": {
+        "/nationalities": {
             "get": {
-                "description": "Get a list of persons with optional filtering",
+                "description": "Get a list of nationalities with optional filtering",
                 "consumes": [
                     "application/json"
                 ],
@@ -461,77 +604,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "persons"
+                    "nationalities"
                 ],
-                "summary": "List persons",
+                "summary": "List nationalities",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Person ID",
+                        "description": "Nationality ID",
                         "name": "id",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Person name",
+                        "description": "Nationality name",
                         "name": "name",
                         "in": "query"
                     },
-                    {
-                        "type": "string",
-                        "description": "Person surname",
-                        "name": "surname",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Minimum age",
-                        "name": "age_from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Maximum age",
-                        "name": "age_to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Gender ID",
-                        "name": "gender_id",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "description": "Nationality ID",
-                        "name": "nationality_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page",
-                        "name": "Page",
+                        "description": "Page number for pagination",
+                        "name": "page",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "LIMIT",
-                        "name": "Limit",
+                        "description": "Number of items per page",
+                        "name": "limit",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully retrieved person list",
+                        "description": "Successfully retrieved nationality list",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Person"
+                                "$ref": "#/definitions/models.Nationality"
                             }
                         }
                     },
                     "500": {
-                        "description": "Internal server error - Database connection issues or query problems",
+                        "description": "Internal server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -542,7 +655,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new person with automatic enrichment of age, gender, and nationality",
+                "description": "Create a new nationality entry in the database",
                 "consumes": [
                     "application/json"
                 ],
@@ -550,29 +663,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "persons"
+                    "nationalities"
                 ],
-                "summary": "Create a new person",
+                "summary": "Create a new nationality",
                 "parameters": [
                     {
-                        "description": "Person data (name is required for enrichment)",
-                        "name": "person",
+                        "description": "Nationality data (name is required for enrichment)",
+                        "name": "nationality",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.PersonCreateRequest"
+                            "$ref": "#/definitions/models.NationalityCreateRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successfully created person",
+                        "description": "Successfully created nationality",
                         "schema": {
-                            "$ref": "#/definitions/models.Person"
+                            "$ref": "#/definitions/models.Nationality"
                         }
                     },
                     "400": {
-                        "description": "Invalid request - Missing required fields or invalid data format",
+                        "description": "Invalid request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -581,7 +694,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal server error - External API failures, database errors, or enrichment failures",
+                        "description": "Internal server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -592,9 +705,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/persons/{id}": {
+        "/nationalities/{id}": {
             "put": {
-                "description": "Replace an existing person's data by ID",
+                "description": "Update an existing nationality by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -602,45 +715,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "persons"
+                    "nationalities"
                 ],
-                "summary": "Update a person completely",
+                "summary": "Update a nationality",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Person ID",
+                        "description": "Nationality ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Complete person data",
-                        "name": "person",
+                        "description": "Nationality update data",
+                        "name": "nationality",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.PersonPatch"
+                            "$ref": "#/definitions/models.PatchNationality"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully updated person",
+                        "description": "Successfully updated nationality",
                         "schema": {
-                            "$ref": "#/definitions/models.Person"
+                            "$ref": "#/definitions/models.Nationality"
                         }
                     },
                     "400": {
-                        "description": "Invalid request - Bad ID format, missing required fields, or invalid JSON format",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "404": {
-                        "description": "Person not found - The specified ID does not exist",
+                        "description": "Invalid request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -649,7 +753,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal server error - Database errors or foreign key violations",
+                        "description": "Internal server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -660,7 +764,435 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a person by their ID",
+                "description": "Delete a nationality by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nationalities"
+                ],
+                "summary": "Delete a nationality",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Nationality ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted nationality",
+                        "schema": {
+                            "$ref": "#/definitions/models.Nationality"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/persons": {
+            "get": {
+                "description": "Get a list of persons with optional filtering",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "List persons",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the person name, ignoring case and Unicode composition",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the person surname, ignoring case and Unicode composition",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Match name and surname in any transliteration, e.g. Dmitriy finds Дмитрий",
+                        "name": "transliterate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also match the aliases and canonical form of the name, e.g. Sasha finds Aleksandr",
+                        "name": "expand_aliases",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum age",
+                        "name": "age_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum age",
+                        "name": "age_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gender ID",
+                        "name": "gender_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Nationality ID",
+                        "name": "nationality_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum gender probability (0-1)",
+                        "name": "min_gender_probability",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum nationality probability (0-1)",
+                        "name": "min_nationality_probability",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page",
+                        "name": "Page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "LIMIT",
+                        "name": "Limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved person list",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Person"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - Database connection issues or query problems",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new person with automatic enrichment of age, gender, and nationality.\nWith partial=true a person is stored even when some providers fail; the failed fields are null with status pending.\nAn optional country_hint localizes the age and gender predictions to that country.\nInstead of name, surname and patronymic a full_name can be given; the parts parsed from it are returned in parsed_name.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Create a new person",
+                "parameters": [
+                    {
+                        "description": "Person data (name or full_name is required for enrichment)",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PersonCreateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Store the person even if some providers fail (defaults to ENRICH_ALLOW_PARTIAL)",
+                        "name": "partial",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created and fully enriched person",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "202": {
+                        "description": "Person created, some fields are pending enrichment (see enrichment_status)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request - Missing required fields or invalid data format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "A provider rejected the name (reason invalid_name)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - Database errors or unexpected enrichment failures",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "502": {
+                        "description": "A provider is unreachable or answered with an error (reason provider_unavailable, listed per field in failed_fields)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Provider quota exhausted (reason quota_exhausted) - retry after the number of seconds in the Retry-After header",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the provider quota resets"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/parse": {
+            "post": {
+                "description": "Split a full name into name, surname and patronymic without storing anything.\nSurname-first orders (\"Ivanov Ivan Petrovich\", \"Ivanov, Ivan\") are detected from commas, patronymic endings and typical surname endings.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Parse a full name",
+                "parameters": [
+                    {
+                        "description": "Full name",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PersonParseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Parsed name parts with the detected order and a confidence score",
+                        "schema": {
+                            "$ref": "#/definitions/models.ParsedName"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request - Invalid JSON format or empty full name",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/{id}": {
+            "get": {
+                "description": "Get a single person by ID, optionally with related data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Get a person",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "nationality_candidates",
+                            "provider_predictions"
+                        ],
+                        "type": "string",
+                        "description": "Comma-separated related data to include",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved person",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request - Bad ID format or unknown include value",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Person not found - The specified ID does not exist",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - Database connection issues or query problems",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace an existing person's data by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Update a person completely",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Complete person data",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PersonPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated person",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request - Bad ID format, missing required fields, or invalid JSON format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Person not found - The specified ID does not exist",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - Database errors or foreign key violations",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a person by their ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -782,9 +1314,125 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/persons:async": {
+            "post": {
+                "description": "Validate and queue the creation of a person; enrichment runs in the background.\nPoll the job from the Location header until it is done to get the created person.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Create a new person asynchronously",
+                "parameters": [
+                    {
+                        "description": "Person data (name is required for enrichment)",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PersonCreateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Store the person even if some providers fail (defaults to ENRICH_ALLOW_PARTIAL)",
+                        "name": "partial",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Creation queued",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "/jobs/{id}"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request - Missing required fields or invalid data format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - Database connection issues",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
+        "enrich.BreakerStatus": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "retry_at": {
+                    "description": "RetryAt is when an open circuit lets the next probe through.",
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/enrich.CircuitState"
+                }
+            }
+        },
+        "enrich.CircuitState": {
+            "type": "string",
+            "enum": [
+                "closed",
+                "open",
+                "half_open"
+            ],
+            "x-enum-varnames": [
+                "CircuitClosed",
+                "CircuitOpen",
+                "CircuitHalfOpen"
+            ]
+        },
+        "models.EnrichmentStatus": {
+            "type": "string",
+            "enum": [
+                "ok",
+                "unknown",
+                "pending",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "EnrichmentOK",
+                "EnrichmentUnknown",
+                "EnrichmentPending",
+                "EnrichmentFailed"
+            ]
+        },
         "models.Gender": {
             "type": "object",
             "properties": {
@@ -804,6 +1452,123 @@ const docTemplate = `{
                 }
             }
         },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "person_id": {
+                    "type": "integer"
+                },
+                "run_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.JobStatus"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "person": {
+                    "$ref": "#/definitions/models.Person"
+                },
+                "person_id": {
+                    "type": "integer"
+                },
+                "run_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.JobStatus"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobStatus": {
+            "type": "string",
+            "enum": [
+                "queued",
+                "running",
+                "done",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "JobQueued",
+                "JobRunning",
+                "JobDone",
+                "JobFailed"
+            ]
+        },
+        "models.NameAlias": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "Sasha"
+                },
+                "canonical": {
+                    "type": "string",
+                    "example": "Aleksandr"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NameAliasCreateRequest": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "Sasha"
+                },
+                "canonical": {
+                    "type": "string",
+                    "example": "Aleksandr"
+                }
+            }
+        },
         "models.Nationality": {
             "type": "object",
             "properties": {
@@ -815,6 +1580,20 @@ const docTemplate = `{
                 }
             }
         },
+        "models.NationalityCandidate": {
+            "type": "object",
+            "properties": {
+                "country_id": {
+                    "type": "string"
+                },
+                "probability": {
+                    "type": "number"
+                },
+                "rank": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.NationalityCreateRequest": {
             "type": "object",
             "properties": {
@@ -823,6 +1602,28 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ParsedName": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "type": "number",
+                    "example": 0.95
+                },
+                "name": {
+                    "type": "string"
+                },
+                "order": {
+                    "type": "string",
+                    "example": "surname_first"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                }
+            }
+        },
         "models.PatchGender": {
             "type": "object",
             "properties": {
@@ -831,6 +1632,17 @@ const docTemplate = `{
                 }
             }
         },
+        "models.PatchNameAlias": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "canonical": {
+                    "type": "string"
+                }
+            }
+        },
         "models.PatchNationality": {
             "type": "object",
             "properties": {
@@ -845,9 +1657,30 @@ const docTemplate = `{
                 "age": {
                     "type": "integer"
                 },
+                "age_count": {
+                    "type": "integer"
+                },
+                "country_hint": {
+                    "description": "CountryHint is the country the age and gender were localized to, as\ngiven when the person was created.",
+                    "type": "string"
+                },
+                "enrichment_source": {
+                    "description": "EnrichmentSource audits where every enriched value came from.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PersonEnrichmentSource"
+                        }
+                    ]
+                },
+                "enrichment_status": {
+                    "$ref": "#/definitions/models.PersonEnrichment"
+                },
                 "gender": {
                     "$ref": "#/definitions/models.Gender"
                 },
+                "gender_probability": {
+                    "type": "number"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -857,9 +1690,34 @@ const docTemplate = `{
                 "nationality": {
                     "$ref": "#/definitions/models.Nationality"
                 },
+                "nationality_candidates": {
+                    "description": "NationalityCandidates is only loaded on request, see GetPersonNationalityCandidates.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.NationalityCandidate"
+                    }
+                },
+                "nationality_probability": {
+                    "type": "number"
+                },
+                "parsed_name": {
+                    "description": "ParsedName is only set in creation responses for persons given as a full name.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ParsedName"
+                        }
+                    ]
+                },
                 "patronymic": {
                     "type": "string"
                 },
+                "provider_predictions": {
+                    "description": "ProviderPredictions is only loaded on request, see GetPersonProviderPredictions.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProviderPrediction"
+                    }
+                },
                 "surname": {
                     "type": "string"
                 }
@@ -868,6 +1726,16 @@ const docTemplate = `{
         "models.PersonCreateRequest": {
             "type": "object",
             "properties": {
+                "country_hint": {
+                    "description": "CountryHint is an ISO 3166-1 alpha-2 code used to localize the age and gender predictions.",
+                    "type": "string",
+                    "example": "US"
+                },
+                "full_name": {
+                    "description": "FullName replaces name, surname and patronymic, which are parsed from it.",
+                    "type": "string",
+                    "example": "Ivanov Ivan Petrovich"
+                },
                 "name": {
                     "type": "string"
                 },
@@ -879,6 +1747,43 @@ const docTemplate = `{
                 }
             }
         },
+        "models.PersonEnrichment": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "$ref": "#/definitions/models.EnrichmentStatus"
+                },
+                "gender": {
+                    "$ref": "#/definitions/models.EnrichmentStatus"
+                },
+                "nationality": {
+                    "$ref": "#/definitions/models.EnrichmentStatus"
+                }
+            }
+        },
+        "models.PersonEnrichmentSource": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "string"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PersonParseRequest": {
+            "type": "object",
+            "properties": {
+                "full_name": {
+                    "type": "string",
+                    "example": "Ivanov Ivan Petrovich"
+                }
+            }
+        },
         "models.PersonPatch": {
             "type": "object",
             "properties": {
@@ -901,6 +1806,26 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.ProviderPrediction": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "probability": {
+                    "type": "number"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
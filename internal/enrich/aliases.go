@@ -0,0 +1,26 @@
+package enrich
+
+import (
+	"NameEnricher/internal/models"
+	"context"
+	"database/sql"
+)
+
+// Aliases resolves variants of given names, such as "Sasha" or "Alex", to
+// their canonical form "Aleksandr", so that they share one enrichment.
+type Aliases interface {
+	Canonical(ctx context.Context, name string) (string, bool, error)
+}
+
+// DBAliases is an Aliases backed by the name_aliases table.
+type DBAliases struct {
+	DB *sql.DB
+}
+
+func NewDBAliases(db *sql.DB) *DBAliases {
+	return &DBAliases{DB: db}
+}
+
+func (a *DBAliases) Canonical(ctx context.Context, name string) (string, bool, error) {
+	return models.ResolveNameAlias(a.DB, ctx, name)
+}
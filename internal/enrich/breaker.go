@@ -0,0 +1,189 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a Breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen fails every request without calling the provider.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single probe request through after the cooldown;
+	// its outcome closes or reopens the circuit.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// Defaults used when no breaker settings are configured.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is the cause of the *ProviderError returned while a circuit is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Breaker is a circuit breaker for a single provider. It opens after
+// Threshold consecutive failures and then fails fast until Cooldown has
+// passed. Only errors matching ErrProviderUnavailable count as failures: a
+// rejected name or an exhausted quota means the provider is answering.
+//
+// A nil *Breaker allows every request. Breaker is safe for concurrent use.
+type Breaker struct {
+	Provider  string
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	lastError string
+	probing   bool
+	now       func() time.Time
+}
+
+func NewBreaker(provider string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Provider:  provider,
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		state:     CircuitClosed,
+		now:       time.Now,
+	}
+}
+
+// BreakerStatus is a snapshot of a Breaker, as reported by GET /admin/providers.
+type BreakerStatus struct {
+	Provider            string       `json:"provider"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	// RetryAt is when an open circuit lets the next probe through.
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Allow reports whether a request may be sent. It returns a *ProviderError
+// wrapping ErrCircuitOpen while the circuit is open, and while another
+// request is probing a half-open circuit.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.Cooldown {
+		b.state = CircuitHalfOpen
+	}
+
+	switch b.state {
+	case CircuitOpen:
+		return &ProviderError{Provider: b.Provider, Err: ErrProviderUnavailable, Cause: ErrCircuitOpen}
+	case CircuitHalfOpen:
+		if b.probing {
+			return &ProviderError{Provider: b.Provider, Err: ErrProviderUnavailable, Cause: ErrCircuitOpen}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record reports the outcome of a request let through by Allow.
+func (b *Breaker) Record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	switch {
+	case errors.Is(err, context.Canceled):
+		// The caller gave up, which says nothing about the provider.
+	case err == nil || !errors.Is(err, ErrProviderUnavailable):
+		b.state = CircuitClosed
+		b.failures = 0
+	default:
+		b.failures++
+		b.lastError = err.Error()
+		if b.state == CircuitHalfOpen || b.failures >= b.Threshold {
+			b.state = CircuitOpen
+			b.openedAt = b.now()
+		}
+	}
+}
+
+// Release gives back a request let through by Allow that was not sent after
+// all. Its outcome says nothing about the provider, so only a half-open probe
+// is freed for the next request.
+func (b *Breaker) Release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Status returns the current state of the breaker.
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Provider:            b.Provider,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if b.state != CircuitClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	if b.state == CircuitOpen {
+		retryAt := b.openedAt.Add(b.Cooldown)
+		status.RetryAt = &retryAt
+		if !b.now().Before(retryAt) {
+			status.State = CircuitHalfOpen
+		}
+	}
+	return status
+}
+
+// breakerProvider is implemented by providers guarded by circuit breakers.
+type breakerProvider interface {
+	breakers() []*Breaker
+}
+
+func (a *Agify) breakers() []*Breaker       { return []*Breaker{a.Breaker} }
+func (g *Genderize) breakers() []*Breaker   { return []*Breaker{g.Breaker} }
+func (n *Nationalize) breakers() []*Breaker { return []*Breaker{n.Breaker} }
+
+// Breakers returns the circuit breakers guarding p, in age, gender,
+// nationality order. Providers without a breaker are skipped.
+func (p Providers) Breakers() []*Breaker {
+	var breakers []*Breaker
+	seen := make(map[*Breaker]bool)
+	for _, provider := range []interface{}{p.Age, p.Gender, p.Nationality} {
+		guarded, ok := provider.(breakerProvider)
+		if !ok {
+			continue
+		}
+		for _, breaker := range guarded.breakers() {
+			if breaker != nil && !seen[breaker] {
+				seen[breaker] = true
+				breakers = append(breakers, breaker)
+			}
+		}
+	}
+	return breakers
+}
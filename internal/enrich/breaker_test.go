@@ -0,0 +1,162 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	newBreaker := func() *Breaker {
+		breaker := NewBreaker(ProviderGenderize, 2, time.Minute)
+		breaker.now = func() time.Time { return now }
+		return breaker
+	}
+	unavailable := &ProviderError{Provider: ProviderGenderize, StatusCode: http.StatusBadGateway, Err: ErrProviderUnavailable}
+
+	t.Run("OpensAfterThreshold", func(t *testing.T) {
+		breaker := newBreaker()
+		breaker.Record(unavailable)
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow() after one failure = %v, want nil", err)
+		}
+		breaker.Record(unavailable)
+
+		err := breaker.Allow()
+		if !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, ErrProviderUnavailable) {
+			t.Errorf("Allow() = %v, want ErrCircuitOpen", err)
+		}
+		if status := breaker.Status(); status.State != CircuitOpen || status.ConsecutiveFailures != 2 || status.RetryAt == nil {
+			t.Errorf("Status() = %+v", status)
+		}
+	})
+
+	t.Run("OtherErrorsResetFailures", func(t *testing.T) {
+		breaker := newBreaker()
+		breaker.Record(unavailable)
+		breaker.Record(&ProviderError{Provider: ProviderGenderize, StatusCode: http.StatusUnprocessableEntity, Err: ErrInvalidName})
+		breaker.Record(unavailable)
+
+		if err := breaker.Allow(); err != nil {
+			t.Errorf("Allow() = %v, want nil", err)
+		}
+	})
+
+	t.Run("HalfOpenProbe", func(t *testing.T) {
+		breaker := newBreaker()
+		breaker.Record(unavailable)
+		breaker.Record(unavailable)
+
+		now = now.Add(time.Minute)
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow() after cooldown = %v, want nil", err)
+		}
+		if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Allow() during probe = %v, want ErrCircuitOpen", err)
+		}
+
+		breaker.Record(unavailable)
+		if status := breaker.Status(); status.State != CircuitOpen {
+			t.Fatalf("State after failed probe = %s, want open", status.State)
+		}
+
+		now = now.Add(time.Minute)
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow() after cooldown = %v, want nil", err)
+		}
+		breaker.Record(nil)
+		if status := breaker.Status(); status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+			t.Errorf("Status() after successful probe = %+v", status)
+		}
+	})
+
+	t.Run("ReleasedProbe", func(t *testing.T) {
+		breaker := newBreaker()
+		breaker.Record(unavailable)
+		breaker.Record(unavailable)
+
+		now = now.Add(time.Minute)
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow() after cooldown = %v, want nil", err)
+		}
+		breaker.Release()
+		if err := breaker.Allow(); err != nil {
+			t.Errorf("Allow() after released probe = %v, want nil", err)
+		}
+		if status := breaker.Status(); status.State != CircuitHalfOpen {
+			t.Errorf("State after released probe = %s, want half_open", status.State)
+		}
+	})
+
+	t.Run("CancelledRequestIgnored", func(t *testing.T) {
+		breaker := newBreaker()
+		breaker.Record(unavailable)
+		breaker.Record(&ProviderError{Provider: ProviderGenderize, Err: ErrProviderUnavailable, Cause: context.Canceled})
+
+		if status := breaker.Status(); status.ConsecutiveFailures != 1 {
+			t.Errorf("ConsecutiveFailures = %d, want 1", status.ConsecutiveFailures)
+		}
+	})
+}
+
+func TestProviderFailsFastWhenOpen(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	genderize := &Genderize{
+		BaseURL: server.URL,
+		Client:  server.Client(),
+		Breaker: NewBreaker(ProviderGenderize, 2, time.Minute),
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := genderize.Gender(context.Background(), "John"); !errors.Is(err, ErrProviderUnavailable) {
+			t.Errorf("Gender() error = %v, want ErrProviderUnavailable", err)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Provider received %d requests, want 2", requests)
+	}
+
+	breakers := Providers{Gender: genderize}.Breakers()
+	if len(breakers) != 1 || breakers[0].Status().State != CircuitOpen {
+		t.Errorf("Breakers() = %v, want the open genderize breaker", breakers)
+	}
+}
+
+func TestOpenBreakerKeepsQuota(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(10-requests))
+		w.Header().Set(HeaderRateLimitReset, "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	quota := NewQuota(ProviderGenderize)
+	genderize := &Genderize{
+		BaseURL: server.URL,
+		Client:  server.Client(),
+		Quota:   quota,
+		Breaker: NewBreaker(ProviderGenderize, 2, time.Minute),
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := genderize.Gender(context.Background(), "John"); !errors.Is(err, ErrProviderUnavailable) {
+			t.Errorf("Gender() error = %v, want ErrProviderUnavailable", err)
+		}
+	}
+	if quota.remaining != 8 {
+		t.Errorf("Remaining quota = %d, want 8: a request failed by the open circuit must not take quota", quota.remaining)
+	}
+}
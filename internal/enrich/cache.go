@@ -0,0 +1,165 @@
+package enrich
+
+import (
+	"NameEnricher/internal/models"
+	"NameEnricher/pkg/logger"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached results are reused when no TTL is configured.
+const DefaultCacheTTL = 30 * 24 * time.Hour
+
+// Cache stores enrichment results by normalized first name.
+type Cache interface {
+	Get(ctx context.Context, name string) (Result, bool, error)
+	Set(ctx context.Context, name string, result Result) error
+}
+
+// SourceCachePrefix is prepended to the Source of predictions served from the
+// cache, so that "cache:agify" is an agify answer reused without calling agify.
+const SourceCachePrefix = "cache:"
+
+// fromCache marks the predictions of a cached result as served from the cache.
+func fromCache(result Result) Result {
+	if result.Age.Source != "" {
+		result.Age.Source = SourceCachePrefix + result.Age.Source
+	}
+	if result.Gender.Source != "" {
+		result.Gender.Source = SourceCachePrefix + result.Gender.Source
+	}
+	if result.Nationality.Source != "" {
+		result.Nationality.Source = SourceCachePrefix + result.Nationality.Source
+	}
+	return result
+}
+
+// toCache removes the marks fromCache put on result, so that answers reused
+// from the cache are stored again as the provider's.
+func toCache(result Result) Result {
+	result.Age.Source = strings.TrimPrefix(result.Age.Source, SourceCachePrefix)
+	result.Gender.Source = strings.TrimPrefix(result.Gender.Source, SourceCachePrefix)
+	result.Nationality.Source = strings.TrimPrefix(result.Nationality.Source, SourceCachePrefix)
+	return result
+}
+
+// reused reports whether every prediction of result was reused from the
+// cache, so storing it again would only extend the life of a stale entry.
+func reused(result Result) bool {
+	for _, source := range []string{result.Age.Source, result.Gender.Source, result.Nationality.Source} {
+		if !strings.HasPrefix(source, SourceCachePrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProviderCache is the name of the cache in a provider chain, where it answers
+// a field from the cached result of the name.
+const ProviderCache = "cache"
+
+// cacheProvider is the member of a provider chain that answers from a Cache.
+// A name without a cached result gets an unknown prediction, so that the chain
+// moves on; cache errors are logged and count as such a miss.
+type cacheProvider struct {
+	Cache Cache
+}
+
+func (c cacheProvider) Age(ctx context.Context, name string) (AgePrediction, error) {
+	return c.LocalizedAge(ctx, name, "")
+}
+
+func (c cacheProvider) LocalizedAge(ctx context.Context, name, countryID string) (AgePrediction, error) {
+	return fromCache(c.lookup(ctx, Query{Name: name, CountryID: countryID})).Age, nil
+}
+
+func (c cacheProvider) Gender(ctx context.Context, name string) (GenderPrediction, error) {
+	return c.LocalizedGender(ctx, name, "")
+}
+
+func (c cacheProvider) LocalizedGender(ctx context.Context, name, countryID string) (GenderPrediction, error) {
+	return fromCache(c.lookup(ctx, Query{Name: name, CountryID: countryID})).Gender, nil
+}
+
+func (c cacheProvider) Nationality(ctx context.Context, name string) (NationalityPrediction, error) {
+	return fromCache(c.lookup(ctx, Query{Name: name})).Nationality, nil
+}
+
+func (c cacheProvider) lookup(ctx context.Context, q Query) Result {
+	result, found, err := c.Cache.Get(ctx, q.cacheKey())
+	if err != nil {
+		logger.Log.Warnf("Failed to read enrichment cache for name %s: %v", q.Name, err)
+		return Result{}
+	}
+	if found {
+		logger.Log.Debugf("Using cached enrichment for name %s", q.Name)
+	}
+	return result
+}
+
+// NormalizeName returns the key under which results for name are cached,
+// see models.NameKey.
+func NormalizeName(name string) string {
+	return models.NameKey(name)
+}
+
+// cacheKey returns the key under which results for q are cached. Localized
+// results are kept apart from worldwide ones as "name@CC".
+func (q Query) cacheKey() string {
+	if q.CountryID == "" {
+		return NormalizeName(q.Name)
+	}
+	return NormalizeName(q.Name) + "@" + strings.ToUpper(q.CountryID)
+}
+
+// DBCache is a Cache backed by the name_enrichment_cache table.
+type DBCache struct {
+	DB  *sql.DB
+	TTL time.Duration
+}
+
+func NewDBCache(db *sql.DB, ttl time.Duration) *DBCache {
+	return &DBCache{DB: db, TTL: ttl}
+}
+
+func (c *DBCache) Get(ctx context.Context, name string) (Result, bool, error) {
+	entry, found, err := models.GetNameEnrichmentCache(c.DB, ctx, name)
+	if err != nil || !found {
+		return Result{}, false, err
+	}
+
+	var result Result
+	if err := json.Unmarshal(entry.Age, &result.Age); err != nil {
+		return Result{}, false, fmt.Errorf("failed to decode cached age: %w", err)
+	}
+	if err := json.Unmarshal(entry.Gender, &result.Gender); err != nil {
+		return Result{}, false, fmt.Errorf("failed to decode cached gender: %w", err)
+	}
+	if err := json.Unmarshal(entry.Nationality, &result.Nationality); err != nil {
+		return Result{}, false, fmt.Errorf("failed to decode cached nationality: %w", err)
+	}
+	return result, true, nil
+}
+
+func (c *DBCache) Set(ctx context.Context, name string, result Result) error {
+	entry := models.NameEnrichmentCache{Name: name}
+	var err error
+
+	if entry.Age, err = json.Marshal(result.Age); err != nil {
+		return fmt.Errorf("failed to encode age: %w", err)
+	}
+	if entry.Gender, err = json.Marshal(result.Gender); err != nil {
+		return fmt.Errorf("failed to encode gender: %w", err)
+	}
+	if entry.Nationality, err = json.Marshal(result.Nationality); err != nil {
+		return fmt.Errorf("failed to encode nationality: %w", err)
+	}
+
+	entry.FetchedAt = time.Now()
+	entry.ExpiresAt = entry.FetchedAt.Add(c.TTL)
+	return models.SaveNameEnrichmentCache(c.DB, ctx, entry)
+}
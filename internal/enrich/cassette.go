@@ -0,0 +1,182 @@
+package enrich
+
+import (
+	"NameEnricher/pkg/logger"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// Cassette modes, selected by ENRICH_CASSETTE_MODE.
+const (
+	// CassetteRecord sends provider requests as usual and writes every
+	// request and its response to the cassette.
+	CassetteRecord = "record"
+	// CassetteReplay answers provider requests from the cassette without
+	// touching the network.
+	CassetteReplay = "replay"
+)
+
+// ErrCassetteMiss is matched when a replayed request was never recorded.
+var ErrCassetteMiss = errors.New("request not found in cassette")
+
+// Interaction is a provider request and the response it received.
+type Interaction struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Cassette records provider requests to a JSON file and replays them, so
+// that the behavior of the real providers can be captured once and tested
+// against deterministically. API keys are never recorded.
+//
+// Replayed requests are matched by method and URL. A request recorded
+// several times is answered with its recordings in order, the last one
+// repeating once they are used up. It is safe for concurrent use.
+type Cassette struct {
+	Path string
+	Mode string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     map[string]int
+}
+
+// NewCassette returns a cassette recording to path, which is written after
+// every request, replacing any earlier recording.
+func NewCassette(path string) *Cassette {
+	return &Cassette{Path: path, Mode: CassetteRecord}
+}
+
+// LoadCassette reads the cassette at path for replay.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	cassette := &Cassette{Path: path, Mode: CassetteReplay, replayed: make(map[string]int)}
+	if err := json.Unmarshal(data, &cassette.interactions); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	logger.Log.Infof("Loaded cassette %s with %d interactions", path, len(cassette.interactions))
+	return cassette, nil
+}
+
+// Transport returns an http.RoundTripper that records the requests it sends
+// through next, or replays them, depending on the mode of the cassette.
+func (c *Cassette) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return cassetteTransport{cassette: c, next: next}
+}
+
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+func (t cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cassette.Mode == CassetteReplay {
+		return t.cassette.replay(req)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Method:  req.Method,
+		URL:     cassetteURL(req.URL),
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string, len(resp.Header)),
+		Body:    string(body),
+	}
+	for header := range resp.Header {
+		if header != "Date" && header != "Set-Cookie" {
+			interaction.Headers[header] = resp.Header.Get(header)
+		}
+	}
+	if err := t.cassette.record(interaction); err != nil {
+		logger.Log.Errorf("Failed to record provider response: %v", err)
+	}
+	return resp, nil
+}
+
+// record appends interaction to the cassette and writes it out.
+func (c *Cassette) record(interaction Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, interaction)
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.Path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// replay answers req with its next recording.
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + cassetteURL(req.URL)
+
+	c.mu.Lock()
+	var matches []Interaction
+	for _, interaction := range c.interactions {
+		if interaction.Method+" "+interaction.URL == key {
+			matches = append(matches, interaction)
+		}
+	}
+	if len(matches) == 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrCassetteMiss, key)
+	}
+	interaction := matches[min(c.replayed[key], len(matches)-1)]
+	c.replayed[key]++
+	c.mu.Unlock()
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header, len(interaction.Headers)),
+		Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Body))),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       req,
+	}
+	for header, value := range interaction.Headers {
+		resp.Header.Set(header, value)
+	}
+	return resp, nil
+}
+
+// cassetteURL returns u without its apikey parameter and with the rest of its
+// query in a canonical order.
+func cassetteURL(u *url.URL) string {
+	stripped := *u
+	query := stripped.Query()
+	query.Del("apikey")
+	stripped.RawQuery = query.Encode()
+	return stripped.String()
+}
@@ -0,0 +1,100 @@
+package enrich
+
+import (
+	"NameEnricher/internal/enrich/enrichtest"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCassette(t *testing.T) {
+	server := enrichtest.NewServer(enrichtest.Fixtures{
+		enrichtest.Agify: {
+			"john":  {Response: map[string]interface{}{"age": 35, "count": 1200}},
+			"limit": {Status: 429},
+		},
+		enrichtest.Genderize: {
+			"john": {Response: map[string]interface{}{"gender": "male", "probability": 0.99, "count": 1200}},
+		},
+	})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cfg := Config{
+		Agify:        ProviderConfig{BaseURL: server.ProviderURL(enrichtest.Agify), APIKey: "secret", Timeout: time.Second},
+		Genderize:    ProviderConfig{BaseURL: server.ProviderURL(enrichtest.Genderize), APIKey: "secret", Timeout: time.Second},
+		Nationalize:  ProviderConfig{BaseURL: server.ProviderURL(enrichtest.Nationalize), APIKey: "secret", Timeout: time.Second},
+		Cassette:     path,
+		CassetteMode: CassetteRecord,
+	}
+	ctx := context.Background()
+
+	// enrich asks every provider about name and returns what they answered.
+	enrich := func(providers Providers, name string) (AgePrediction, GenderPrediction, error) {
+		age, err := providers.Age.Age(ctx, name)
+		if err != nil {
+			return AgePrediction{}, GenderPrediction{}, err
+		}
+		gender, err := providers.Gender.Gender(ctx, name)
+		return age, gender, err
+	}
+
+	recording, err := NewProviders(cfg)
+	if err != nil {
+		t.Fatalf("NewProviders() error: %v", err)
+	}
+	recordedAge, recordedGender, err := enrich(recording, "John")
+	if err != nil {
+		t.Fatalf("Unexpected error while recording: %v", err)
+	}
+	if _, err := recording.Age.Age(ctx, "Limit"); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("Age() error = %v, want ErrQuotaExhausted", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Cassette was not written: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Cassette contains the API key:\n%s", data)
+	}
+
+	cfg.CassetteMode = CassetteReplay
+	replaying, err := NewProviders(cfg)
+	if err != nil {
+		t.Fatalf("NewProviders() error: %v", err)
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		age, gender, err := enrich(replaying, "John")
+		if err != nil {
+			t.Fatalf("Unexpected error while replaying: %v", err)
+		}
+		if !reflect.DeepEqual(age, recordedAge) || !reflect.DeepEqual(gender, recordedGender) {
+			t.Errorf("Replayed %+v and %+v, recorded %+v and %+v", age, gender, recordedAge, recordedGender)
+		}
+	})
+
+	t.Run("ReplayQuotaExhausted", func(t *testing.T) {
+		if _, err := replaying.Age.Age(ctx, "Limit"); !errors.Is(err, ErrQuotaExhausted) {
+			t.Errorf("Age() error = %v, want ErrQuotaExhausted", err)
+		}
+	})
+
+	t.Run("NotRecorded", func(t *testing.T) {
+		if _, err := replaying.Gender.Gender(ctx, "Mary"); !errors.Is(err, ErrCassetteMiss) {
+			t.Errorf("Gender() error = %v, want ErrCassetteMiss", err)
+		}
+	})
+
+	t.Run("MissingCassette", func(t *testing.T) {
+		cfg.Cassette = filepath.Join(t.TempDir(), "missing.json")
+		if _, err := NewProviders(cfg); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
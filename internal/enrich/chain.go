@@ -0,0 +1,220 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+)
+
+// AgeChain asks its providers in order until one returns an age based on at
+// least MinCount samples. When none is that confident, the answer with the
+// most samples wins. Source on the returned prediction names the provider
+// that answered.
+type AgeChain struct {
+	Providers []AgeProvider
+	MinCount  int
+}
+
+func (c AgeChain) Age(ctx context.Context, name string) (AgePrediction, error) {
+	return c.LocalizedAge(ctx, name, "")
+}
+
+func (c AgeChain) LocalizedAge(ctx context.Context, name, countryID string) (AgePrediction, error) {
+	predictions, errs := c.lookupAll(ctx, []string{name}, []string{countryID})
+	return predictions[0], errs[0]
+}
+
+func (c AgeChain) lookupAll(ctx context.Context, names, countries []string) ([]AgePrediction, []error) {
+	members := make([]chainMember[AgePrediction], len(c.Providers))
+	for i, provider := range c.Providers {
+		members[i].single, members[i].batch = ageLookups(provider)
+	}
+	return runChain(ctx, names, countries, members, func(p AgePrediction) (float64, bool) {
+		if !p.Known() {
+			return -1, false
+		}
+		return float64(p.Count), p.Count >= c.MinCount
+	})
+}
+
+func (c AgeChain) breakers() []*Breaker {
+	return chainBreakers(c.Providers)
+}
+
+// GenderChain asks its providers in order until one returns a gender with a
+// probability of at least MinProbability. When none is that confident, the
+// most probable answer wins.
+type GenderChain struct {
+	Providers      []GenderProvider
+	MinProbability float64
+}
+
+func (c GenderChain) Gender(ctx context.Context, name string) (GenderPrediction, error) {
+	return c.LocalizedGender(ctx, name, "")
+}
+
+func (c GenderChain) LocalizedGender(ctx context.Context, name, countryID string) (GenderPrediction, error) {
+	predictions, errs := c.lookupAll(ctx, []string{name}, []string{countryID})
+	return predictions[0], errs[0]
+}
+
+func (c GenderChain) lookupAll(ctx context.Context, names, countries []string) ([]GenderPrediction, []error) {
+	members := make([]chainMember[GenderPrediction], len(c.Providers))
+	for i, provider := range c.Providers {
+		members[i].single, members[i].batch = genderLookups(provider)
+	}
+	return runChain(ctx, names, countries, members, func(p GenderPrediction) (float64, bool) {
+		if !p.Known() {
+			return -1, false
+		}
+		return p.Probability, p.Probability >= c.MinProbability
+	})
+}
+
+func (c GenderChain) breakers() []*Breaker {
+	return chainBreakers(c.Providers)
+}
+
+// NationalityChain asks its providers in order until one returns a country
+// with a probability of at least MinProbability. When none is that confident,
+// the most probable answer wins.
+type NationalityChain struct {
+	Providers      []NationalityProvider
+	MinProbability float64
+}
+
+func (c NationalityChain) Nationality(ctx context.Context, name string) (NationalityPrediction, error) {
+	predictions, errs := c.lookupAll(ctx, []string{name}, nil)
+	return predictions[0], errs[0]
+}
+
+func (c NationalityChain) lookupAll(ctx context.Context, names, countries []string) ([]NationalityPrediction, []error) {
+	members := make([]chainMember[NationalityPrediction], len(c.Providers))
+	for i, provider := range c.Providers {
+		members[i].single, members[i].batch = nationalityLookups(provider)
+	}
+	return runChain(ctx, names, countries, members, func(p NationalityPrediction) (float64, bool) {
+		if !p.Known() {
+			return -1, false
+		}
+		return p.Probability, p.Probability >= c.MinProbability
+	})
+}
+
+func (c NationalityChain) breakers() []*Breaker {
+	return chainBreakers(c.Providers)
+}
+
+// chainLookup is implemented by the chains and ensembles, which resolve a
+// whole batch themselves and report one error per name instead of one per
+// request.
+type chainLookup[T any] interface {
+	lookupAll(ctx context.Context, names, countries []string) ([]T, []error)
+}
+
+// lookupAll runs provider for every name like lookupBatch, letting chains
+// and ensembles spread the names over their members.
+func lookupAll[T any](ctx context.Context, provider any, names, countries []string,
+	single func(context.Context, string, string) (T, error),
+	batch func(context.Context, []string, string) ([]T, error)) ([]T, []error) {
+	if chain, ok := provider.(chainLookup[T]); ok {
+		return chain.lookupAll(ctx, names, countries)
+	}
+	return lookupBatch(ctx, names, countries, single, batch)
+}
+
+type chainMember[T any] struct {
+	single func(context.Context, string, string) (T, error)
+	batch  func(context.Context, []string, string) ([]T, error)
+}
+
+// runChain asks each member for the names that have no confident answer yet.
+// rank scores a prediction, negative when it is unknown, and reports whether
+// it is confident enough to stop. Every name gets the best-ranked answer; a
+// name without a known answer gets the members' errors, if any failed.
+func runChain[T any](ctx context.Context, names, countries []string, members []chainMember[T],
+	rank func(T) (float64, bool)) ([]T, []error) {
+	predictions := make([]T, len(names))
+	answered := make([]bool, len(names))
+	failures := make([][]error, len(names))
+
+	pending := make([]int, len(names))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for _, member := range members {
+		if len(pending) == 0 {
+			break
+		}
+		memberNames := make([]string, len(pending))
+		var memberCountries []string
+		if countries != nil {
+			memberCountries = make([]string, len(pending))
+		}
+		for j, i := range pending {
+			memberNames[j] = names[i]
+			if countries != nil {
+				memberCountries[j] = countries[i]
+			}
+		}
+
+		memberPredictions, memberErrs := lookupBatch(ctx, memberNames, memberCountries, member.single, member.batch)
+		var next []int
+		for j, i := range pending {
+			if memberErrs[j] != nil {
+				failures[i] = append(failures[i], memberErrs[j])
+				next = append(next, i)
+				continue
+			}
+			score, confident := rank(memberPredictions[j])
+			if best, _ := rank(predictions[i]); !answered[i] || score > best {
+				predictions[i] = memberPredictions[j]
+				answered[i] = true
+			}
+			if !confident {
+				next = append(next, i)
+			}
+		}
+		pending = next
+	}
+
+	errs := make([]error, len(names))
+	for i := range names {
+		if score, _ := rank(predictions[i]); answered[i] && score >= 0 {
+			continue
+		}
+		if len(failures[i]) > 0 {
+			errs[i] = chainError(failures[i])
+		}
+	}
+	return predictions, errs
+}
+
+// chainError combines the failures of a chain for one name. Once any member
+// failed for a reason other than rejecting the name, the rejections are left
+// out, so that the field is retried rather than reported as invalid.
+func chainError(failures []error) error {
+	var retryable []error
+	for _, err := range failures {
+		if !errors.Is(err, ErrInvalidName) {
+			retryable = append(retryable, err)
+		}
+	}
+	if len(retryable) > 0 {
+		failures = retryable
+	}
+	if len(failures) == 1 {
+		return failures[0]
+	}
+	return errors.Join(failures...)
+}
+
+func chainBreakers[P any](providers []P) []*Breaker {
+	var breakers []*Breaker
+	for _, provider := range providers {
+		if guarded, ok := any(provider).(breakerProvider); ok {
+			breakers = append(breakers, guarded.breakers()...)
+		}
+	}
+	return breakers
+}
@@ -0,0 +1,173 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAgeChain(t *testing.T) {
+	unavailable := &ProviderError{Provider: ProviderAgify, Err: ErrProviderUnavailable}
+	invalid := &ProviderError{Provider: ProviderAgify, Err: ErrInvalidName}
+
+	tests := []struct {
+		name      string
+		providers []AgeProvider
+		want      AgePrediction
+		wantErr   error
+	}{
+		{
+			name: "StopsAtConfidentAnswer",
+			providers: []AgeProvider{
+				stubAge{age: AgePrediction{Age: intPtr(35), Count: 1200, Source: ProviderOffline}},
+				stubAge{err: errors.New("must not be called")},
+			},
+			want: AgePrediction{Age: intPtr(35), Count: 1200, Source: ProviderOffline},
+		},
+		{
+			name: "FallsThroughUnknown",
+			providers: []AgeProvider{
+				stubAge{age: AgePrediction{Source: ProviderOffline}},
+				stubAge{age: AgePrediction{Age: intPtr(40), Count: 500, Source: ProviderAgify}},
+			},
+			want: AgePrediction{Age: intPtr(40), Count: 500, Source: ProviderAgify},
+		},
+		{
+			name: "KeepsBestUnconfidentAnswer",
+			providers: []AgeProvider{
+				stubAge{age: AgePrediction{Age: intPtr(35), Count: 50, Source: ProviderOffline}},
+				stubAge{age: AgePrediction{Age: intPtr(40), Count: 10, Source: ProviderAgify}},
+			},
+			want: AgePrediction{Age: intPtr(35), Count: 50, Source: ProviderOffline},
+		},
+		{
+			name: "FallsThroughError",
+			providers: []AgeProvider{
+				stubAge{err: unavailable},
+				stubAge{age: AgePrediction{Age: intPtr(40), Count: 500, Source: ProviderAgify}},
+			},
+			want: AgePrediction{Age: intPtr(40), Count: 500, Source: ProviderAgify},
+		},
+		{
+			name: "UnknownAfterError",
+			providers: []AgeProvider{
+				stubAge{err: unavailable},
+				stubAge{age: AgePrediction{Source: ProviderOffline}},
+			},
+			want:    AgePrediction{Source: ProviderOffline},
+			wantErr: ErrProviderUnavailable,
+		},
+		{
+			name: "AllUnknown",
+			providers: []AgeProvider{
+				stubAge{age: AgePrediction{Source: ProviderOffline}},
+				stubAge{age: AgePrediction{Source: ProviderAgify}},
+			},
+			want: AgePrediction{Source: ProviderOffline},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := AgeChain{Providers: tt.providers, MinCount: 100}
+
+			got, err := chain.Age(context.Background(), "John")
+
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Age = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("RejectedByAll", func(t *testing.T) {
+		chain := AgeChain{Providers: []AgeProvider{stubAge{err: invalid}, stubAge{err: invalid}}}
+
+		_, err := chain.Age(context.Background(), "John")
+
+		if !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Error = %v, want ErrInvalidName", err)
+		}
+	})
+
+	t.Run("RejectedAndUnavailable", func(t *testing.T) {
+		chain := AgeChain{Providers: []AgeProvider{stubAge{err: invalid}, stubAge{err: unavailable}}}
+
+		_, err := chain.Age(context.Background(), "John")
+
+		if errors.Is(err, ErrInvalidName) || !errors.Is(err, ErrProviderUnavailable) {
+			t.Errorf("Error = %v, want only ErrProviderUnavailable", err)
+		}
+	})
+}
+
+func TestGenderChainThreshold(t *testing.T) {
+	local := GenderPrediction{Gender: "female", Probability: 0.6, Count: 20, Source: ProviderOffline}
+	remote := GenderPrediction{Gender: "female", Probability: 0.9, Count: 4000, Source: ProviderGenderize}
+	chain := GenderChain{
+		Providers:      []GenderProvider{stubGender{gender: local}, stubGender{gender: remote}},
+		MinProbability: 0.8,
+	}
+
+	got, err := chain.Gender(context.Background(), "Andrea")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, remote) {
+		t.Errorf("Gender = %+v, want %+v", got, remote)
+	}
+
+	chain.MinProbability = 0.5
+	if got, _ := chain.Gender(context.Background(), "Andrea"); !reflect.DeepEqual(got, local) {
+		t.Errorf("Gender with lower threshold = %+v, want %+v", got, local)
+	}
+}
+
+func TestProvidersEnrichBatchChain(t *testing.T) {
+	offline, err := LoadOffline("testdata/names.csv")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	remote := &batchAge{}
+	providers := Providers{
+		Age:         AgeChain{Providers: []AgeProvider{offline, remote}, MinCount: 100},
+		Gender:      stubGender{gender: john.Gender},
+		Nationality: stubNationality{nationality: john.Nationality},
+	}
+	queries := []Query{{Name: "John"}, {Name: "Kim"}, {Name: "Pavel"}, {Name: "Maria"}}
+
+	results, errs := providers.EnrichBatch(context.Background(), queries)
+
+	// Only the names the dataset has no age for reach the remote provider,
+	// in a single request.
+	if !reflect.DeepEqual(remote.batches, []int{2}) {
+		t.Errorf("Batches = %v, want [2]", remote.batches)
+	}
+	wantSources := []string{ProviderOffline, "", "", ProviderOffline}
+	wantAges := []int{47, 3, 5, 39}
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Errorf("Unexpected error for %s: %v", queries[i].Name, errs[i])
+			continue
+		}
+		if result.Age.Source != wantSources[i] || *result.Age.Age != wantAges[i] {
+			t.Errorf("Age for %s = %+v, want %d from %q", queries[i].Name, result.Age, wantAges[i], wantSources[i])
+		}
+	}
+}
+
+func TestChainBreakers(t *testing.T) {
+	agify := NewAgify()
+	providers := Providers{
+		Age:         AgeChain{Providers: []AgeProvider{&Offline{}, agify}},
+		Gender:      stubGender{},
+		Nationality: stubNationality{},
+	}
+
+	if breakers := providers.Breakers(); !reflect.DeepEqual(breakers, []*Breaker{agify.Breaker}) {
+		t.Errorf("Breakers() = %v, want the agify breaker", breakers)
+	}
+}
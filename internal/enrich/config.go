@@ -0,0 +1,570 @@
+package enrich
+
+import (
+	"NameEnricher/pkg/logger"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a single provider request when no timeout is configured.
+const DefaultTimeout = 10 * time.Second
+
+// Default confidence thresholds of the provider chains.
+const (
+	DefaultMinAgeCount               = 100
+	DefaultMinGenderProbability      = 0.8
+	DefaultMinNationalityProbability = 0.2
+)
+
+// ProviderConfig configures how a single external provider is reached.
+type ProviderConfig struct {
+	BaseURL string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// Sources of enrichment data, selected by ENRICH_SOURCE.
+const (
+	// SourceHTTP queries the agify, genderize and nationalize APIs.
+	SourceHTTP = "http"
+	// SourceOffline answers from the dataset at Config.OfflineDataset.
+	SourceOffline = "offline"
+)
+
+// Config holds the settings for all enrichment providers.
+type Config struct {
+	Agify       ProviderConfig
+	Genderize   ProviderConfig
+	Nationalize ProviderConfig
+	// Source selects where predictions come from: SourceHTTP or SourceOffline.
+	Source string
+	// OfflineDataset is the path of the CSV dataset used by SourceOffline.
+	OfflineDataset string
+	// AgeProviders, GenderProviders and NationalityProviders name the
+	// providers asked for each field, in order, e.g. ProviderOffline then
+	// ProviderAgify. When empty, the field uses the provider of Source.
+	AgeProviders         []string
+	GenderProviders      []string
+	NationalityProviders []string
+	// MinAgeCount, MinGenderProbability and MinNationalityProbability are the
+	// confidence a provider's answer needs before the rest of a chain is skipped.
+	MinAgeCount               int
+	MinGenderProbability      float64
+	MinNationalityProbability float64
+	// Ensemble lists the fields, FieldGender or FieldNationality, whose
+	// providers are all asked and combined instead of chained.
+	Ensemble []string
+	// EnsembleWeights weighs the providers of an ensemble by name; providers
+	// that are not listed weigh 1.
+	EnsembleWeights map[string]float64
+	// CacheTTL is how long results are kept in name_enrichment_cache.
+	// Zero disables the cache.
+	CacheTTL time.Duration
+	// Cache answers the ProviderCache entries of the chains, which are left
+	// out while it is nil. LoadConfig never sets it.
+	Cache Cache
+	// AllowPartial makes person creation succeed when some providers fail;
+	// the failed fields are stored as pending. Clients can override it per request.
+	AllowPartial bool
+	// ResolveCountry localizes age and gender to the most likely nationality
+	// when the client gives no country hint.
+	ResolveCountry bool
+	// Rules enables the surname and patronymic rules, see Rules.
+	Rules bool
+	// StripDiacritics removes the accents of names before they are cached
+	// and sent to the providers.
+	StripDiacritics bool
+	// Transliterate romanizes Cyrillic names before they are cached and sent
+	// to the providers, which know the Latin forms much better.
+	Transliterate bool
+	// ResolveAliases enriches aliased first names in their canonical form,
+	// see Aliases.
+	ResolveAliases bool
+	// BreakerThreshold is the number of consecutive failures that open a
+	// provider's circuit breaker. Zero disables the breakers.
+	BreakerThreshold int
+	// BreakerCooldown is how long an open circuit fails fast before a probe
+	// request is let through.
+	BreakerCooldown time.Duration
+	// Cassette is the file CassetteMode records provider responses to or
+	// replays them from; see Cassette. Empty disables both.
+	Cassette     string
+	CassetteMode string
+}
+
+// LoadConfig reads provider settings from the environment. Every provider is
+// configured by <PREFIX>_URL, <PREFIX>_API_KEY and <PREFIX>_TIMEOUT, where the
+// prefix is AGIFY, GENDERIZE or NATIONALIZE and the timeout is a Go duration
+// such as "5s". ENRICH_CACHE_TTL sets how long results are cached; "0"
+// disables the cache. ENRICH_ALLOW_PARTIAL enables partial person creation.
+// ENRICH_SOURCE selects "http" (the default) or "offline" providers, the
+// latter reading the dataset at ENRICH_OFFLINE_DATASET.
+// ENRICH_AGE_PROVIDERS, ENRICH_GENDER_PROVIDERS and ENRICH_NATIONALITY_PROVIDERS
+// override the source per field with comma-separated fallback chains, which
+// may place the enrichment cache ("cache") anywhere in the order, tuned by
+// ENRICH_MIN_AGE_COUNT, ENRICH_MIN_GENDER_PROBABILITY and
+// ENRICH_MIN_NATIONALITY_PROBABILITY. ENRICH_ENSEMBLE lists the fields
+// ("gender", "nationality") whose providers are combined instead, weighted by
+// ENRICH_ENSEMBLE_WEIGHTS such as "genderize:2,offline:1".
+// ENRICH_RESOLVE_COUNTRY enables the nationality lookup for requests without
+// a country hint. ENRICH_RULES enables the surname and patronymic rules.
+// ENRICH_STRIP_DIACRITICS removes accents from names before enrichment and
+// ENRICH_TRANSLITERATE, on by default, romanizes Cyrillic names.
+// ENRICH_RESOLVE_ALIASES enriches aliases such as "Sasha" as their canonical
+// name.
+// ENRICH_BREAKER_THRESHOLD and ENRICH_BREAKER_COOLDOWN tune the circuit
+// breakers; a threshold of "0" disables them.
+// ENRICH_CASSETTE_MODE, "record" or "replay", records the provider responses
+// to the file ENRICH_CASSETTE or replays them from it without network access.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Source:                    SourceHTTP,
+		MinAgeCount:               DefaultMinAgeCount,
+		MinGenderProbability:      DefaultMinGenderProbability,
+		MinNationalityProbability: DefaultMinNationalityProbability,
+		Transliterate:             true,
+		CacheTTL:                  DefaultCacheTTL,
+		BreakerThreshold:          DefaultBreakerThreshold,
+		BreakerCooldown:           DefaultBreakerCooldown,
+	}
+	var err error
+
+	if cfg.Agify, err = loadProviderConfig("AGIFY", AgifyURL); err != nil {
+		return Config{}, err
+	}
+	if cfg.Genderize, err = loadProviderConfig("GENDERIZE", GenderizeURL); err != nil {
+		return Config{}, err
+	}
+	if cfg.Nationalize, err = loadProviderConfig("NATIONALIZE", NationalizeURL); err != nil {
+		return Config{}, err
+	}
+
+	if source := os.Getenv("ENRICH_SOURCE"); source != "" {
+		cfg.Source = strings.ToLower(source)
+	}
+	cfg.OfflineDataset = os.Getenv("ENRICH_OFFLINE_DATASET")
+	if cfg.Source != SourceHTTP && cfg.Source != SourceOffline {
+		return Config{}, fmt.Errorf("invalid ENRICH_SOURCE %q", cfg.Source)
+	}
+
+	if cfg.AgeProviders, err = loadChain("ENRICH_AGE_PROVIDERS", ProviderAgify); err != nil {
+		return Config{}, err
+	}
+	if cfg.GenderProviders, err = loadChain("ENRICH_GENDER_PROVIDERS", ProviderGenderize); err != nil {
+		return Config{}, err
+	}
+	if cfg.NationalityProviders, err = loadChain("ENRICH_NATIONALITY_PROVIDERS", ProviderNationalize); err != nil {
+		return Config{}, err
+	}
+	if cfg.usesOffline() && cfg.OfflineDataset == "" {
+		return Config{}, fmt.Errorf("ENRICH_OFFLINE_DATASET is required to use the %s provider", ProviderOffline)
+	}
+
+	if ensembleStr := os.Getenv("ENRICH_ENSEMBLE"); ensembleStr != "" {
+		for _, entry := range strings.Split(ensembleStr, ",") {
+			field := strings.ToLower(strings.TrimSpace(entry))
+			if field != FieldGender && field != FieldNationality {
+				return Config{}, fmt.Errorf("invalid ENRICH_ENSEMBLE: unknown field %q, expected %s or %s", entry, FieldGender, FieldNationality)
+			}
+			cfg.Ensemble = append(cfg.Ensemble, field)
+		}
+	}
+	for _, field := range cfg.cachedFields() {
+		if slices.Contains(cfg.Ensemble, field) {
+			return Config{}, fmt.Errorf("invalid ENRICH_ENSEMBLE: the %s providers list %s, which cannot vote in an ensemble", field, ProviderCache)
+		}
+	}
+	if cfg.EnsembleWeights, err = loadWeights("ENRICH_ENSEMBLE_WEIGHTS"); err != nil {
+		return Config{}, err
+	}
+
+	if minCountStr := os.Getenv("ENRICH_MIN_AGE_COUNT"); minCountStr != "" {
+		minCount, err := strconv.Atoi(minCountStr)
+		if err != nil || minCount < 0 {
+			return Config{}, fmt.Errorf("invalid ENRICH_MIN_AGE_COUNT %q", minCountStr)
+		}
+		cfg.MinAgeCount = minCount
+	}
+	if cfg.MinGenderProbability, err = loadProbability("ENRICH_MIN_GENDER_PROBABILITY", cfg.MinGenderProbability); err != nil {
+		return Config{}, err
+	}
+	if cfg.MinNationalityProbability, err = loadProbability("ENRICH_MIN_NATIONALITY_PROBABILITY", cfg.MinNationalityProbability); err != nil {
+		return Config{}, err
+	}
+
+	if ttlStr := os.Getenv("ENRICH_CACHE_TTL"); ttlStr != "" {
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil || ttl < 0 {
+			return Config{}, fmt.Errorf("invalid ENRICH_CACHE_TTL %q", ttlStr)
+		}
+		cfg.CacheTTL = ttl
+	}
+
+	if allowPartialStr := os.Getenv("ENRICH_ALLOW_PARTIAL"); allowPartialStr != "" {
+		allowPartial, err := strconv.ParseBool(allowPartialStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ENRICH_ALLOW_PARTIAL %q", allowPartialStr)
+		}
+		cfg.AllowPartial = allowPartial
+	}
+
+	if resolveCountryStr := os.Getenv("ENRICH_RESOLVE_COUNTRY"); resolveCountryStr != "" {
+		resolveCountry, err := strconv.ParseBool(resolveCountryStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ENRICH_RESOLVE_COUNTRY %q", resolveCountryStr)
+		}
+		cfg.ResolveCountry = resolveCountry
+	}
+
+	if rulesStr := os.Getenv("ENRICH_RULES"); rulesStr != "" {
+		rules, err := strconv.ParseBool(rulesStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ENRICH_RULES %q", rulesStr)
+		}
+		cfg.Rules = rules
+	}
+
+	if stripStr := os.Getenv("ENRICH_STRIP_DIACRITICS"); stripStr != "" {
+		strip, err := strconv.ParseBool(stripStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ENRICH_STRIP_DIACRITICS %q", stripStr)
+		}
+		cfg.StripDiacritics = strip
+	}
+
+	if transliterateStr := os.Getenv("ENRICH_TRANSLITERATE"); transliterateStr != "" {
+		transliterate, err := strconv.ParseBool(transliterateStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ENRICH_TRANSLITERATE %q", transliterateStr)
+		}
+		cfg.Transliterate = transliterate
+	}
+
+	if aliasesStr := os.Getenv("ENRICH_RESOLVE_ALIASES"); aliasesStr != "" {
+		aliases, err := strconv.ParseBool(aliasesStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ENRICH_RESOLVE_ALIASES %q", aliasesStr)
+		}
+		cfg.ResolveAliases = aliases
+	}
+
+	if thresholdStr := os.Getenv("ENRICH_BREAKER_THRESHOLD"); thresholdStr != "" {
+		threshold, err := strconv.Atoi(thresholdStr)
+		if err != nil || threshold < 0 {
+			return Config{}, fmt.Errorf("invalid ENRICH_BREAKER_THRESHOLD %q", thresholdStr)
+		}
+		cfg.BreakerThreshold = threshold
+	}
+
+	if cooldownStr := os.Getenv("ENRICH_BREAKER_COOLDOWN"); cooldownStr != "" {
+		cooldown, err := time.ParseDuration(cooldownStr)
+		if err != nil || cooldown <= 0 {
+			return Config{}, fmt.Errorf("invalid ENRICH_BREAKER_COOLDOWN %q", cooldownStr)
+		}
+		cfg.BreakerCooldown = cooldown
+	}
+
+	cfg.Cassette = os.Getenv("ENRICH_CASSETTE")
+	if mode := os.Getenv("ENRICH_CASSETTE_MODE"); mode != "" {
+		cfg.CassetteMode = strings.ToLower(mode)
+		if cfg.CassetteMode != CassetteRecord && cfg.CassetteMode != CassetteReplay {
+			return Config{}, fmt.Errorf("invalid ENRICH_CASSETTE_MODE %q", mode)
+		}
+		if cfg.Cassette == "" {
+			return Config{}, fmt.Errorf("ENRICH_CASSETTE is required to %s provider responses", cfg.CassetteMode)
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadProviderConfig(prefix, defaultURL string) (ProviderConfig, error) {
+	cfg := ProviderConfig{
+		BaseURL: defaultURL,
+		APIKey:  os.Getenv(prefix + "_API_KEY"),
+		Timeout: DefaultTimeout,
+	}
+
+	if baseURL := os.Getenv(prefix + "_URL"); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+
+	if timeoutStr := os.Getenv(prefix + "_TIMEOUT"); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return ProviderConfig{}, fmt.Errorf("invalid %s_TIMEOUT %q", prefix, timeoutStr)
+		}
+		cfg.Timeout = timeout
+	}
+
+	return cfg, nil
+}
+
+// loadChain reads a comma-separated list of providers from the environment
+// variable key, or nil when it is unset. Each entry must be httpProvider,
+// ProviderOffline or ProviderCache, at most once, and the cache cannot be the
+// only one.
+func loadChain(key, httpProvider string) ([]string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	var chain []string
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		provider := strings.ToLower(strings.TrimSpace(entry))
+		if provider != httpProvider && provider != ProviderOffline && provider != ProviderCache {
+			return nil, fmt.Errorf("invalid %s: unknown provider %q, expected %s, %s or %s", key, entry, httpProvider, ProviderOffline, ProviderCache)
+		}
+		if seen[provider] {
+			return nil, fmt.Errorf("invalid %s: provider %q is listed twice", key, provider)
+		}
+		seen[provider] = true
+		chain = append(chain, provider)
+	}
+	if len(chain) == 1 && chain[0] == ProviderCache {
+		return nil, fmt.Errorf("invalid %s: %s needs a provider to fall back on", key, ProviderCache)
+	}
+	return chain, nil
+}
+
+// loadWeights reads comma-separated provider:weight pairs from the
+// environment variable key.
+func loadWeights(key string) (map[string]float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	weights := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		provider, weightStr, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		provider = strings.ToLower(provider)
+		if !ok || !slices.Contains([]string{ProviderAgify, ProviderGenderize, ProviderNationalize, ProviderOffline}, provider) {
+			return nil, fmt.Errorf("invalid %s entry %q, expected provider:weight", key, entry)
+		}
+		weight, err := strconv.ParseFloat(weightStr, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid %s weight %q for %s", key, weightStr, provider)
+		}
+		weights[provider] = weight
+	}
+	return weights, nil
+}
+
+// weights returns the ensemble weights of the providers of a field, in order.
+func (cfg Config) weights(providers []string) []float64 {
+	weights := make([]float64, len(providers))
+	for i, provider := range providers {
+		weights[i] = 1
+		if weight, ok := cfg.EnsembleWeights[provider]; ok {
+			weights[i] = weight
+		}
+	}
+	return weights
+}
+
+func loadProbability(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	probability, err := strconv.ParseFloat(value, 64)
+	if err != nil || probability < 0 || probability > 1 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return probability, nil
+}
+
+// chain returns the providers configured for a field, defaulting to the
+// provider of Source: httpProvider or ProviderOffline.
+func (cfg Config) chain(providers []string, httpProvider string) []string {
+	if len(providers) > 0 {
+		return providers
+	}
+	if cfg.Source == SourceOffline {
+		return []string{ProviderOffline}
+	}
+	return []string{httpProvider}
+}
+
+// cachedFields lists the fields whose chains name ProviderCache.
+func (cfg Config) cachedFields() []string {
+	var fields []string
+	if slices.Contains(cfg.AgeProviders, ProviderCache) {
+		fields = append(fields, FieldAge)
+	}
+	if slices.Contains(cfg.GenderProviders, ProviderCache) {
+		fields = append(fields, FieldGender)
+	}
+	if slices.Contains(cfg.NationalityProviders, ProviderCache) {
+		fields = append(fields, FieldNationality)
+	}
+	return fields
+}
+
+// usesOffline reports whether any field is answered from the offline dataset.
+func (cfg Config) usesOffline() bool {
+	for _, chain := range [][]string{
+		cfg.chain(cfg.AgeProviders, ProviderAgify),
+		cfg.chain(cfg.GenderProviders, ProviderGenderize),
+		cfg.chain(cfg.NationalityProviders, ProviderNationalize),
+	} {
+		if slices.Contains(chain, ProviderOffline) {
+			return true
+		}
+	}
+	return false
+}
+
+// newBreaker returns the circuit breaker for provider, or nil when breakers
+// are disabled.
+func (cfg Config) newBreaker(provider string) *Breaker {
+	if cfg.BreakerThreshold == 0 {
+		return nil
+	}
+	return NewBreaker(provider, cfg.BreakerThreshold, cfg.BreakerCooldown)
+}
+
+// newCassette returns the cassette selected by cfg, or nil when there is none.
+func (cfg Config) newCassette() (*Cassette, error) {
+	switch cfg.CassetteMode {
+	case CassetteRecord:
+		logger.Log.Infof("Recording provider responses to %s", cfg.Cassette)
+		return NewCassette(cfg.Cassette), nil
+	case CassetteReplay:
+		return LoadCassette(cfg.Cassette)
+	}
+	return nil, nil
+}
+
+// newClient returns the HTTP client of a provider, going through cassette
+// when it is set.
+func newClient(provider ProviderConfig, cassette *Cassette) *http.Client {
+	client := &http.Client{Timeout: provider.Timeout}
+	if cassette != nil {
+		client.Transport = cassette.Transport(nil)
+	}
+	return client
+}
+
+// NewProviders builds the providers described by cfg. When a field lists
+// several providers they are combined into a chain, or into an ensemble for
+// the fields in cfg.Ensemble. A ProviderCache entry answers from cfg.Cache at
+// its place in the chain. If any field uses the
+// offline provider the dataset is loaded into memory, which fails if it
+// cannot be read, as does replaying a cassette that cannot be read.
+func NewProviders(cfg Config) (Providers, error) {
+	cassette, err := cfg.newCassette()
+	if err != nil {
+		return Providers{}, err
+	}
+
+	var offline *Offline
+	if cfg.usesOffline() {
+		if offline, err = LoadOffline(cfg.OfflineDataset); err != nil {
+			return Providers{}, err
+		}
+	}
+
+	var ages []AgeProvider
+	for _, name := range cfg.chain(cfg.AgeProviders, ProviderAgify) {
+		switch name {
+		case ProviderOffline:
+			ages = append(ages, offline)
+			continue
+		case ProviderCache:
+			if cfg.Cache != nil {
+				ages = append(ages, cacheProvider{Cache: cfg.Cache})
+			}
+			continue
+		}
+		ages = append(ages, &Agify{
+			BaseURL: cfg.Agify.BaseURL,
+			APIKey:  cfg.Agify.APIKey,
+			Client:  newClient(cfg.Agify, cassette),
+			Quota:   NewQuota(ProviderAgify),
+			Breaker: cfg.newBreaker(ProviderAgify),
+		})
+	}
+
+	var genders []GenderProvider
+	for _, name := range cfg.chain(cfg.GenderProviders, ProviderGenderize) {
+		switch name {
+		case ProviderOffline:
+			genders = append(genders, offline)
+			continue
+		case ProviderCache:
+			if cfg.Cache != nil {
+				genders = append(genders, cacheProvider{Cache: cfg.Cache})
+			}
+			continue
+		}
+		genders = append(genders, &Genderize{
+			BaseURL: cfg.Genderize.BaseURL,
+			APIKey:  cfg.Genderize.APIKey,
+			Client:  newClient(cfg.Genderize, cassette),
+			Quota:   NewQuota(ProviderGenderize),
+			Breaker: cfg.newBreaker(ProviderGenderize),
+		})
+	}
+
+	var nationalities []NationalityProvider
+	for _, name := range cfg.chain(cfg.NationalityProviders, ProviderNationalize) {
+		switch name {
+		case ProviderOffline:
+			nationalities = append(nationalities, offline)
+			continue
+		case ProviderCache:
+			if cfg.Cache != nil {
+				nationalities = append(nationalities, cacheProvider{Cache: cfg.Cache})
+			}
+			continue
+		}
+		nationalities = append(nationalities, &Nationalize{
+			BaseURL: cfg.Nationalize.BaseURL,
+			APIKey:  cfg.Nationalize.APIKey,
+			Client:  newClient(cfg.Nationalize, cassette),
+			Quota:   NewQuota(ProviderNationalize),
+			Breaker: cfg.newBreaker(ProviderNationalize),
+		})
+	}
+
+	providers := Providers{
+		Age:            AgeChain{Providers: ages, MinCount: cfg.MinAgeCount},
+		Gender:         GenderChain{Providers: genders, MinProbability: cfg.MinGenderProbability},
+		Nationality:    NationalityChain{Providers: nationalities, MinProbability: cfg.MinNationalityProbability},
+		ResolveCountry: cfg.ResolveCountry,
+		CacheInChains:  cfg.Cache != nil && len(cfg.cachedFields()) > 0,
+	}
+	// A chain of one adds nothing but indirection.
+	if len(ages) == 1 {
+		providers.Age = ages[0]
+	}
+	if len(genders) == 1 {
+		providers.Gender = genders[0]
+	}
+	if len(nationalities) == 1 {
+		providers.Nationality = nationalities[0]
+	}
+
+	if len(genders) > 1 && slices.Contains(cfg.Ensemble, FieldGender) {
+		providers.Gender = GenderEnsemble{
+			Providers: genders,
+			Weights:   cfg.weights(cfg.chain(cfg.GenderProviders, ProviderGenderize)),
+		}
+	}
+	if len(nationalities) > 1 && slices.Contains(cfg.Ensemble, FieldNationality) {
+		providers.Nationality = NationalityEnsemble{
+			Providers: nationalities,
+			Weights:   cfg.weights(cfg.chain(cfg.NationalityProviders, ProviderNationalize)),
+		}
+	}
+	return providers, nil
+}
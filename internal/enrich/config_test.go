@@ -0,0 +1,303 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if cfg.Agify.BaseURL != AgifyURL || cfg.Genderize.BaseURL != GenderizeURL || cfg.Nationalize.BaseURL != NationalizeURL {
+			t.Errorf("Unexpected default URLs: %+v", cfg)
+		}
+		if cfg.Agify.Timeout != DefaultTimeout {
+			t.Errorf("Timeout = %v, want %v", cfg.Agify.Timeout, DefaultTimeout)
+		}
+		if !cfg.Transliterate {
+			t.Errorf("Transliterate = false, want true")
+		}
+	})
+
+	t.Run("FromEnvironment", func(t *testing.T) {
+		t.Setenv("GENDERIZE_URL", "http://localhost:9000")
+		t.Setenv("GENDERIZE_API_KEY", "secret")
+		t.Setenv("GENDERIZE_TIMEOUT", "250ms")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := ProviderConfig{BaseURL: "http://localhost:9000", APIKey: "secret", Timeout: 250 * time.Millisecond}
+		if cfg.Genderize != want {
+			t.Errorf("Genderize config = %+v, want %+v", cfg.Genderize, want)
+		}
+		if cfg.Agify.BaseURL != AgifyURL {
+			t.Errorf("Agify config changed unexpectedly: %+v", cfg.Agify)
+		}
+	})
+
+	t.Run("AllowPartial", func(t *testing.T) {
+		t.Setenv("ENRICH_ALLOW_PARTIAL", "true")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !cfg.AllowPartial {
+			t.Errorf("AllowPartial = false, want true")
+		}
+	})
+
+	t.Run("InvalidAllowPartial", func(t *testing.T) {
+		t.Setenv("ENRICH_ALLOW_PARTIAL", "sometimes")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+
+	t.Run("BreakerDisabled", func(t *testing.T) {
+		t.Setenv("ENRICH_BREAKER_THRESHOLD", "0")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		providers, err := NewProviders(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if breakers := providers.Breakers(); len(breakers) != 0 {
+			t.Errorf("Breakers() = %v, want none", breakers)
+		}
+	})
+
+	t.Run("OfflineSource", func(t *testing.T) {
+		t.Setenv("ENRICH_SOURCE", "offline")
+		t.Setenv("ENRICH_OFFLINE_DATASET", "testdata/names.csv")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		providers, err := NewProviders(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, ok := providers.Age.(*Offline); !ok {
+			t.Errorf("Age provider = %T, want *Offline", providers.Age)
+		}
+	})
+
+	t.Run("OfflineSourceWithoutDataset", func(t *testing.T) {
+		t.Setenv("ENRICH_SOURCE", "offline")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+
+	t.Run("ProviderChains", func(t *testing.T) {
+		t.Setenv("ENRICH_AGE_PROVIDERS", "offline, agify")
+		t.Setenv("ENRICH_OFFLINE_DATASET", "testdata/names.csv")
+		t.Setenv("ENRICH_MIN_GENDER_PROBABILITY", "0.9")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.MinGenderProbability != 0.9 {
+			t.Errorf("MinGenderProbability = %v, want 0.9", cfg.MinGenderProbability)
+		}
+		providers, err := NewProviders(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		chain, ok := providers.Age.(AgeChain)
+		if !ok || len(chain.Providers) != 2 {
+			t.Fatalf("Age provider = %#v, want a chain of two", providers.Age)
+		}
+		if _, ok := chain.Providers[0].(*Offline); !ok {
+			t.Errorf("First age provider = %T, want *Offline", chain.Providers[0])
+		}
+		if _, ok := providers.Gender.(*Genderize); !ok {
+			t.Errorf("Gender provider = %T, want *Genderize", providers.Gender)
+		}
+	})
+
+	t.Run("CacheInChain", func(t *testing.T) {
+		t.Setenv("ENRICH_AGE_PROVIDERS", "offline,cache,agify")
+		t.Setenv("ENRICH_OFFLINE_DATASET", "testdata/names.csv")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cfg.Cache = &memoryCache{entries: map[string]Result{}}
+		providers, err := NewProviders(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		chain, ok := providers.Age.(AgeChain)
+		if !ok || len(chain.Providers) != 3 {
+			t.Fatalf("Age provider = %#v, want a chain of three", providers.Age)
+		}
+		if _, ok := chain.Providers[1].(cacheProvider); !ok {
+			t.Errorf("Second age provider = %T, want cacheProvider", chain.Providers[1])
+		}
+		if !providers.CacheInChains {
+			t.Errorf("CacheInChains = false, want true")
+		}
+
+		cfg.Cache = nil
+		if providers, err = NewProviders(cfg); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if chain, ok := providers.Age.(AgeChain); !ok || len(chain.Providers) != 2 || providers.CacheInChains {
+			t.Errorf("Age provider = %#v, want a chain of two without the disabled cache", providers.Age)
+		}
+	})
+
+	t.Run("Ensemble", func(t *testing.T) {
+		t.Setenv("ENRICH_GENDER_PROVIDERS", "genderize,offline")
+		t.Setenv("ENRICH_OFFLINE_DATASET", "testdata/names.csv")
+		t.Setenv("ENRICH_ENSEMBLE", "gender")
+		t.Setenv("ENRICH_ENSEMBLE_WEIGHTS", "genderize:2")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		providers, err := NewProviders(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ensemble, ok := providers.Gender.(GenderEnsemble)
+		if !ok {
+			t.Fatalf("Gender provider = %T, want GenderEnsemble", providers.Gender)
+		}
+		if !reflect.DeepEqual(ensemble.Weights, []float64{2, 1}) {
+			t.Errorf("Weights = %v, want [2 1]", ensemble.Weights)
+		}
+	})
+
+	t.Run("InvalidEnsemble", func(t *testing.T) {
+		for key, value := range map[string]string{"ENRICH_ENSEMBLE": "age", "ENRICH_ENSEMBLE_WEIGHTS": "genderize=2"} {
+			t.Run(key, func(t *testing.T) {
+				t.Setenv(key, value)
+
+				if _, err := LoadConfig(); err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+			})
+		}
+	})
+
+	t.Run("CachedEnsemble", func(t *testing.T) {
+		t.Setenv("ENRICH_GENDER_PROVIDERS", "cache,genderize")
+		t.Setenv("ENRICH_ENSEMBLE", "gender")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+
+	t.Run("InvalidProviderChain", func(t *testing.T) {
+		for _, chain := range []string{"genderize", "agify,agify", "agify,", "cache"} {
+			t.Setenv("ENRICH_AGE_PROVIDERS", chain)
+
+			if _, err := LoadConfig(); err == nil {
+				t.Errorf("Expected error for %q, got nil", chain)
+			}
+		}
+	})
+
+	t.Run("OfflineChainWithoutDataset", func(t *testing.T) {
+		t.Setenv("ENRICH_NATIONALITY_PROVIDERS", "nationalize,offline")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+
+	t.Run("InvalidBreakerCooldown", func(t *testing.T) {
+		t.Setenv("ENRICH_BREAKER_COOLDOWN", "0s")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+
+	t.Run("Cassette", func(t *testing.T) {
+		t.Setenv("ENRICH_CASSETTE_MODE", "Replay")
+		t.Setenv("ENRICH_CASSETTE", "testdata/cassette.json")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.CassetteMode != CassetteReplay || cfg.Cassette != "testdata/cassette.json" {
+			t.Errorf("Cassette = %s %q, want replay of testdata/cassette.json", cfg.CassetteMode, cfg.Cassette)
+		}
+	})
+
+	t.Run("InvalidCassette", func(t *testing.T) {
+		for mode, path := range map[string]string{"rewind": "cassette.json", "record": ""} {
+			t.Run(mode, func(t *testing.T) {
+				t.Setenv("ENRICH_CASSETTE_MODE", mode)
+				t.Setenv("ENRICH_CASSETTE", path)
+
+				if _, err := LoadConfig(); err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+			})
+		}
+	})
+
+	t.Run("InvalidTimeout", func(t *testing.T) {
+		t.Setenv("NATIONALIZE_TIMEOUT", "soon")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestNewProvidersSendsAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("apikey"); got != "secret" {
+			t.Errorf("apikey = %q, want %q", got, "secret")
+		}
+		if got := r.URL.Query().Get("name"); got != "John Paul" {
+			t.Errorf("name = %q, want %q", got, "John Paul")
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"age": 40})
+	}))
+	defer server.Close()
+
+	providers, err := NewProviders(Config{
+		Agify: ProviderConfig{BaseURL: server.URL + "/", APIKey: "secret", Timeout: time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	age, err := providers.Age.Age(context.Background(), "John Paul")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !age.Known() || *age.Age != 40 {
+		t.Errorf("Age = %+v, want 40", age)
+	}
+}
@@ -0,0 +1,151 @@
+// Package enrich predicts age, gender and nationality for a first name.
+//
+// The handlers depend only on the provider interfaces declared here, so the
+// public agify/genderize/nationalize APIs can be swapped for other services,
+// test doubles or in-house models without touching the HTTP layer.
+package enrich
+
+import "context"
+
+// AgePrediction is an estimated age and the number of samples it is based on.
+// Age is nil when the provider has no estimate for the name.
+type AgePrediction struct {
+	Age   *int `json:"age"`
+	Count int  `json:"count"`
+	// Source is the name of the provider that answered.
+	Source string `json:"source,omitempty"`
+}
+
+// Known reports whether the provider returned an age.
+func (p AgePrediction) Known() bool {
+	return p.Age != nil
+}
+
+// GenderPrediction is an estimated gender with its probability and sample count.
+// Gender is empty when the provider has no estimate for the name.
+type GenderPrediction struct {
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+	// Source is the name of the provider that answered.
+	Source string `json:"source,omitempty"`
+	// Members holds the prediction of every provider a GenderEnsemble combined.
+	Members []GenderPrediction `json:"members,omitempty"`
+}
+
+// Known reports whether the provider returned a gender.
+func (p GenderPrediction) Known() bool {
+	return p.Gender != ""
+}
+
+// CountryProbability is a single entry of a nationality distribution.
+type CountryProbability struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+// NationalityPrediction is the most likely country code and its probability.
+// Countries holds the full distribution, ordered from most to least likely.
+// CountryID is empty when the provider returned no countries for the name.
+type NationalityPrediction struct {
+	CountryID   string               `json:"country_id"`
+	Probability float64              `json:"probability"`
+	Countries   []CountryProbability `json:"countries,omitempty"`
+	// Source is the name of the provider that answered.
+	Source string `json:"source,omitempty"`
+	// Members holds the prediction of every provider a NationalityEnsemble combined.
+	Members []NationalityPrediction `json:"members,omitempty"`
+}
+
+// Known reports whether the provider returned at least one country.
+func (p NationalityPrediction) Known() bool {
+	return p.CountryID != ""
+}
+
+// AgeProvider predicts the age of a person by first name.
+type AgeProvider interface {
+	Age(ctx context.Context, name string) (AgePrediction, error)
+}
+
+// GenderProvider predicts the gender of a person by first name.
+type GenderProvider interface {
+	Gender(ctx context.Context, name string) (GenderPrediction, error)
+}
+
+// NationalityProvider predicts the most likely country code for a first name.
+type NationalityProvider interface {
+	Nationality(ctx context.Context, name string) (NationalityPrediction, error)
+}
+
+// BatchAgeProvider is an AgeProvider that can answer several names with a
+// single request. Predictions are returned in the order of names.
+type BatchAgeProvider interface {
+	AgeProvider
+	AgeBatch(ctx context.Context, names []string) ([]AgePrediction, error)
+}
+
+// BatchGenderProvider is a GenderProvider that can answer several names with a
+// single request. Predictions are returned in the order of names.
+type BatchGenderProvider interface {
+	GenderProvider
+	GenderBatch(ctx context.Context, names []string) ([]GenderPrediction, error)
+}
+
+// BatchNationalityProvider is a NationalityProvider that can answer several
+// names with a single request. Predictions are returned in the order of names.
+type BatchNationalityProvider interface {
+	NationalityProvider
+	NationalityBatch(ctx context.Context, names []string) ([]NationalityPrediction, error)
+}
+
+// LocalizedAgeProvider is an AgeProvider that can narrow its prediction to
+// the people of a country, given as an ISO 3166-1 alpha-2 code.
+type LocalizedAgeProvider interface {
+	AgeProvider
+	LocalizedAge(ctx context.Context, name, countryID string) (AgePrediction, error)
+}
+
+// LocalizedGenderProvider is a GenderProvider that can narrow its prediction
+// to the people of a country, given as an ISO 3166-1 alpha-2 code.
+type LocalizedGenderProvider interface {
+	GenderProvider
+	LocalizedGender(ctx context.Context, name, countryID string) (GenderPrediction, error)
+}
+
+// LocalizedBatchAgeProvider is a BatchAgeProvider whose batches can be
+// localized to a single country.
+type LocalizedBatchAgeProvider interface {
+	BatchAgeProvider
+	LocalizedAgeBatch(ctx context.Context, names []string, countryID string) ([]AgePrediction, error)
+}
+
+// LocalizedBatchGenderProvider is a BatchGenderProvider whose batches can be
+// localized to a single country.
+type LocalizedBatchGenderProvider interface {
+	BatchGenderProvider
+	LocalizedGenderBatch(ctx context.Context, names []string, countryID string) ([]GenderPrediction, error)
+}
+
+// Query is a name to enrich. CountryID, an ISO 3166-1 alpha-2 code, localizes
+// the age and gender predictions of providers that support it. Surname and
+// Patronymic are never sent to the providers; they feed the Enricher's Rules.
+type Query struct {
+	Name       string
+	CountryID  string
+	Surname    string
+	Patronymic string
+}
+
+// Providers groups the providers used to enrich a person.
+type Providers struct {
+	Age         AgeProvider
+	Gender      GenderProvider
+	Nationality NationalityProvider
+	// ResolveCountry localizes the age and gender of queries without a
+	// CountryID to the most likely nationality. The nationality is then
+	// looked up first instead of concurrently with the other fields.
+	ResolveCountry bool
+	// CacheInChains reports that the chains ask the Enricher's Cache at the
+	// places they list ProviderCache, so it is not consulted before them.
+	CacheInChains bool
+}
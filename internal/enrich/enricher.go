@@ -0,0 +1,464 @@
+package enrich
+
+import (
+	"NameEnricher/internal/models"
+	"NameEnricher/internal/translit"
+	"NameEnricher/pkg/logger"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field names used to report per-provider outcomes.
+const (
+	FieldAge         = "age"
+	FieldGender      = "gender"
+	FieldNationality = "nationality"
+)
+
+// Result is the combined outcome of all providers for a single name.
+type Result struct {
+	Age         AgePrediction
+	Gender      GenderPrediction
+	Nationality NationalityPrediction
+}
+
+// Error aggregates the failures of every provider that did not answer.
+type Error struct {
+	Failures map[string]error
+}
+
+func (e *Error) Error() string {
+	fields := e.Fields()
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field+": "+e.Failures[field].Error())
+	}
+	return "enrichment failed: " + strings.Join(messages, "; ")
+}
+
+func (e *Error) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, field := range e.Fields() {
+		errs = append(errs, e.Failures[field])
+	}
+	return errs
+}
+
+// Fields returns the names of the failed fields in a stable order.
+func (e *Error) Fields() []string {
+	fields := make([]string, 0, len(e.Failures))
+	for field := range e.Failures {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Enrich queries all providers concurrently. Cancelling ctx aborts the
+// in-flight requests. If any provider fails, the returned error is an *Error
+// describing every failure; the fields that succeeded are still set on the
+// returned Result.
+//
+// Age and gender are localized to q.CountryID or, with ResolveCountry, to the
+// nationality found for the name.
+func (p Providers) Enrich(ctx context.Context, q Query) (Result, error) {
+	var (
+		result   Result
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures = make(map[string]error)
+	)
+
+	run := func(field string, lookup func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := lookup(); err != nil {
+				mu.Lock()
+				failures[field] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	countryID := q.CountryID
+	if countryID == "" && p.ResolveCountry {
+		nationality, err := p.Nationality.Nationality(ctx, q.Name)
+		result.Nationality = nationality
+		if err != nil {
+			failures[FieldNationality] = err
+		} else if nationality.Known() {
+			countryID = nationality.CountryID
+		}
+	} else {
+		run(FieldNationality, func() (err error) {
+			result.Nationality, err = p.Nationality.Nationality(ctx, q.Name)
+			return err
+		})
+	}
+
+	ageSingle, _ := ageLookups(p.Age)
+	genderSingle, _ := genderLookups(p.Gender)
+	run(FieldAge, func() (err error) {
+		result.Age, err = ageSingle(ctx, q.Name, countryID)
+		return err
+	})
+	run(FieldGender, func() (err error) {
+		result.Gender, err = genderSingle(ctx, q.Name, countryID)
+		return err
+	})
+
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return result, &Error{Failures: failures}
+	}
+	return result, nil
+}
+
+// EnrichBatch enriches several names, grouping them into name[] requests of
+// up to MaxBatchSize names for providers that support it. Names localized to
+// different countries go into different requests. Providers without batch
+// support are queried once per name, and chains only pass the names still
+// unanswered on to their next member. The returned slices are parallel to
+// queries; errs[i] is an *Error when a provider failed for queries[i].
+func (p Providers) EnrichBatch(ctx context.Context, queries []Query) ([]Result, []error) {
+	var (
+		wg                                   sync.WaitGroup
+		ages                                 []AgePrediction
+		genders                              []GenderPrediction
+		nationalities                        []NationalityPrediction
+		ageErrs, genderErrs, nationalityErrs []error
+	)
+
+	names := make([]string, len(queries))
+	countries := make([]string, len(queries))
+	for i, q := range queries {
+		names[i] = q.Name
+		countries[i] = q.CountryID
+	}
+
+	nationalitySingle, nationalityBatch := nationalityLookups(p.Nationality)
+
+	if p.ResolveCountry {
+		nationalities, nationalityErrs = lookupAll(ctx, p.Nationality, names, nil, nationalitySingle, nationalityBatch)
+		for i := range queries {
+			if countries[i] == "" && nationalityErrs[i] == nil && nationalities[i].Known() {
+				countries[i] = nationalities[i].CountryID
+			}
+		}
+	} else {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nationalities, nationalityErrs = lookupAll(ctx, p.Nationality, names, nil, nationalitySingle, nationalityBatch)
+		}()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		single, batch := ageLookups(p.Age)
+		ages, ageErrs = lookupAll(ctx, p.Age, names, countries, single, batch)
+	}()
+	go func() {
+		defer wg.Done()
+		single, batch := genderLookups(p.Gender)
+		genders, genderErrs = lookupAll(ctx, p.Gender, names, countries, single, batch)
+	}()
+	wg.Wait()
+
+	results := make([]Result, len(queries))
+	errs := make([]error, len(queries))
+	for i := range queries {
+		results[i] = Result{Age: ages[i], Gender: genders[i], Nationality: nationalities[i]}
+
+		failures := make(map[string]error)
+		if ageErrs[i] != nil {
+			failures[FieldAge] = ageErrs[i]
+		}
+		if genderErrs[i] != nil {
+			failures[FieldGender] = genderErrs[i]
+		}
+		if nationalityErrs[i] != nil {
+			failures[FieldNationality] = nationalityErrs[i]
+		}
+		if len(failures) > 0 {
+			errs[i] = &Error{Failures: failures}
+		}
+	}
+	return results, errs
+}
+
+// ageLookups returns the single and batch lookups of provider, localized to a
+// country when the provider supports it. batch is nil without batch support.
+func ageLookups(provider AgeProvider) (
+	single func(ctx context.Context, name, countryID string) (AgePrediction, error),
+	batch func(ctx context.Context, names []string, countryID string) ([]AgePrediction, error)) {
+	single = func(ctx context.Context, name, countryID string) (AgePrediction, error) {
+		if localized, ok := provider.(LocalizedAgeProvider); ok && countryID != "" {
+			return localized.LocalizedAge(ctx, name, countryID)
+		}
+		return provider.Age(ctx, name)
+	}
+	if b, ok := provider.(BatchAgeProvider); ok {
+		batch = func(ctx context.Context, names []string, countryID string) ([]AgePrediction, error) {
+			if localized, ok := provider.(LocalizedBatchAgeProvider); ok && countryID != "" {
+				return localized.LocalizedAgeBatch(ctx, names, countryID)
+			}
+			return b.AgeBatch(ctx, names)
+		}
+	}
+	return single, batch
+}
+
+// genderLookups is the gender counterpart of ageLookups.
+func genderLookups(provider GenderProvider) (
+	single func(ctx context.Context, name, countryID string) (GenderPrediction, error),
+	batch func(ctx context.Context, names []string, countryID string) ([]GenderPrediction, error)) {
+	single = func(ctx context.Context, name, countryID string) (GenderPrediction, error) {
+		if localized, ok := provider.(LocalizedGenderProvider); ok && countryID != "" {
+			return localized.LocalizedGender(ctx, name, countryID)
+		}
+		return provider.Gender(ctx, name)
+	}
+	if b, ok := provider.(BatchGenderProvider); ok {
+		batch = func(ctx context.Context, names []string, countryID string) ([]GenderPrediction, error) {
+			if localized, ok := provider.(LocalizedBatchGenderProvider); ok && countryID != "" {
+				return localized.LocalizedGenderBatch(ctx, names, countryID)
+			}
+			return b.GenderBatch(ctx, names)
+		}
+	}
+	return single, batch
+}
+
+// nationalityLookups is the nationality counterpart of ageLookups. Nationality
+// predictions are never localized, so countryID is ignored.
+func nationalityLookups(provider NationalityProvider) (
+	single func(ctx context.Context, name, countryID string) (NationalityPrediction, error),
+	batch func(ctx context.Context, names []string, countryID string) ([]NationalityPrediction, error)) {
+	single = func(ctx context.Context, name, _ string) (NationalityPrediction, error) {
+		return provider.Nationality(ctx, name)
+	}
+	if b, ok := provider.(BatchNationalityProvider); ok {
+		batch = func(ctx context.Context, names []string, _ string) ([]NationalityPrediction, error) {
+			return b.NationalityBatch(ctx, names)
+		}
+	}
+	return single, batch
+}
+
+// lookupBatch runs a single provider for every name, localized to the country
+// at the same index of countries (nil means none). With batch set, names of
+// the same country are sent in chunks of MaxBatchSize and a failed chunk fails
+// all of its names; otherwise single is called concurrently for each name.
+func lookupBatch[T any](ctx context.Context, names, countries []string,
+	single func(context.Context, string, string) (T, error),
+	batch func(context.Context, []string, string) ([]T, error)) ([]T, []error) {
+	predictions := make([]T, len(names))
+	errs := make([]error, len(names))
+	countryOf := func(i int) string {
+		if countries == nil {
+			return ""
+		}
+		return countries[i]
+	}
+
+	if batch == nil {
+		var wg sync.WaitGroup
+		for i, name := range names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				predictions[i], errs[i] = single(ctx, name, countryOf(i))
+			}()
+		}
+		wg.Wait()
+		return predictions, errs
+	}
+
+	// A request is localized to a single country, so names are grouped by it.
+	var order []string
+	groups := make(map[string][]int)
+	for i := range names {
+		country := countryOf(i)
+		if _, ok := groups[country]; !ok {
+			order = append(order, country)
+		}
+		groups[country] = append(groups[country], i)
+	}
+
+	for _, country := range order {
+		indexes := groups[country]
+		for start := 0; start < len(indexes); start += MaxBatchSize {
+			chunk := indexes[start:min(start+MaxBatchSize, len(indexes))]
+			chunkNames := make([]string, len(chunk))
+			for j, i := range chunk {
+				chunkNames[j] = names[i]
+			}
+
+			chunkPredictions, err := batch(ctx, chunkNames, country)
+			if errors.Is(err, ErrInvalidName) && len(chunk) > 1 {
+				// One rejected name fails the whole request; look the names up one
+				// by one so only that name is reported as invalid.
+				chunkCountries := make([]string, len(chunk))
+				for j := range chunkCountries {
+					chunkCountries[j] = country
+				}
+				var chunkErrs []error
+				chunkPredictions, chunkErrs = lookupBatch(ctx, chunkNames, chunkCountries, single, nil)
+				for j, i := range chunk {
+					predictions[i], errs[i] = chunkPredictions[j], chunkErrs[j]
+				}
+				continue
+			}
+			for j, i := range chunk {
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				predictions[i] = chunkPredictions[j]
+			}
+		}
+	}
+	return predictions, errs
+}
+
+// Enricher is the service the handlers use to enrich a name. When a Cache is
+// set, it is consulted before any provider is called, or only where the chains
+// list ProviderCache when Providers.CacheInChains is set. It is refreshed after
+// every fully successful lookup unless all of the answers came from it;
+// cached predictions carry SourceCachePrefix. When Rules are set, they are
+// applied to every result, cached or not; the cache only holds the provider
+// answers.
+type Enricher struct {
+	Providers Providers
+	Cache     Cache
+	Rules     *Rules
+	// StripDiacritics makes "José" and "Jose" the same name for the cache
+	// and the providers.
+	StripDiacritics bool
+	// Transliterate sends Cyrillic names to the providers in their Latin
+	// form, see translit.Latin.
+	Transliterate bool
+	// Aliases, when set, replaces aliased first names by their canonical form.
+	Aliases Aliases
+}
+
+func NewEnricher(providers Providers, cache Cache) *Enricher {
+	return &Enricher{Providers: providers, Cache: cache}
+}
+
+// Enrich returns the cached result for q or queries the providers.
+// Cache errors are logged and never fail the enrichment.
+func (e *Enricher) Enrich(ctx context.Context, q Query) (Result, error) {
+	q = e.normalize(ctx, q)
+	key := q.cacheKey()
+
+	if e.Cache != nil && !e.Providers.CacheInChains {
+		result, found, err := e.Cache.Get(ctx, key)
+		if err != nil {
+			logger.Log.Warnf("Failed to read enrichment cache for name %s: %v", q.Name, err)
+		} else if found {
+			logger.Log.Debugf("Using cached enrichment for name %s", q.Name)
+			return e.Rules.apply(q, fromCache(result)), nil
+		}
+	}
+
+	result, err := e.Providers.Enrich(ctx, q)
+	if err != nil {
+		return e.Rules.apply(q, result), err
+	}
+
+	if e.Cache != nil && !reused(result) {
+		if err := e.Cache.Set(ctx, key, toCache(result)); err != nil {
+			logger.Log.Warnf("Failed to store enrichment cache for name %s: %v", q.Name, err)
+		}
+	}
+	return e.Rules.apply(q, result), nil
+}
+
+// EnrichBatch is the batch counterpart of Enrich. Cached queries are answered
+// from the cache, the rest are deduplicated by cache key and sent to the
+// providers with Providers.EnrichBatch. The returned slices are parallel to queries.
+func (e *Enricher) EnrichBatch(ctx context.Context, queries []Query) ([]Result, []error) {
+	results := make([]Result, len(queries))
+	errs := make([]error, len(queries))
+
+	// positions maps every cache key that still needs the providers to the
+	// indexes of queries it answers.
+	positions := make(map[string][]int)
+	var misses []Query
+	queries = slices.Clone(queries)
+	for i, q := range queries {
+		q = e.normalize(ctx, q)
+		queries[i] = q
+		key := q.cacheKey()
+		if e.Cache != nil && !e.Providers.CacheInChains {
+			result, found, err := e.Cache.Get(ctx, key)
+			if err != nil {
+				logger.Log.Warnf("Failed to read enrichment cache for name %s: %v", q.Name, err)
+			} else if found {
+				logger.Log.Debugf("Using cached enrichment for name %s", q.Name)
+				results[i] = e.Rules.apply(q, fromCache(result))
+				continue
+			}
+		}
+		if _, ok := positions[key]; !ok {
+			misses = append(misses, q)
+		}
+		positions[key] = append(positions[key], i)
+	}
+
+	if len(misses) == 0 {
+		return results, errs
+	}
+	logger.Log.Debugf("Enriching %d of %d names with the providers", len(misses), len(queries))
+
+	fetched, fetchErrs := e.Providers.EnrichBatch(ctx, misses)
+	for j, q := range misses {
+		key := q.cacheKey()
+		for _, i := range positions[key] {
+			results[i] = e.Rules.apply(queries[i], fetched[j])
+			errs[i] = fetchErrs[j]
+		}
+
+		if fetchErrs[j] == nil && e.Cache != nil && !reused(fetched[j]) {
+			if err := e.Cache.Set(ctx, key, toCache(fetched[j])); err != nil {
+				logger.Log.Warnf("Failed to store enrichment cache for name %s: %v", q.Name, err)
+			}
+		}
+	}
+	return results, errs
+}
+
+// normalize cleans up the names of q and resolves an aliased first name
+// before they are looked up. The providers receive the cleaned name as it is;
+// the cache is keyed by its case-folded form. Alias errors are logged and
+// leave the name as it is.
+func (e *Enricher) normalize(ctx context.Context, q Query) Query {
+	q.Name = models.CleanName(q.Name)
+	q.Surname = models.CleanName(q.Surname)
+	q.Patronymic = models.CleanName(q.Patronymic)
+	if e.Aliases != nil {
+		canonical, found, err := e.Aliases.Canonical(ctx, q.Name)
+		if err != nil {
+			logger.Log.Warnf("Failed to resolve alias of name %s: %v", q.Name, err)
+		} else if found {
+			logger.Log.Debugf("Enriching name %s as %s", q.Name, canonical)
+			q.Name = models.CleanName(canonical)
+		}
+	}
+	if e.Transliterate {
+		q.Name = translit.Latin(q.Name)
+	}
+	if e.StripDiacritics {
+		q.Name = models.StripDiacritics(q.Name)
+	}
+	return q
+}
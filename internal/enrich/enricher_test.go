@@ -0,0 +1,598 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubAge struct {
+	age     AgePrediction
+	err     error
+	barrier func(ctx context.Context) error
+}
+
+func (s stubAge) Age(ctx context.Context, _ string) (AgePrediction, error) {
+	if s.barrier != nil {
+		if err := s.barrier(ctx); err != nil {
+			return AgePrediction{}, err
+		}
+	}
+	return s.age, s.err
+}
+
+type stubGender struct {
+	gender  GenderPrediction
+	err     error
+	barrier func(ctx context.Context) error
+}
+
+func (s stubGender) Gender(ctx context.Context, _ string) (GenderPrediction, error) {
+	if s.barrier != nil {
+		if err := s.barrier(ctx); err != nil {
+			return GenderPrediction{}, err
+		}
+	}
+	return s.gender, s.err
+}
+
+type stubNationality struct {
+	nationality NationalityPrediction
+	err         error
+	barrier     func(ctx context.Context) error
+}
+
+func (s stubNationality) Nationality(ctx context.Context, _ string) (NationalityPrediction, error) {
+	if s.barrier != nil {
+		if err := s.barrier(ctx); err != nil {
+			return NationalityPrediction{}, err
+		}
+	}
+	return s.nationality, s.err
+}
+
+var (
+	john = Result{
+		Age:    AgePrediction{Age: intPtr(35), Count: 1200},
+		Gender: GenderPrediction{Gender: "male", Probability: 0.99, Count: 5000},
+		Nationality: NationalityPrediction{
+			CountryID:   "US",
+			Probability: 0.8,
+			Countries:   []CountryProbability{{CountryID: "US", Probability: 0.8}, {CountryID: "GB", Probability: 0.1}},
+		},
+	}
+	mary = Result{
+		Age:         AgePrediction{Age: intPtr(28), Count: 900},
+		Gender:      GenderPrediction{Gender: "female", Probability: 0.98, Count: 4000},
+		Nationality: NationalityPrediction{CountryID: "GB", Probability: 0.3},
+	}
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// newBarrier returns a function that blocks until n callers are waiting on it.
+func newBarrier(n int) func(ctx context.Context) error {
+	arrived := make(chan struct{}, n)
+	release := make(chan struct{})
+	go func() {
+		for i := 0; i < n; i++ {
+			<-arrived
+		}
+		close(release)
+	}()
+
+	return func(ctx context.Context) error {
+		arrived <- struct{}{}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestProvidersEnrich(t *testing.T) {
+	t.Run("RunsConcurrently", func(t *testing.T) {
+		barrier := newBarrier(3)
+		providers := Providers{
+			Age:         stubAge{age: john.Age, barrier: barrier},
+			Gender:      stubGender{gender: john.Gender, barrier: barrier},
+			Nationality: stubNationality{nationality: john.Nationality, barrier: barrier},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		result, err := providers.Enrich(ctx, Query{Name: "John"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, john) {
+			t.Errorf("Enrich() = %+v, want %+v", result, john)
+		}
+	})
+
+	t.Run("AggregatesFailures", func(t *testing.T) {
+		ageErr := errors.New("agify down")
+		nationalityErr := errors.New("nationalize down")
+		providers := Providers{
+			Age:         stubAge{err: ageErr},
+			Gender:      stubGender{gender: mary.Gender},
+			Nationality: stubNationality{err: nationalityErr},
+		}
+
+		result, err := providers.Enrich(context.Background(), Query{Name: "Mary"})
+
+		var enrichErr *Error
+		if !errors.As(err, &enrichErr) {
+			t.Fatalf("Expected *Error, got %v", err)
+		}
+		if fields := enrichErr.Fields(); len(fields) != 2 || fields[0] != FieldAge || fields[1] != FieldNationality {
+			t.Errorf("Failed fields = %v, want [age nationality]", fields)
+		}
+		if !errors.Is(err, ageErr) || !errors.Is(err, nationalityErr) {
+			t.Errorf("Expected error to wrap provider errors, got %v", err)
+		}
+		if !reflect.DeepEqual(result.Gender, mary.Gender) {
+			t.Errorf("Gender = %+v, want %+v", result.Gender, mary.Gender)
+		}
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		block := func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		providers := Providers{
+			Age:         stubAge{barrier: block},
+			Gender:      stubGender{barrier: block},
+			Nationality: stubNationality{barrier: block},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := providers.Enrich(ctx, Query{Name: "John"})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+type memoryCache struct {
+	entries map[string]Result
+	sets    int
+}
+
+func (m *memoryCache) Get(_ context.Context, name string) (Result, bool, error) {
+	result, found := m.entries[name]
+	return result, found, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, name string, result Result) error {
+	m.entries[name] = result
+	m.sets++
+	return nil
+}
+
+// stubAliases maps alias keys to canonical names.
+type stubAliases map[string]string
+
+func (s stubAliases) Canonical(_ context.Context, name string) (string, bool, error) {
+	canonical, found := s[strings.ToLower(name)]
+	return canonical, found, nil
+}
+
+func TestEnricherCache(t *testing.T) {
+	t.Run("HitSkipsProviders", func(t *testing.T) {
+		cache := &memoryCache{entries: map[string]Result{"john": john}}
+		providerErr := errors.New("provider must not be called")
+		enricher := NewEnricher(Providers{
+			Age:         stubAge{err: providerErr},
+			Gender:      stubGender{err: providerErr},
+			Nationality: stubNationality{err: providerErr},
+		}, cache)
+
+		result, err := enricher.Enrich(context.Background(), Query{Name: "  John "})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(result, john) {
+			t.Errorf("Enrich() = %+v, want %+v", result, john)
+		}
+	})
+
+	t.Run("HitTagsSource", func(t *testing.T) {
+		sourced := john
+		sourced.Age.Source = ProviderOffline
+		sourced.Gender.Source = SourceEnsemble
+		cache := &memoryCache{entries: map[string]Result{"john": sourced}}
+		enricher := NewEnricher(Providers{}, cache)
+
+		result, err := enricher.Enrich(context.Background(), Query{Name: "John"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Age.Source != "cache:offline" || result.Gender.Source != "cache:ensemble" || result.Nationality.Source != "" {
+			t.Errorf("Sources = %q, %q, %q; want cache:offline, cache:ensemble and none",
+				result.Age.Source, result.Gender.Source, result.Nationality.Source)
+		}
+		if cache.entries["john"].Age.Source != ProviderOffline {
+			t.Errorf("Cached source = %q, want it unchanged", cache.entries["john"].Age.Source)
+		}
+	})
+
+	t.Run("MissStoresResult", func(t *testing.T) {
+		cache := &memoryCache{entries: map[string]Result{}}
+		enricher := NewEnricher(Providers{
+			Age:         stubAge{age: mary.Age},
+			Gender:      stubGender{gender: mary.Gender},
+			Nationality: stubNationality{nationality: mary.Nationality},
+		}, cache)
+
+		if _, err := enricher.Enrich(context.Background(), Query{Name: "Mary"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(cache.entries["mary"], mary) {
+			t.Errorf("Cached %+v, want %+v", cache.entries["mary"], mary)
+		}
+	})
+
+	t.Run("StripDiacritics", func(t *testing.T) {
+		cache := &memoryCache{entries: map[string]Result{"jose": john}}
+		providerErr := errors.New("provider must not be called")
+		enricher := NewEnricher(Providers{
+			Age:         stubAge{err: providerErr},
+			Gender:      stubGender{err: providerErr},
+			Nationality: stubNationality{err: providerErr},
+		}, cache)
+		enricher.StripDiacritics = true
+
+		// A decomposed accent and a non-breaking space.
+		result, err := enricher.Enrich(context.Background(), Query{Name: "JOSE\u0301\u00a0"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(result, john) {
+			t.Errorf("Enrich() = %+v, want %+v", result, john)
+		}
+	})
+
+	t.Run("Transliterate", func(t *testing.T) {
+		cache := &memoryCache{entries: map[string]Result{}}
+		enricher := NewEnricher(Providers{
+			Age:         stubAge{age: mary.Age},
+			Gender:      stubGender{gender: mary.Gender},
+			Nationality: stubNationality{nationality: mary.Nationality},
+		}, cache)
+		enricher.Transliterate = true
+
+		if _, err := enricher.Enrich(context.Background(), Query{Name: "Мария"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, found := cache.entries["mariya"]; !found {
+			t.Errorf("Cache keys = %v, want the Latin form mariya", cache.entries)
+		}
+	})
+
+	t.Run("ResolvesAliases", func(t *testing.T) {
+		cache := &memoryCache{entries: map[string]Result{"aleksandr": john}}
+		providerErr := errors.New("provider must not be called")
+		enricher := NewEnricher(Providers{
+			Age:         stubAge{err: providerErr},
+			Gender:      stubGender{err: providerErr},
+			Nationality: stubNationality{err: providerErr},
+		}, cache)
+		enricher.Aliases = stubAliases{"sasha": "Aleksandr"}
+
+		results, errs := enricher.EnrichBatch(context.Background(), []Query{{Name: "Sasha"}, {Name: "Aleksandr"}})
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("Unexpected error for name %d: %v", i, err)
+			}
+			if !reflect.DeepEqual(results[i], john) {
+				t.Errorf("EnrichBatch()[%d] = %+v, want %+v", i, results[i], john)
+			}
+		}
+	})
+
+	t.Run("FailureNotCached", func(t *testing.T) {
+		cache := &memoryCache{entries: map[string]Result{}}
+		enricher := NewEnricher(Providers{
+			Age:         stubAge{age: mary.Age},
+			Gender:      stubGender{err: errors.New("genderize down")},
+			Nationality: stubNationality{nationality: mary.Nationality},
+		}, cache)
+
+		if _, err := enricher.Enrich(context.Background(), Query{Name: "Mary"}); err == nil {
+			t.Fatalf("Expected error, got nil")
+		}
+		if cache.sets != 0 {
+			t.Errorf("Expected nothing cached, got %d writes", cache.sets)
+		}
+	})
+
+	t.Run("ChainPosition", func(t *testing.T) {
+		cached := mary
+		cached.Age.Source = ProviderAgify
+		cached.Gender.Source = ProviderGenderize
+		cache := &memoryCache{entries: map[string]Result{"mary": cached}}
+		offlineAge := john.Age
+		offlineAge.Source = ProviderOffline
+		notCalled := func(context.Context) error {
+			t.Errorf("Provider behind a confident cache answer was called")
+			return nil
+		}
+		enricher := NewEnricher(Providers{
+			Age: AgeChain{
+				Providers: []AgeProvider{stubAge{age: offlineAge}, cacheProvider{Cache: cache}},
+				MinCount:  100,
+			},
+			Gender: GenderChain{
+				Providers:      []GenderProvider{cacheProvider{Cache: cache}, stubGender{barrier: notCalled}},
+				MinProbability: 0.8,
+			},
+			Nationality:   stubNationality{nationality: john.Nationality},
+			CacheInChains: true,
+		}, cache)
+
+		result, err := enricher.Enrich(context.Background(), Query{Name: "Mary"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Age.Source != ProviderOffline || result.Gender.Source != "cache:genderize" || result.Nationality.CountryID != "US" {
+			t.Errorf("Enrich() = %+v, want the offline age, the cached gender and the provider's nationality", result)
+		}
+		if stored := cache.entries["mary"]; stored.Age.Source != ProviderOffline || stored.Gender.Source != ProviderGenderize {
+			t.Errorf("Cached %+v, want the new result without cache marks", stored)
+		}
+	})
+
+	t.Run("ChainReusedNotStored", func(t *testing.T) {
+		cached := mary
+		cached.Age.Source = ProviderAgify
+		cached.Gender.Source = ProviderGenderize
+		cached.Nationality.Source = ProviderNationalize
+		cache := &memoryCache{entries: map[string]Result{"mary": cached}}
+		providerErr := errors.New("provider must not be called")
+		enricher := NewEnricher(Providers{
+			Age:           AgeChain{Providers: []AgeProvider{cacheProvider{Cache: cache}, stubAge{err: providerErr}}},
+			Gender:        GenderChain{Providers: []GenderProvider{cacheProvider{Cache: cache}, stubGender{err: providerErr}}},
+			Nationality:   NationalityChain{Providers: []NationalityProvider{cacheProvider{Cache: cache}, stubNationality{err: providerErr}}},
+			CacheInChains: true,
+		}, cache)
+
+		if _, err := enricher.Enrich(context.Background(), Query{Name: "Mary"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cache.sets != 0 {
+			t.Errorf("Expected nothing cached, got %d writes", cache.sets)
+		}
+	})
+}
+
+// batchAge is a BatchAgeProvider that answers every name with age len(name)
+// and records the size of each batch it receives.
+type batchAge struct {
+	batches []int
+	failAt  int
+}
+
+func (b *batchAge) Age(_ context.Context, name string) (AgePrediction, error) {
+	return AgePrediction{}, errors.New("single lookup must not be used")
+}
+
+func (b *batchAge) AgeBatch(_ context.Context, names []string) ([]AgePrediction, error) {
+	b.batches = append(b.batches, len(names))
+	if len(b.batches) == b.failAt {
+		return nil, errors.New("agify down")
+	}
+	predictions := make([]AgePrediction, len(names))
+	for i, name := range names {
+		predictions[i] = AgePrediction{Age: intPtr(len(name)), Count: 1}
+	}
+	return predictions, nil
+}
+
+func TestProvidersEnrichBatch(t *testing.T) {
+	queries := make([]Query, 12)
+	for i := range queries {
+		queries[i] = Query{Name: strings.Repeat("a", i+1)}
+	}
+
+	t.Run("GroupsIntoChunks", func(t *testing.T) {
+		age := &batchAge{}
+		providers := Providers{
+			Age:         age,
+			Gender:      stubGender{gender: john.Gender},
+			Nationality: stubNationality{nationality: john.Nationality},
+		}
+
+		results, errs := providers.EnrichBatch(context.Background(), queries)
+
+		if !reflect.DeepEqual(age.batches, []int{MaxBatchSize, 2}) {
+			t.Errorf("Batches = %v, want [%d 2]", age.batches, MaxBatchSize)
+		}
+		for i, result := range results {
+			if errs[i] != nil {
+				t.Errorf("Unexpected error for %s: %v", queries[i].Name, errs[i])
+			}
+			if *result.Age.Age != i+1 || !reflect.DeepEqual(result.Gender, john.Gender) {
+				t.Errorf("Result for %s = %+v", queries[i].Name, result)
+			}
+		}
+	})
+
+	t.Run("FailedChunk", func(t *testing.T) {
+		age := &batchAge{failAt: 2}
+		providers := Providers{
+			Age:         age,
+			Gender:      stubGender{gender: john.Gender},
+			Nationality: stubNationality{nationality: john.Nationality},
+		}
+
+		_, errs := providers.EnrichBatch(context.Background(), queries)
+
+		for i, err := range errs {
+			var enrichErr *Error
+			failed := errors.As(err, &enrichErr)
+			if wantFailed := i >= MaxBatchSize; failed != wantFailed {
+				t.Errorf("Name %d failed = %v, want %v", i, failed, wantFailed)
+			}
+			if failed && !reflect.DeepEqual(enrichErr.Fields(), []string{FieldAge}) {
+				t.Errorf("Failed fields = %v, want [age]", enrichErr.Fields())
+			}
+		}
+	})
+}
+
+func TestEnricherEnrichBatch(t *testing.T) {
+	cache := &memoryCache{entries: map[string]Result{"john": john}}
+	age := &batchAge{}
+	enricher := NewEnricher(Providers{
+		Age:         age,
+		Gender:      stubGender{gender: mary.Gender},
+		Nationality: stubNationality{nationality: mary.Nationality},
+	}, cache)
+
+	results, errs := enricher.EnrichBatch(context.Background(), []Query{{Name: "John"}, {Name: "Mary"}, {Name: " mary"}, {Name: "Ann"}})
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error for name %d: %v", i, err)
+		}
+	}
+	if !reflect.DeepEqual(results[0], john) {
+		t.Errorf("Cached result = %+v, want %+v", results[0], john)
+	}
+	if !reflect.DeepEqual(age.batches, []int{2}) {
+		t.Errorf("Batches = %v, want one batch of the 2 uncached names", age.batches)
+	}
+	if !reflect.DeepEqual(results[1], results[2]) || *results[1].Age.Age != len("Mary") {
+		t.Errorf("Duplicate names got %+v and %+v", results[1], results[2])
+	}
+	if cache.sets != 2 {
+		t.Errorf("Cache writes = %d, want 2", cache.sets)
+	}
+}
+
+// localizedAge is a LocalizedBatchAgeProvider that records the country of
+// every request as "country:names", with "-" for worldwide requests.
+type localizedAge struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (l *localizedAge) record(countryID string, names int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if countryID == "" {
+		countryID = "-"
+	}
+	l.requests = append(l.requests, fmt.Sprintf("%s:%d", countryID, names))
+}
+
+func (l *localizedAge) Age(ctx context.Context, name string) (AgePrediction, error) {
+	return l.LocalizedAge(ctx, name, "")
+}
+
+func (l *localizedAge) LocalizedAge(_ context.Context, _ string, countryID string) (AgePrediction, error) {
+	l.record(countryID, 1)
+	return john.Age, nil
+}
+
+func (l *localizedAge) AgeBatch(ctx context.Context, names []string) ([]AgePrediction, error) {
+	return l.LocalizedAgeBatch(ctx, names, "")
+}
+
+func (l *localizedAge) LocalizedAgeBatch(_ context.Context, names []string, countryID string) ([]AgePrediction, error) {
+	l.record(countryID, len(names))
+	return make([]AgePrediction, len(names)), nil
+}
+
+func TestProvidersLocalize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CountryHint", func(t *testing.T) {
+		age := &localizedAge{}
+		providers := Providers{Age: age, Gender: stubGender{gender: john.Gender}, Nationality: stubNationality{nationality: john.Nationality}}
+
+		if _, err := providers.Enrich(ctx, Query{Name: "John", CountryID: "FR"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(age.requests, []string{"FR:1"}) {
+			t.Errorf("Requests = %v, want [FR:1]", age.requests)
+		}
+	})
+
+	t.Run("ResolveCountry", func(t *testing.T) {
+		age := &localizedAge{}
+		providers := Providers{
+			Age:            age,
+			Gender:         stubGender{gender: john.Gender},
+			Nationality:    stubNationality{nationality: john.Nationality},
+			ResolveCountry: true,
+		}
+
+		result, err := providers.Enrich(ctx, Query{Name: "John"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(age.requests, []string{"US:1"}) || !reflect.DeepEqual(result.Nationality, john.Nationality) {
+			t.Errorf("Requests = %v, nationality = %+v", age.requests, result.Nationality)
+		}
+	})
+
+	t.Run("ResolveCountryFails", func(t *testing.T) {
+		age := &localizedAge{}
+		providers := Providers{
+			Age:            age,
+			Gender:         stubGender{gender: john.Gender},
+			Nationality:    stubNationality{err: errors.New("nationalize down")},
+			ResolveCountry: true,
+		}
+
+		_, err := providers.Enrich(ctx, Query{Name: "John"})
+		var enrichErr *Error
+		if !errors.As(err, &enrichErr) || !reflect.DeepEqual(enrichErr.Fields(), []string{FieldNationality}) {
+			t.Errorf("Enrich() error = %v, want a nationality failure", err)
+		}
+		if !reflect.DeepEqual(age.requests, []string{"-:1"}) {
+			t.Errorf("Requests = %v, want one worldwide request", age.requests)
+		}
+	})
+
+	t.Run("BatchGroupsByCountry", func(t *testing.T) {
+		age := &localizedAge{}
+		providers := Providers{
+			Age:            age,
+			Gender:         stubGender{gender: john.Gender},
+			Nationality:    stubNationality{nationality: john.Nationality},
+			ResolveCountry: true,
+		}
+
+		_, errs := providers.EnrichBatch(ctx, []Query{
+			{Name: "Ana", CountryID: "ES"},
+			{Name: "John"},
+			{Name: "Lucia", CountryID: "ES"},
+		})
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("Unexpected error for query %d: %v", i, err)
+			}
+		}
+		if !reflect.DeepEqual(age.requests, []string{"ES:2", "US:1"}) {
+			t.Errorf("Requests = %v, want [ES:2 US:1]", age.requests)
+		}
+	})
+}
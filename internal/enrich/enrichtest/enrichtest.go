@@ -0,0 +1,244 @@
+// Package enrichtest provides a stand-in for the agify, genderize and
+// nationalize APIs that answers from fixtures, so that the enrichment can be
+// tested and developed without network access. Each API is served under its
+// own path, such as "/agify", which is what its base URL should point to.
+package enrichtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider names, used as the keys of Fixtures and as URL paths.
+const (
+	Agify       = "agify"
+	Genderize   = "genderize"
+	Nationalize = "nationalize"
+)
+
+// Wildcard is the fixture name that matches every name without a fixture of
+// its own.
+const Wildcard = "*"
+
+// Fixture is the canned answer of a provider for one name.
+type Fixture struct {
+	// Response is the JSON object answered for the name, such as
+	// {"age": 35, "count": 1200}. The "name" field, and "country_id" for
+	// localized requests, are filled in.
+	Response map[string]interface{} `json:"response,omitempty"`
+	// Status, other than 200, is answered with an error body instead. A 429
+	// reports an exhausted quota that resets in a minute, unless Headers say
+	// otherwise.
+	Status int `json:"status,omitempty"`
+	// Headers are added to the answer.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Latency delays the answer.
+	Latency Duration `json:"latency,omitempty"`
+	// Body, when set, is answered verbatim with status 200, which is how
+	// malformed JSON is simulated.
+	Body string `json:"body,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1.5s" in JSON.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"1.5s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Fixtures holds the fixtures of every provider by lower-case name. Names
+// without a fixture, and without a Wildcard one, get the provider's answer
+// for a name it does not know, such as {"age": null, "count": 0}.
+type Fixtures map[string]map[string]Fixture
+
+// LoadFixtures reads fixtures from a JSON file.
+func LoadFixtures(path string) (Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures Fixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	for provider := range fixtures {
+		if _, ok := unknownResponses[provider]; !ok {
+			return nil, fmt.Errorf("unknown provider %q in fixtures %s", provider, path)
+		}
+	}
+	return fixtures, nil
+}
+
+// The answers of the providers for names they do not know.
+var unknownResponses = map[string]map[string]interface{}{
+	Agify:       {"age": nil, "count": 0},
+	Genderize:   {"gender": nil, "probability": 0, "count": 0},
+	Nationalize: {"country": []interface{}{}, "count": 0},
+}
+
+// fixture returns the fixture of provider for name.
+func (f Fixtures) fixture(provider, name string) Fixture {
+	byName := f[provider]
+	if fixture, ok := byName[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return fixture
+	}
+	if fixture, ok := byName[Wildcard]; ok {
+		return fixture
+	}
+	return Fixture{Response: unknownResponses[provider]}
+}
+
+// Handler serves the provider APIs from fixtures and counts the requests
+// each provider receives.
+type Handler struct {
+	fixtures Fixtures
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func NewHandler(fixtures Fixtures) *Handler {
+	return &Handler{fixtures: fixtures, requests: make(map[string]int)}
+}
+
+// Requests returns the number of requests provider has received.
+func (h *Handler) Requests(provider string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests[provider]
+}
+
+// ServeHTTP answers a single name ("name") with an object and several names
+// ("name[]") with an array. A batch is delayed by the latency of its slowest
+// name and fails as a whole when the fixture of any name is a failure.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	provider := strings.Trim(r.URL.Path, "/")
+	if _, ok := unknownResponses[provider]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h.mu.Lock()
+	h.requests[provider]++
+	h.mu.Unlock()
+
+	query := r.URL.Query()
+	names, batch := query["name[]"]
+	if !batch {
+		names = []string{query.Get("name")}
+	}
+	if len(names) == 0 || names[0] == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Missing 'name' parameter")
+		return
+	}
+
+	fixtures := make([]Fixture, len(names))
+	var latency time.Duration
+	failure := -1
+	for i, name := range names {
+		fixtures[i] = h.fixtures.fixture(provider, name)
+		latency = max(latency, time.Duration(fixtures[i].Latency))
+		if failure < 0 && (fixtures[i].Body != "" || fixtures[i].Status != 0 && fixtures[i].Status != http.StatusOK) {
+			failure = i
+		}
+	}
+
+	select {
+	case <-time.After(latency):
+	case <-r.Context().Done():
+		return
+	}
+
+	if failure >= 0 {
+		writeFailure(w, fixtures[failure])
+		return
+	}
+
+	responses := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		for header, value := range fixtures[i].Headers {
+			w.Header().Set(header, value)
+		}
+		responses[i] = response(fixtures[i], name, query.Get("country_id"))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if batch {
+		json.NewEncoder(w).Encode(responses)
+		return
+	}
+	json.NewEncoder(w).Encode(responses[0])
+}
+
+// response returns the answer of fixture for name.
+func response(fixture Fixture, name, countryID string) map[string]interface{} {
+	answer := make(map[string]interface{}, len(fixture.Response)+2)
+	for field, value := range fixture.Response {
+		answer[field] = value
+	}
+	answer["name"] = name
+	if countryID != "" {
+		answer["country_id"] = countryID
+	}
+	return answer
+}
+
+// writeFailure answers with the failure described by fixture.
+func writeFailure(w http.ResponseWriter, fixture Fixture) {
+	if fixture.Status == http.StatusTooManyRequests {
+		w.Header().Set("X-Rate-Limit-Remaining", "0")
+		w.Header().Set("X-Rate-Limit-Reset", strconv.Itoa(60))
+	}
+	for header, value := range fixture.Headers {
+		w.Header().Set(header, value)
+	}
+
+	if fixture.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, fixture.Body)
+		return
+	}
+	writeError(w, fixture.Status, http.StatusText(fixture.Status))
+}
+
+// writeError answers like the providers do on errors.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// Server is a Handler listening on a local port, for tests.
+type Server struct {
+	*httptest.Server
+	Handler *Handler
+}
+
+// NewServer starts a server answering from fixtures. Callers should Close it.
+func NewServer(fixtures Fixtures) *Server {
+	handler := NewHandler(fixtures)
+	return &Server{Server: httptest.NewServer(handler), Handler: handler}
+}
+
+// ProviderURL returns the base URL of provider on s.
+func (s *Server) ProviderURL(provider string) string {
+	return s.URL + "/" + provider
+}
@@ -0,0 +1,167 @@
+package enrichtest_test
+
+import (
+	"NameEnricher/internal/enrich"
+	"NameEnricher/internal/enrich/enrichtest"
+	"NameEnricher/pkg/logger"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init()
+
+	exitCode := m.Run()
+
+	os.Exit(exitCode)
+}
+
+var fixtures = enrichtest.Fixtures{
+	enrichtest.Agify: {
+		"john":  {Response: map[string]interface{}{"age": 35, "count": 1200}},
+		"slow":  {Response: map[string]interface{}{"age": 40, "count": 10}, Latency: enrichtest.Duration(time.Second)},
+		"limit": {Status: 429},
+	},
+	enrichtest.Genderize: {
+		"john":   {Response: map[string]interface{}{"gender": "male", "probability": 0.99, "count": 1200}},
+		"broken": {Body: `{"gender": "male",`},
+	},
+	enrichtest.Nationalize: {
+		"*": {Response: map[string]interface{}{"country": []map[string]interface{}{{"country_id": "US", "probability": 0.6}}}},
+	},
+}
+
+// newProviders returns the providers of the enrichment pointed at server.
+func newProviders(t *testing.T, server *enrichtest.Server, timeout time.Duration) enrich.Providers {
+	t.Helper()
+	providers, err := enrich.NewProviders(enrich.Config{
+		Agify:       enrich.ProviderConfig{BaseURL: server.ProviderURL(enrichtest.Agify), Timeout: timeout},
+		Genderize:   enrich.ProviderConfig{BaseURL: server.ProviderURL(enrichtest.Genderize), Timeout: timeout},
+		Nationalize: enrich.ProviderConfig{BaseURL: server.ProviderURL(enrichtest.Nationalize), Timeout: timeout},
+	})
+	if err != nil {
+		t.Fatalf("NewProviders() error: %v", err)
+	}
+	return providers
+}
+
+func TestServer(t *testing.T) {
+	server := enrichtest.NewServer(fixtures)
+	defer server.Close()
+
+	providers := newProviders(t, server, 200*time.Millisecond)
+	ctx := context.Background()
+
+	t.Run("Fixture", func(t *testing.T) {
+		age, err := providers.Age.Age(ctx, "John")
+		if err != nil {
+			t.Fatalf("Age() error: %v", err)
+		}
+		if age.Age == nil || *age.Age != 35 || age.Count != 1200 {
+			t.Errorf("Age() = %+v, want 35 from 1200 samples", age)
+		}
+
+		gender, err := providers.Gender.Gender(ctx, "John")
+		if err != nil {
+			t.Fatalf("Gender() error: %v", err)
+		}
+		if gender.Gender != "male" {
+			t.Errorf("Gender() = %+v, want male", gender)
+		}
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		nationality, err := providers.Nationality.Nationality(ctx, "Anyone")
+		if err != nil {
+			t.Fatalf("Nationality() error: %v", err)
+		}
+		expected := []enrich.CountryProbability{{CountryID: "US", Probability: 0.6}}
+		if !reflect.DeepEqual(nationality.Countries, expected) {
+			t.Errorf("Nationality() = %+v, want %+v", nationality.Countries, expected)
+		}
+	})
+
+	t.Run("UnknownName", func(t *testing.T) {
+		age, err := providers.Age.Age(ctx, "Zyxw")
+		if err != nil {
+			t.Fatalf("Age() error: %v", err)
+		}
+		if age.Known() {
+			t.Errorf("Age() = %+v, want unknown", age)
+		}
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		_, err := providers.Age.Age(ctx, "Slow")
+		if !errors.Is(err, enrich.ErrProviderUnavailable) {
+			t.Errorf("Age() error = %v, want a timeout", err)
+		}
+	})
+
+	// The agify quota stays exhausted after this test.
+	t.Run("QuotaExhausted", func(t *testing.T) {
+		_, err := providers.Age.Age(ctx, "Limit")
+		var quotaErr *enrich.QuotaError
+		if !errors.As(err, &quotaErr) {
+			t.Fatalf("Age() error = %v, want a QuotaError", err)
+		}
+		if quotaErr.RetryAfter() <= 0 {
+			t.Errorf("RetryAfter() = %s, want the reset of the fixture", quotaErr.RetryAfter())
+		}
+	})
+
+	t.Run("MalformedJSON", func(t *testing.T) {
+		_, err := providers.Gender.Gender(ctx, "Broken")
+		if !errors.Is(err, enrich.ErrProviderUnavailable) {
+			t.Errorf("Gender() error = %v, want ErrProviderUnavailable", err)
+		}
+	})
+}
+
+func TestServerBatch(t *testing.T) {
+	server := enrichtest.NewServer(fixtures)
+	defer server.Close()
+
+	providers := newProviders(t, server, 2*time.Second)
+	agify := providers.Age.(enrich.BatchAgeProvider)
+
+	ages, err := agify.AgeBatch(context.Background(), []string{"John", "Zyxw"})
+	if err != nil {
+		t.Fatalf("AgeBatch() error: %v", err)
+	}
+	if len(ages) != 2 || ages[0].Age == nil || *ages[0].Age != 35 || ages[1].Known() {
+		t.Errorf("AgeBatch() = %+v, want John at 35 and an unknown name", ages)
+	}
+	if requests := server.Handler.Requests(enrichtest.Agify); requests != 1 {
+		t.Errorf("Requests() = %d, want 1 for the batch", requests)
+	}
+
+	if _, err := agify.AgeBatch(context.Background(), []string{"John", "Limit"}); !errors.Is(err, enrich.ErrQuotaExhausted) {
+		t.Errorf("AgeBatch() error = %v, want the whole batch to hit the quota", err)
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	loaded, err := enrichtest.LoadFixtures(filepath.Join("..", "..", "..", "cmd", "fakeproviders", "fixtures.json"))
+	if err != nil {
+		t.Fatalf("LoadFixtures() error: %v", err)
+	}
+	for _, provider := range []string{enrichtest.Agify, enrichtest.Genderize, enrichtest.Nationalize} {
+		if len(loaded[provider]) == 0 {
+			t.Errorf("LoadFixtures() has no %s fixtures", provider)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(path, []byte(`{"ageify": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enrichtest.LoadFixtures(path); err == nil {
+		t.Errorf("LoadFixtures() accepted an unknown provider")
+	}
+}
@@ -0,0 +1,182 @@
+package enrich
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// SourceEnsemble is the Source of predictions combined from several providers.
+const SourceEnsemble = "ensemble"
+
+// GenderEnsemble asks all of its providers and averages their answers,
+// weighting each provider by the weight at the same index of Weights
+// (missing weights count as 1). The combined prediction lists every
+// provider's answer in Members. A name only fails when no provider knows it
+// and at least one provider failed.
+type GenderEnsemble struct {
+	Providers []GenderProvider
+	Weights   []float64
+}
+
+func (e GenderEnsemble) Gender(ctx context.Context, name string) (GenderPrediction, error) {
+	return e.LocalizedGender(ctx, name, "")
+}
+
+func (e GenderEnsemble) LocalizedGender(ctx context.Context, name, countryID string) (GenderPrediction, error) {
+	predictions, errs := e.lookupAll(ctx, []string{name}, []string{countryID})
+	return predictions[0], errs[0]
+}
+
+func (e GenderEnsemble) lookupAll(ctx context.Context, names, countries []string) ([]GenderPrediction, []error) {
+	members := make([]chainMember[GenderPrediction], len(e.Providers))
+	for i, provider := range e.Providers {
+		members[i].single, members[i].batch = genderLookups(provider)
+	}
+	return runEnsemble(ctx, names, countries, members, e.Weights, combineGenders)
+}
+
+func (e GenderEnsemble) breakers() []*Breaker {
+	return chainBreakers(e.Providers)
+}
+
+// NationalityEnsemble asks all of its providers and merges their country
+// distributions into a weighted average, like GenderEnsemble.
+type NationalityEnsemble struct {
+	Providers []NationalityProvider
+	Weights   []float64
+}
+
+func (e NationalityEnsemble) Nationality(ctx context.Context, name string) (NationalityPrediction, error) {
+	predictions, errs := e.lookupAll(ctx, []string{name}, nil)
+	return predictions[0], errs[0]
+}
+
+func (e NationalityEnsemble) lookupAll(ctx context.Context, names, countries []string) ([]NationalityPrediction, []error) {
+	members := make([]chainMember[NationalityPrediction], len(e.Providers))
+	for i, provider := range e.Providers {
+		members[i].single, members[i].batch = nationalityLookups(provider)
+	}
+	return runEnsemble(ctx, names, countries, members, e.Weights, combineNationalities)
+}
+
+func (e NationalityEnsemble) breakers() []*Breaker {
+	return chainBreakers(e.Providers)
+}
+
+// weightedPrediction is a member's answer with the weight of its provider.
+type weightedPrediction[T any] struct {
+	prediction T
+	weight     float64
+}
+
+// runEnsemble asks every member for every name concurrently and combines the
+// answers of each name with combine. The members that failed for a name are
+// left out; their errors are only returned when combine finds no known answer.
+func runEnsemble[T interface{ Known() bool }](ctx context.Context, names, countries []string, members []chainMember[T],
+	weights []float64, combine func([]weightedPrediction[T]) T) ([]T, []error) {
+	memberPredictions := make([][]T, len(members))
+	memberErrs := make([][]error, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			memberPredictions[i], memberErrs[i] = lookupBatch(ctx, names, countries, member.single, member.batch)
+		}()
+	}
+	wg.Wait()
+
+	predictions := make([]T, len(names))
+	errs := make([]error, len(names))
+	for n := range names {
+		var answers []weightedPrediction[T]
+		var failures []error
+		for i := range members {
+			if err := memberErrs[i][n]; err != nil {
+				failures = append(failures, err)
+				continue
+			}
+			weight := 1.0
+			if i < len(weights) {
+				weight = weights[i]
+			}
+			answers = append(answers, weightedPrediction[T]{prediction: memberPredictions[i][n], weight: weight})
+		}
+
+		predictions[n] = combine(answers)
+		if !predictions[n].Known() && len(failures) > 0 {
+			errs[n] = chainError(failures)
+		}
+	}
+	return predictions, errs
+}
+
+// combineGenders averages the probability of "male" over the known answers.
+// The combined gender is the more likely of "male" and "female", and its
+// count is the sum of the members' counts.
+func combineGenders(answers []weightedPrediction[GenderPrediction]) GenderPrediction {
+	combined := GenderPrediction{Source: SourceEnsemble}
+	var male, total float64
+	for _, answer := range answers {
+		combined.Members = append(combined.Members, answer.prediction)
+		if !answer.prediction.Known() || answer.weight <= 0 {
+			continue
+		}
+		share := answer.prediction.Probability
+		if answer.prediction.Gender != "male" {
+			share = 1 - share
+		}
+		male += answer.weight * share
+		total += answer.weight
+		combined.Count += answer.prediction.Count
+	}
+	if total == 0 {
+		return combined
+	}
+
+	male /= total
+	combined.Gender, combined.Probability = "male", male
+	if male < 0.5 {
+		combined.Gender, combined.Probability = "female", 1-male
+	}
+	return combined
+}
+
+// combineNationalities averages the country distributions of the known
+// answers; a country missing from a distribution counts as probability 0.
+func combineNationalities(answers []weightedPrediction[NationalityPrediction]) NationalityPrediction {
+	combined := NationalityPrediction{Source: SourceEnsemble}
+	merged := make(map[string]float64)
+	var total float64
+	for _, answer := range answers {
+		combined.Members = append(combined.Members, answer.prediction)
+		if !answer.prediction.Known() || answer.weight <= 0 {
+			continue
+		}
+		countries := answer.prediction.Countries
+		if len(countries) == 0 {
+			countries = []CountryProbability{{CountryID: answer.prediction.CountryID, Probability: answer.prediction.Probability}}
+		}
+		for _, country := range countries {
+			merged[country.CountryID] += answer.weight * country.Probability
+		}
+		total += answer.weight
+	}
+	if total == 0 {
+		return combined
+	}
+
+	for countryID, probability := range merged {
+		combined.Countries = append(combined.Countries, CountryProbability{CountryID: countryID, Probability: probability / total})
+	}
+	sort.Slice(combined.Countries, func(i, j int) bool {
+		if combined.Countries[i].Probability != combined.Countries[j].Probability {
+			return combined.Countries[i].Probability > combined.Countries[j].Probability
+		}
+		return combined.Countries[i].CountryID < combined.Countries[j].CountryID
+	})
+	combined.CountryID = combined.Countries[0].CountryID
+	combined.Probability = combined.Countries[0].Probability
+	return combined
+}
@@ -0,0 +1,98 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGenderEnsemble(t *testing.T) {
+	remote := GenderPrediction{Gender: "male", Probability: 0.9, Count: 1000, Source: ProviderGenderize}
+	local := GenderPrediction{Gender: "female", Probability: 0.6, Count: 100, Source: ProviderOffline}
+	unavailable := &ProviderError{Provider: ProviderGenderize, Err: ErrProviderUnavailable}
+
+	t.Run("WeightedAverage", func(t *testing.T) {
+		ensemble := GenderEnsemble{
+			Providers: []GenderProvider{stubGender{gender: remote}, stubGender{gender: local}},
+			Weights:   []float64{2, 1},
+		}
+
+		got, err := ensemble.Gender(context.Background(), "Andrea")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// (2*0.9 + 1*0.4) / 3 of the weight says male.
+		if got.Gender != "male" || math.Abs(got.Probability-2.2/3) > 1e-9 || got.Count != 1100 || got.Source != SourceEnsemble {
+			t.Errorf("Gender = %+v, want male with probability %.3f from 1100 samples", got, 2.2/3)
+		}
+		if !reflect.DeepEqual(got.Members, []GenderPrediction{remote, local}) {
+			t.Errorf("Members = %+v, want both answers", got.Members)
+		}
+	})
+
+	t.Run("SkipsFailedProvider", func(t *testing.T) {
+		ensemble := GenderEnsemble{Providers: []GenderProvider{stubGender{err: unavailable}, stubGender{gender: local}}}
+
+		got, err := ensemble.Gender(context.Background(), "Andrea")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got.Gender != "female" || math.Abs(got.Probability-0.6) > 1e-9 || len(got.Members) != 1 {
+			t.Errorf("Gender = %+v, want the offline answer only", got)
+		}
+	})
+
+	t.Run("FailsWithoutKnownAnswer", func(t *testing.T) {
+		ensemble := GenderEnsemble{Providers: []GenderProvider{
+			stubGender{err: unavailable},
+			stubGender{gender: GenderPrediction{Source: ProviderOffline}},
+		}}
+
+		got, err := ensemble.Gender(context.Background(), "Andrea")
+		if !errors.Is(err, ErrProviderUnavailable) {
+			t.Errorf("Error = %v, want ErrProviderUnavailable", err)
+		}
+		if got.Known() {
+			t.Errorf("Gender = %+v, want unknown", got)
+		}
+	})
+}
+
+func TestNationalityEnsemble(t *testing.T) {
+	ensemble := NationalityEnsemble{Providers: []NationalityProvider{
+		stubNationality{nationality: NationalityPrediction{
+			CountryID:   "US",
+			Probability: 0.6,
+			Countries:   []CountryProbability{{CountryID: "US", Probability: 0.6}, {CountryID: "GB", Probability: 0.2}},
+			Source:      ProviderNationalize,
+		}},
+		stubNationality{nationality: NationalityPrediction{
+			CountryID:   "GB",
+			Probability: 0.5,
+			Countries:   []CountryProbability{{CountryID: "GB", Probability: 0.5}, {CountryID: "IE", Probability: 0.3}},
+			Source:      ProviderOffline,
+		}},
+	}}
+
+	got, err := ensemble.Nationality(context.Background(), "John")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []CountryProbability{{CountryID: "GB", Probability: 0.35}, {CountryID: "US", Probability: 0.3}, {CountryID: "IE", Probability: 0.15}}
+	if len(got.Countries) != len(want) {
+		t.Fatalf("Countries = %+v, want %+v", got.Countries, want)
+	}
+	for i := range want {
+		if got.Countries[i].CountryID != want[i].CountryID || math.Abs(got.Countries[i].Probability-want[i].Probability) > 1e-9 {
+			t.Errorf("Countries = %+v, want %+v", got.Countries, want)
+			break
+		}
+	}
+	if got.CountryID != "GB" || got.Source != SourceEnsemble || len(got.Members) != 2 {
+		t.Errorf("Nationality = %+v, want GB from the ensemble with two members", got)
+	}
+}
@@ -0,0 +1,87 @@
+package enrich
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrProviderUnavailable is matched by failures that say nothing about the
+	// name: network errors, 5xx responses, unusable bodies or a rejected API key.
+	ErrProviderUnavailable = errors.New("provider unavailable")
+	// ErrInvalidName is matched when a provider rejected the name itself, so
+	// retrying the same name cannot succeed.
+	ErrInvalidName = errors.New("invalid name")
+)
+
+// ProviderError is a failed provider request. Err is ErrProviderUnavailable or
+// ErrInvalidName; StatusCode is zero when no response was received.
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Err        error
+	// Message is the error reported in the response body, if any.
+	Message string
+	// Cause is the underlying transport or decoding error, if any.
+	Cause error
+}
+
+func (e *ProviderError) Error() string {
+	msg := e.Provider + ": " + e.Err.Error()
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *ProviderError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Cause}
+}
+
+// maxErrorBody bounds how much of an error response is read for its message.
+const maxErrorBody = 4 << 10
+
+// newStatusError builds the error for a non-200, non-429 provider response.
+// The providers answer 422 for names they cannot process; everything else
+// means the provider cannot be used right now.
+func newStatusError(provider string, resp *http.Response) error {
+	sentinel := ErrProviderUnavailable
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		sentinel = ErrInvalidName
+	}
+	return &ProviderError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Err:        sentinel,
+		Message:    errorMessage(resp.Body),
+	}
+}
+
+// errorMessage returns the "error" field of a JSON error body, or the body
+// itself when it is not JSON.
+func errorMessage(body io.Reader) string {
+	data, err := io.ReadAll(io.LimitReader(body, maxErrorBody))
+	if err != nil {
+		return ""
+	}
+	var response struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &response) == nil && response.Error != "" {
+		return response.Error
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,101 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantErr     error
+		wantMessage string
+	}{
+		{"InvalidName", http.StatusUnprocessableEntity, `{"error":"Invalid 'name' parameter"}`, ErrInvalidName, "Invalid 'name' parameter"},
+		{"ServerError", http.StatusInternalServerError, "internal error", ErrProviderUnavailable, "internal error"},
+		{"Unauthorized", http.StatusUnauthorized, `{"error":"Invalid API key"}`, ErrProviderUnavailable, "Invalid API key"},
+		{"QuotaExhausted", http.StatusTooManyRequests, `{"error":"Request limit reached"}`, ErrQuotaExhausted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := (&Agify{BaseURL: server.URL, Client: server.Client()}).Age(context.Background(), "John")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Age() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var providerErr *ProviderError
+			if errors.As(err, &providerErr) {
+				if providerErr.Provider != ProviderAgify || providerErr.StatusCode != tt.status || providerErr.Message != tt.wantMessage {
+					t.Errorf("ProviderError = %+v", providerErr)
+				}
+				return
+			}
+			var quotaErr *QuotaError
+			if !errors.As(err, &quotaErr) || quotaErr.Provider != ProviderAgify || quotaErr.StatusCode != tt.status {
+				t.Errorf("Age() error = %#v, want a *QuotaError with status %d", err, tt.status)
+			}
+		})
+	}
+
+	t.Run("Unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		_, err := (&Genderize{BaseURL: server.URL, Client: http.DefaultClient}).Gender(context.Background(), "John")
+		var providerErr *ProviderError
+		if !errors.Is(err, ErrProviderUnavailable) || !errors.As(err, &providerErr) || providerErr.StatusCode != 0 {
+			t.Errorf("Gender() error = %v, want unavailable without status", err)
+		}
+	})
+}
+
+func TestProvidersEnrichBatchInvalidName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := r.URL.Query()["name[]"]
+		if names == nil {
+			names = []string{r.URL.Query().Get("name")}
+		}
+		for _, name := range names {
+			if name == "" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"error":"Invalid 'name' parameter"}`))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Has("name") {
+			w.Write([]byte(`{"name":"John","age":40,"count":5}`))
+			return
+		}
+		w.Write([]byte(`[{"name":"John","age":40,"count":5},{"name":"","age":null,"count":0}]`))
+	}))
+	defer server.Close()
+
+	providers := Providers{
+		Age:         &Agify{BaseURL: server.URL, Client: server.Client()},
+		Gender:      stubGender{gender: john.Gender},
+		Nationality: stubNationality{nationality: john.Nationality},
+	}
+
+	results, errs := providers.EnrichBatch(context.Background(), []Query{{Name: "John"}, {Name: ""}})
+
+	if errs[0] != nil || !results[0].Age.Known() {
+		t.Errorf("John = %+v, %v; want a known age", results[0], errs[0])
+	}
+	if !errors.Is(errs[1], ErrInvalidName) {
+		t.Errorf("Empty name error = %v, want ErrInvalidName", errs[1])
+	}
+}
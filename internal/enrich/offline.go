@@ -0,0 +1,208 @@
+package enrich
+
+import (
+	"NameEnricher/pkg/logger"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProviderOffline is the provider name of Offline.
+const ProviderOffline = "offline"
+
+// Offline answers age, gender and nationality lookups from an in-memory
+// dataset of name statistics, for environments without access to the public
+// APIs. Names missing from the dataset get an unknown prediction, like a
+// public API that has no data for them.
+//
+// The dataset is a CSV file with a header row. Only the name column is
+// required; empty cells mean the statistic is unknown:
+//
+//	name,age,age_count,male_ratio,gender_count,countries
+//	john,47.3,120000,0.994,250000,US:0.35;GB:0.10;IE:0.08
+//
+// age is the mean age, male_ratio the share of males between 0 and 1, and
+// countries a ;-separated list of country_id:probability pairs.
+type Offline struct {
+	entries map[string]offlineEntry
+}
+
+type offlineEntry struct {
+	age         AgePrediction
+	gender      GenderPrediction
+	nationality NationalityPrediction
+}
+
+// LoadOffline reads the dataset at path.
+func LoadOffline(path string) (*Offline, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offline dataset: %w", err)
+	}
+	defer file.Close()
+
+	offline, err := ReadOffline(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offline dataset %s: %w", path, err)
+	}
+	logger.Log.Infof("Loaded offline dataset %s with %d names", path, offline.Len())
+	return offline, nil
+}
+
+// ReadOffline parses a dataset in the format described on Offline.
+func ReadOffline(r io.Reader) (*Offline, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("missing name column")
+	}
+
+	offline := &Offline{entries: make(map[string]offlineEntry)}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		cell := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		key := NormalizeName(cell("name"))
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty name", line)
+		}
+		if _, ok := offline.entries[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate name %q", line, key)
+		}
+
+		entry, err := parseOfflineEntry(cell)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		offline.entries[key] = entry
+	}
+	return offline, nil
+}
+
+func parseOfflineEntry(cell func(column string) string) (offlineEntry, error) {
+	var entry offlineEntry
+	entry.age.Source = ProviderOffline
+	entry.gender.Source = ProviderOffline
+	entry.nationality.Source = ProviderOffline
+
+	ageCount, err := parseOfflineCount(cell("age_count"))
+	if err != nil {
+		return offlineEntry{}, fmt.Errorf("invalid age_count: %w", err)
+	}
+	entry.age.Count = ageCount
+	if ageStr := cell("age"); ageStr != "" {
+		age, err := strconv.ParseFloat(ageStr, 64)
+		if err != nil || age < 0 {
+			return offlineEntry{}, fmt.Errorf("invalid age %q", ageStr)
+		}
+		rounded := int(math.Round(age))
+		entry.age.Age = &rounded
+	}
+
+	genderCount, err := parseOfflineCount(cell("gender_count"))
+	if err != nil {
+		return offlineEntry{}, fmt.Errorf("invalid gender_count: %w", err)
+	}
+	entry.gender.Count = genderCount
+	if ratioStr := cell("male_ratio"); ratioStr != "" {
+		ratio, err := strconv.ParseFloat(ratioStr, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return offlineEntry{}, fmt.Errorf("invalid male_ratio %q", ratioStr)
+		}
+		entry.gender.Gender, entry.gender.Probability = "male", ratio
+		if ratio < 0.5 {
+			entry.gender.Gender, entry.gender.Probability = "female", 1-ratio
+		}
+	}
+
+	if countriesStr := cell("countries"); countriesStr != "" {
+		for _, pair := range strings.Split(countriesStr, ";") {
+			countryID, probabilityStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			probability, err := strconv.ParseFloat(probabilityStr, 64)
+			if !ok || countryID == "" || err != nil || probability < 0 || probability > 1 {
+				return offlineEntry{}, fmt.Errorf("invalid country %q", pair)
+			}
+			entry.nationality.Countries = append(entry.nationality.Countries, CountryProbability{
+				CountryID:   strings.ToUpper(countryID),
+				Probability: probability,
+			})
+		}
+		sort.SliceStable(entry.nationality.Countries, func(i, j int) bool {
+			return entry.nationality.Countries[i].Probability > entry.nationality.Countries[j].Probability
+		})
+		entry.nationality.CountryID = entry.nationality.Countries[0].CountryID
+		entry.nationality.Probability = entry.nationality.Countries[0].Probability
+	}
+
+	return entry, nil
+}
+
+func parseOfflineCount(countStr string) (int, error) {
+	if countStr == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("%q is not a count", countStr)
+	}
+	return count, nil
+}
+
+// Len returns the number of names in the dataset.
+func (o *Offline) Len() int {
+	return len(o.entries)
+}
+
+func (o *Offline) Age(_ context.Context, name string) (AgePrediction, error) {
+	entry, ok := o.entries[NormalizeName(name)]
+	if !ok {
+		return AgePrediction{Source: ProviderOffline}, nil
+	}
+	return entry.age, nil
+}
+
+func (o *Offline) Gender(_ context.Context, name string) (GenderPrediction, error) {
+	entry, ok := o.entries[NormalizeName(name)]
+	if !ok {
+		return GenderPrediction{Source: ProviderOffline}, nil
+	}
+	return entry.gender, nil
+}
+
+func (o *Offline) Nationality(_ context.Context, name string) (NationalityPrediction, error) {
+	entry, ok := o.entries[NormalizeName(name)]
+	if !ok {
+		return NationalityPrediction{Source: ProviderOffline}, nil
+	}
+	return entry.nationality, nil
+}
@@ -0,0 +1,93 @@
+package enrich
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOffline(t *testing.T) {
+	offline, err := LoadOffline("testdata/names.csv")
+	if err != nil {
+		t.Fatalf("LoadOffline() error = %v", err)
+	}
+	if offline.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", offline.Len())
+	}
+
+	providers := Providers{Age: offline, Gender: offline, Nationality: offline}
+	ctx := context.Background()
+
+	t.Run("KnownName", func(t *testing.T) {
+		result, err := providers.Enrich(ctx, Query{Name: " john"})
+		if err != nil {
+			t.Fatalf("Enrich() error = %v", err)
+		}
+
+		want := Result{
+			Age:    AgePrediction{Age: intPtr(47), Count: 120000, Source: ProviderOffline},
+			Gender: GenderPrediction{Gender: "male", Probability: 0.994, Count: 250000, Source: ProviderOffline},
+			Nationality: NationalityPrediction{
+				CountryID:   "US",
+				Probability: 0.35,
+				Countries: []CountryProbability{
+					{CountryID: "US", Probability: 0.35},
+					{CountryID: "GB", Probability: 0.10},
+					{CountryID: "IE", Probability: 0.08},
+				},
+				Source: ProviderOffline,
+			},
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("Enrich() = %+v, want %+v", result, want)
+		}
+	})
+
+	t.Run("FemaleRatio", func(t *testing.T) {
+		gender, _ := offline.Gender(ctx, "Maria")
+		if gender.Gender != "female" || gender.Probability != 0.988 {
+			t.Errorf("Gender() = %+v, want female with 0.988", gender)
+		}
+	})
+
+	t.Run("PartialStatistics", func(t *testing.T) {
+		result, err := providers.Enrich(ctx, Query{Name: "Kim"})
+		if err != nil {
+			t.Fatalf("Enrich() error = %v", err)
+		}
+		if result.Age.Known() || result.Nationality.Known() || result.Gender.Gender != "female" {
+			t.Errorf("Enrich() = %+v, want only a gender", result)
+		}
+	})
+
+	t.Run("UnknownName", func(t *testing.T) {
+		result, err := providers.Enrich(ctx, Query{Name: "Zyx"})
+		if err != nil {
+			t.Fatalf("Enrich() error = %v", err)
+		}
+		if result.Age.Known() || result.Gender.Known() || result.Nationality.Known() {
+			t.Errorf("Enrich() = %+v, want nothing known", result)
+		}
+	})
+}
+
+func TestReadOfflineErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		dataset string
+	}{
+		{"MissingNameColumn", "age\n30\n"},
+		{"DuplicateName", "name,age\nJohn,30\njohn,31\n"},
+		{"InvalidRatio", "name,male_ratio\nJohn,1.5\n"},
+		{"InvalidCountry", "name,countries\nJohn,US-0.3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadOffline(strings.NewReader(tt.dataset)); err == nil {
+				t.Errorf("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package enrich
+
+import (
+	"NameEnricher/internal/models"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Name orders recognized by ParseFullName.
+const (
+	// OrderNameFirst is "Name [Patronymic] Surname".
+	OrderNameFirst = "name_first"
+	// OrderSurnameFirst is "Surname Name [Patronymic]", common in Russian and
+	// Kazakh records.
+	OrderSurnameFirst = "surname_first"
+)
+
+// ErrEmptyFullName is returned by ParseFullName for a blank string.
+var ErrEmptyFullName = errors.New("full name is empty")
+
+// Particles that belong to the surname that follows them, as in "van der Berg".
+var surnameParticles = map[string]bool{
+	"van": true, "von": true, "der": true, "den": true, "de": true, "del": true, "della": true,
+	"da": true, "di": true, "du": true, "la": true, "le": true, "bin": true, "ibn": true, "al": true,
+}
+
+// Words that turn the preceding father's name into a Turkic patronymic, as in
+// "Ramiz oglu".
+var patronymicMarkers = map[string]bool{
+	"oglu": true, "ogly": true, "uly": true, "kyzy": true, "qizi": true,
+	"оглы": true, "улы": true, "кызы": true, "кизи": true,
+}
+
+// ParseFullName splits fullName into name, surname and patronymic. A comma
+// ("Ivanov, Ivan") or a patronymic in last position marks the surname-first
+// order; otherwise the order is guessed from which word looks like a surname.
+// Confidence is between 0 and 1 and drops when the order is a guess or
+// several words had to be merged into one part.
+func ParseFullName(fullName string) (models.ParsedName, error) {
+	before, after, hasComma := strings.Cut(fullName, ",")
+	tokens := tokenize(fullName)
+	if len(tokens) == 0 {
+		return models.ParsedName{}, ErrEmptyFullName
+	}
+
+	// Join "Ramiz oglu" into a single patronymic token.
+	for i := len(tokens) - 1; i > 0; i-- {
+		if patronymicMarkers[strings.ToLower(tokens[i])] {
+			tokens = append(tokens[:i-1], append([]string{tokens[i-1] + " " + tokens[i]}, tokens[i+1:]...)...)
+			i--
+		}
+	}
+
+	if len(tokens) == 1 {
+		return models.ParsedName{Name: tokens[0], Order: OrderNameFirst, Confidence: 0.5}, nil
+	}
+
+	isPatronymic := func(token string) bool {
+		_, ok := match(patronymicGenderRules, token)
+		return ok
+	}
+	looksLikeSurname := func(token string) bool {
+		_, gendered := match(surnameGenderRules, token)
+		_, national := match(surnameNationalityRules, token)
+		return gendered || national
+	}
+
+	parsed := models.ParsedName{}
+	last := len(tokens) - 1
+	switch {
+	case hasComma && len(tokenize(before)) > 0 && len(tokenize(after)) > 0:
+		parsed.Order, parsed.Confidence = OrderSurnameFirst, 0.95
+		surnameTokens := tokenize(before)
+		parsed.Surname = strings.Join(surnameTokens, " ")
+		tokens = tokens[len(surnameTokens):]
+		if len(tokens) > 1 && isPatronymic(tokens[len(tokens)-1]) {
+			parsed.Patronymic = tokens[len(tokens)-1]
+			tokens = tokens[:len(tokens)-1]
+		}
+		parsed.Name = strings.Join(tokens, " ")
+	case len(tokens) >= 3 && isPatronymic(tokens[last]):
+		parsed.Order, parsed.Confidence = OrderSurnameFirst, 0.95
+		parsed.Surname, parsed.Patronymic = tokens[0], tokens[last]
+		parsed.Name = strings.Join(tokens[1:last], " ")
+	case len(tokens) >= 3 && isPatronymic(tokens[1]):
+		parsed.Order, parsed.Confidence = OrderNameFirst, 0.95
+		parsed.Name, parsed.Patronymic = tokens[0], tokens[1]
+		parsed.Surname = strings.Join(tokens[2:], " ")
+	case looksLikeSurname(tokens[0]) && !looksLikeSurname(tokens[last]):
+		parsed.Order, parsed.Confidence = OrderSurnameFirst, 0.8
+		parsed.Surname = tokens[0]
+		parsed.Name = strings.Join(tokens[1:], " ")
+	default:
+		parsed.Order, parsed.Confidence = OrderNameFirst, 0.6
+		if looksLikeSurname(tokens[last]) {
+			parsed.Confidence = 0.8
+		}
+		// Particles such as "van der" go with the surname, other middle words
+		// with the name.
+		start := last
+		for start > 1 && surnameParticles[strings.ToLower(tokens[start-1])] {
+			start--
+		}
+		parsed.Name = strings.Join(tokens[:start], " ")
+		parsed.Surname = strings.Join(tokens[start:], " ")
+	}
+
+	if strings.Contains(parsed.Name, " ") {
+		parsed.Confidence -= 0.2
+	}
+	return parsed, nil
+}
+
+// tokenize splits s into words, dropping punctuation other than hyphens and
+// apostrophes, which are part of names such as "Jean-Luc" or "O'Brien".
+func tokenize(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && r != '-' && r != '\''
+	})
+}
@@ -0,0 +1,77 @@
+package enrich
+
+import (
+	"NameEnricher/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestParseFullName(t *testing.T) {
+	tests := []struct {
+		fullName string
+		want     models.ParsedName
+	}{
+		{
+			fullName: "Ivanov Ivan Petrovich",
+			want:     models.ParsedName{Name: "Ivan", Surname: "Ivanov", Patronymic: "Petrovich", Order: OrderSurnameFirst, Confidence: 0.95},
+		},
+		{
+			fullName: "Ivan Petrovich Ivanov",
+			want:     models.ParsedName{Name: "Ivan", Surname: "Ivanov", Patronymic: "Petrovich", Order: OrderNameFirst, Confidence: 0.95},
+		},
+		{
+			fullName: "Кузнецова Анна Андреевна",
+			want:     models.ParsedName{Name: "Анна", Surname: "Кузнецова", Patronymic: "Андреевна", Order: OrderSurnameFirst, Confidence: 0.95},
+		},
+		{
+			fullName: "Aliyev Elchin Ramiz oglu",
+			want:     models.ParsedName{Name: "Elchin", Surname: "Aliyev", Patronymic: "Ramiz oglu", Order: OrderSurnameFirst, Confidence: 0.95},
+		},
+		{
+			fullName: "Nurlanov, Aidos",
+			want:     models.ParsedName{Name: "Aidos", Surname: "Nurlanov", Order: OrderSurnameFirst, Confidence: 0.95},
+		},
+		{
+			fullName: "Shevchenko Taras",
+			want:     models.ParsedName{Name: "Taras", Surname: "Shevchenko", Order: OrderSurnameFirst, Confidence: 0.8},
+		},
+		{
+			fullName: "Taras Shevchenko",
+			want:     models.ParsedName{Name: "Taras", Surname: "Shevchenko", Order: OrderNameFirst, Confidence: 0.8},
+		},
+		{
+			fullName: "John Smith",
+			want:     models.ParsedName{Name: "John", Surname: "Smith", Order: OrderNameFirst, Confidence: 0.6},
+		},
+		{
+			fullName: "Ludwig van Beethoven",
+			want:     models.ParsedName{Name: "Ludwig", Surname: "van Beethoven", Order: OrderNameFirst, Confidence: 0.6},
+		},
+		{
+			fullName: "  Jean-Luc  O'Brien ",
+			want:     models.ParsedName{Name: "Jean-Luc", Surname: "O'Brien", Order: OrderNameFirst, Confidence: 0.6},
+		},
+		{
+			fullName: "Madonna",
+			want:     models.ParsedName{Name: "Madonna", Order: OrderNameFirst, Confidence: 0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fullName, func(t *testing.T) {
+			got, err := ParseFullName(tt.fullName)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFullName(%q) = %+v, want %+v", tt.fullName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFullNameEmpty(t *testing.T) {
+	if _, err := ParseFullName(" , "); !errors.Is(err, ErrEmptyFullName) {
+		t.Errorf("Expected ErrEmptyFullName, got %v", err)
+	}
+}
@@ -0,0 +1,389 @@
+package enrich
+
+import (
+	"NameEnricher/pkg/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	AgifyURL       = "https://api.agify.io"
+	GenderizeURL   = "https://api.genderize.io"
+	NationalizeURL = "https://api.nationalize.io"
+)
+
+// Provider names used in errors and quotas.
+const (
+	ProviderAgify       = "agify"
+	ProviderGenderize   = "genderize"
+	ProviderNationalize = "nationalize"
+)
+
+// Agify is an AgeProvider backed by the agify.io API.
+type Agify struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+	// Quota tracks the rate limit reported by the API; nil disables tracking.
+	Quota *Quota
+	// Breaker stops calling the API while it keeps failing; nil disables it.
+	Breaker *Breaker
+}
+
+// Genderize is a GenderProvider backed by the genderize.io API.
+type Genderize struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+	// Quota tracks the rate limit reported by the API; nil disables tracking.
+	Quota *Quota
+	// Breaker stops calling the API while it keeps failing; nil disables it.
+	Breaker *Breaker
+}
+
+// Nationalize is a NationalityProvider backed by the nationalize.io API.
+type Nationalize struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+	// Quota tracks the rate limit reported by the API; nil disables tracking.
+	Quota *Quota
+	// Breaker stops calling the API while it keeps failing; nil disables it.
+	Breaker *Breaker
+}
+
+func NewAgify() *Agify {
+	return &Agify{BaseURL: AgifyURL, Client: &http.Client{Timeout: DefaultTimeout}, Quota: NewQuota(ProviderAgify),
+		Breaker: NewBreaker(ProviderAgify, DefaultBreakerThreshold, DefaultBreakerCooldown)}
+}
+
+func NewGenderize() *Genderize {
+	return &Genderize{BaseURL: GenderizeURL, Client: &http.Client{Timeout: DefaultTimeout}, Quota: NewQuota(ProviderGenderize),
+		Breaker: NewBreaker(ProviderGenderize, DefaultBreakerThreshold, DefaultBreakerCooldown)}
+}
+
+func NewNationalize() *Nationalize {
+	return &Nationalize{BaseURL: NationalizeURL, Client: &http.Client{Timeout: DefaultTimeout}, Quota: NewQuota(ProviderNationalize),
+		Breaker: NewBreaker(ProviderNationalize, DefaultBreakerThreshold, DefaultBreakerCooldown)}
+}
+
+// MaxBatchSize is the largest number of names the providers accept in a
+// single name[] request.
+const MaxBatchSize = 10
+
+type agifyResponse struct {
+	Age   *int   `json:"age"`
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+}
+
+func (r agifyResponse) prediction() AgePrediction {
+	if r.Age == nil {
+		logger.Log.Warnf("No age data found for name: %s", r.Name)
+		return AgePrediction{Count: r.Count, Source: ProviderAgify}
+	}
+	logger.Log.Infof("Successfully determined age %d (samples: %d) for name: %s", *r.Age, r.Count, r.Name)
+	return AgePrediction{Age: r.Age, Count: r.Count, Source: ProviderAgify}
+}
+
+type genderizeResponse struct {
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+	Name        string  `json:"name"`
+}
+
+func (r genderizeResponse) prediction() GenderPrediction {
+	if r.Gender == nil || *r.Gender == "" {
+		logger.Log.Warnf("No gender data found for name: %s", r.Name)
+		return GenderPrediction{Count: r.Count, Source: ProviderGenderize}
+	}
+	logger.Log.Infof("Successfully determined gender '%s' (probability: %.2f) for name: %s",
+		*r.Gender, r.Probability, r.Name)
+	return GenderPrediction{Gender: *r.Gender, Probability: r.Probability, Count: r.Count, Source: ProviderGenderize}
+}
+
+type nationalizeResponse struct {
+	Country []struct {
+		CountryId   string  `json:"country_id"`
+		Probability float64 `json:"probability"`
+	} `json:"country"`
+	Name string `json:"name"`
+}
+
+func (r nationalizeResponse) prediction() NationalityPrediction {
+	if len(r.Country) == 0 {
+		logger.Log.Warnf("No nationality data found for name: %s", r.Name)
+		return NationalityPrediction{Source: ProviderNationalize}
+	}
+
+	countries := make([]CountryProbability, 0, len(r.Country))
+	for _, country := range r.Country {
+		countries = append(countries, CountryProbability{CountryID: country.CountryId, Probability: country.Probability})
+	}
+	sort.SliceStable(countries, func(i, j int) bool {
+		return countries[i].Probability > countries[j].Probability
+	})
+
+	result := NationalityPrediction{
+		CountryID:   countries[0].CountryID,
+		Probability: countries[0].Probability,
+		Countries:   countries,
+		Source:      ProviderNationalize,
+	}
+
+	logger.Log.Infof("Successfully determined nationality '%s' (probability: %.2f) for name: %s",
+		result.CountryID, result.Probability, r.Name)
+	return result
+}
+
+func (a *Agify) Age(ctx context.Context, name string) (AgePrediction, error) {
+	return a.LocalizedAge(ctx, name, "")
+}
+
+// LocalizedAge requests the age of name among the people of countryID; an
+// empty countryID asks for the worldwide estimate.
+func (a *Agify) LocalizedAge(ctx context.Context, name, countryID string) (AgePrediction, error) {
+	logger.Log.Infof("Requesting age data for name: %s%s", name, countrySuffix(countryID))
+
+	var response agifyResponse
+	if err := a.endpoint().getJSON(ctx, 1, nameQuery(name, countryID), &response); err != nil {
+		logger.Log.Errorf("Failed to request age API: %v", err)
+		return AgePrediction{}, fmt.Errorf("failed to request age API: %w", err)
+	}
+	response.Name = name
+
+	return response.prediction(), nil
+}
+
+// AgeBatch requests the ages of up to MaxBatchSize names at once.
+func (a *Agify) AgeBatch(ctx context.Context, names []string) ([]AgePrediction, error) {
+	return a.LocalizedAgeBatch(ctx, names, "")
+}
+
+// LocalizedAgeBatch is the batch counterpart of LocalizedAge.
+func (a *Agify) LocalizedAgeBatch(ctx context.Context, names []string, countryID string) ([]AgePrediction, error) {
+	logger.Log.Infof("Requesting age data for %d names%s", len(names), countrySuffix(countryID))
+
+	var response []agifyResponse
+	if err := a.endpoint().getBatchJSON(ctx, names, countryID, &response); err != nil {
+		logger.Log.Errorf("Failed to request age API: %v", err)
+		return nil, fmt.Errorf("failed to request age API: %w", err)
+	}
+	if len(response) != len(names) {
+		return nil, &ProviderError{Provider: ProviderAgify, StatusCode: http.StatusOK, Err: ErrProviderUnavailable,
+			Cause: fmt.Errorf("returned %d results for %d names", len(response), len(names))}
+	}
+
+	predictions := make([]AgePrediction, len(response))
+	for i, r := range response {
+		r.Name = names[i]
+		predictions[i] = r.prediction()
+	}
+	return predictions, nil
+}
+
+func (g *Genderize) Gender(ctx context.Context, name string) (GenderPrediction, error) {
+	return g.LocalizedGender(ctx, name, "")
+}
+
+// LocalizedGender requests the gender of name among the people of countryID;
+// an empty countryID asks for the worldwide estimate.
+func (g *Genderize) LocalizedGender(ctx context.Context, name, countryID string) (GenderPrediction, error) {
+	logger.Log.Infof("Requesting gender data for name: %s%s", name, countrySuffix(countryID))
+
+	var response genderizeResponse
+	if err := g.endpoint().getJSON(ctx, 1, nameQuery(name, countryID), &response); err != nil {
+		logger.Log.Errorf("Failed to request gender API: %v", err)
+		return GenderPrediction{}, fmt.Errorf("failed to request gender API: %w", err)
+	}
+	response.Name = name
+
+	return response.prediction(), nil
+}
+
+// GenderBatch requests the genders of up to MaxBatchSize names at once.
+func (g *Genderize) GenderBatch(ctx context.Context, names []string) ([]GenderPrediction, error) {
+	return g.LocalizedGenderBatch(ctx, names, "")
+}
+
+// LocalizedGenderBatch is the batch counterpart of LocalizedGender.
+func (g *Genderize) LocalizedGenderBatch(ctx context.Context, names []string, countryID string) ([]GenderPrediction, error) {
+	logger.Log.Infof("Requesting gender data for %d names%s", len(names), countrySuffix(countryID))
+
+	var response []genderizeResponse
+	if err := g.endpoint().getBatchJSON(ctx, names, countryID, &response); err != nil {
+		logger.Log.Errorf("Failed to request gender API: %v", err)
+		return nil, fmt.Errorf("failed to request gender API: %w", err)
+	}
+	if len(response) != len(names) {
+		return nil, &ProviderError{Provider: ProviderGenderize, StatusCode: http.StatusOK, Err: ErrProviderUnavailable,
+			Cause: fmt.Errorf("returned %d results for %d names", len(response), len(names))}
+	}
+
+	predictions := make([]GenderPrediction, len(response))
+	for i, r := range response {
+		r.Name = names[i]
+		predictions[i] = r.prediction()
+	}
+	return predictions, nil
+}
+
+func (n *Nationalize) Nationality(ctx context.Context, name string) (NationalityPrediction, error) {
+	logger.Log.Infof("Requesting nationality data for name: %s", name)
+
+	var response nationalizeResponse
+	if err := n.endpoint().getJSON(ctx, 1, nameQuery(name, ""), &response); err != nil {
+		logger.Log.Errorf("Failed to request nationality API: %v", err)
+		return NationalityPrediction{}, fmt.Errorf("failed to request nationality API: %w", err)
+	}
+	response.Name = name
+
+	return response.prediction(), nil
+}
+
+// NationalityBatch requests the nationalities of up to MaxBatchSize names at once.
+func (n *Nationalize) NationalityBatch(ctx context.Context, names []string) ([]NationalityPrediction, error) {
+	logger.Log.Infof("Requesting nationality data for %d names", len(names))
+
+	var response []nationalizeResponse
+	if err := n.endpoint().getBatchJSON(ctx, names, "", &response); err != nil {
+		logger.Log.Errorf("Failed to request nationality API: %v", err)
+		return nil, fmt.Errorf("failed to request nationality API: %w", err)
+	}
+	if len(response) != len(names) {
+		return nil, &ProviderError{Provider: ProviderNationalize, StatusCode: http.StatusOK, Err: ErrProviderUnavailable,
+			Cause: fmt.Errorf("returned %d results for %d names", len(response), len(names))}
+	}
+
+	predictions := make([]NationalityPrediction, len(response))
+	for i, r := range response {
+		r.Name = names[i]
+		predictions[i] = r.prediction()
+	}
+	return predictions, nil
+}
+
+// nameQuery returns the query for a single name, localized to countryID
+// when it is set.
+func nameQuery(name, countryID string) url.Values {
+	query := url.Values{}
+	query.Set("name", name)
+	if countryID != "" {
+		query.Set("country_id", countryID)
+	}
+	return query
+}
+
+// countrySuffix formats countryID for log messages.
+func countrySuffix(countryID string) string {
+	if countryID == "" {
+		return ""
+	}
+	return " in country " + countryID
+}
+
+// endpoint is the connection to one provider API.
+type endpoint struct {
+	provider string
+	baseURL  string
+	apiKey   string
+	client   *http.Client
+	quota    *Quota
+	breaker  *Breaker
+}
+
+func (a *Agify) endpoint() endpoint {
+	return endpoint{provider: ProviderAgify, baseURL: a.BaseURL, apiKey: a.APIKey, client: a.Client, quota: a.Quota,
+		breaker: a.Breaker}
+}
+
+func (g *Genderize) endpoint() endpoint {
+	return endpoint{provider: ProviderGenderize, baseURL: g.BaseURL, apiKey: g.APIKey, client: g.Client, quota: g.Quota,
+		breaker: g.Breaker}
+}
+
+func (n *Nationalize) endpoint() endpoint {
+	return endpoint{provider: ProviderNationalize, baseURL: n.BaseURL, apiKey: n.APIKey, client: n.Client, quota: n.Quota,
+		breaker: n.Breaker}
+}
+
+// getBatchJSON queries the API for several names using the name[] parameter,
+// which makes the providers answer with a JSON array in the order of names.
+// A non-empty countryID localizes every name of the batch.
+func (e endpoint) getBatchJSON(ctx context.Context, names []string, countryID string, out interface{}) error {
+	if len(names) > MaxBatchSize {
+		return fmt.Errorf("batch of %d names exceeds the limit of %d", len(names), MaxBatchSize)
+	}
+	query := url.Values{"name[]": names}
+	if countryID != "" {
+		query.Set("country_id", countryID)
+	}
+	return e.getJSON(ctx, len(names), query, out)
+}
+
+// getJSON queries the API with query and decodes the JSON body into out. The
+// apikey parameter is only sent when an API key is set. cost is the number of
+// names in the request, which is what the providers count against the quota;
+// the request is not sent while the circuit breaker is open, or when the quota
+// is known not to cover it. Quota is only taken for requests that are sent.
+//
+// Failures are returned as *ProviderError or *QuotaError, so callers can tell
+// an unusable name from an unreachable provider.
+func (e endpoint) getJSON(ctx context.Context, cost int, query url.Values, out interface{}) error {
+	quota := e.quota
+	if quota == nil {
+		quota = NewQuota(e.provider)
+	}
+
+	if e.apiKey != "" {
+		query.Set("apikey", e.apiKey)
+	}
+
+	apiUrl := strings.TrimRight(e.baseURL, "/") + "/?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	if err := e.breaker.Allow(); err != nil {
+		return err
+	}
+	if err := quota.Reserve(cost); err != nil {
+		e.breaker.Release()
+		return err
+	}
+	err = e.do(req, quota, out)
+	e.breaker.Record(err)
+	return err
+}
+
+// do sends req and decodes a successful response into out.
+func (e endpoint) do(req *http.Request, quota *Quota, out interface{}) error {
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return &ProviderError{Provider: e.provider, Err: ErrProviderUnavailable, Cause: err}
+	}
+	defer resp.Body.Close()
+
+	logger.Log.Debugf("Received response from %s with status: %s", e.baseURL, resp.Status)
+	quota.Update(resp)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return quota.Exhausted()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(e.provider, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &ProviderError{Provider: e.provider, StatusCode: resp.StatusCode, Err: ErrProviderUnavailable,
+			Cause: fmt.Errorf("failed to decode response: %w", err)}
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package enrich
+
+import (
+	"NameEnricher/pkg/logger"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init()
+
+	exitCode := m.Run()
+
+	os.Exit(exitCode)
+}
+
+func TestAgifyAge(t *testing.T) {
+	agifyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			t.Error("Name parameter is missing")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var age interface{}
+		switch name {
+		case "John":
+			age = 35
+		case "Mary":
+			age = 28
+		case "ErrorCase":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "NullAge":
+			age = nil
+		default:
+			age = 30
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name":  name,
+			"age":   age,
+			"count": 1000,
+		})
+	}))
+	defer agifyServer.Close()
+
+	agify := &Agify{BaseURL: agifyServer.URL, Client: agifyServer.Client()}
+
+	tests := []struct {
+		name       string
+		personName string
+		wantAge    *int
+		wantErr    bool
+	}{
+		{"Valid name John", "John", intPtr(35), false},
+		{"Valid name Mary", "Mary", intPtr(28), false},
+		{"Default age", "Unknown", intPtr(30), false},
+		{"Null age", "NullAge", nil, false},
+		{"Error case", "ErrorCase", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			age, err := agify.Age(context.Background(), tt.personName)
+
+			// Проверяем результаты
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Agify.Age() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && (!reflect.DeepEqual(age.Age, tt.wantAge) || age.Count != 1000) {
+				t.Errorf("Agify.Age() = %+v, want age %v with 1000 samples", age, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestGenderizeGender(t *testing.T) {
+	genderizeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			t.Error("Name parameter is missing")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var gender interface{}
+		switch name {
+		case "John":
+			gender = "male"
+		case "Mary":
+			gender = "female"
+		case "ErrorCase":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "EmptyGender":
+			gender = ""
+		case "NullGender":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"name":        name,
+				"gender":      nil,
+				"probability": 0.0,
+				"count":       0,
+			})
+			return
+		default:
+			gender = "unknown"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name":        name,
+			"gender":      gender,
+			"probability": 0.75,
+			"count":       500,
+		})
+	}))
+	defer genderizeServer.Close()
+
+	genderize := &Genderize{BaseURL: genderizeServer.URL, Client: genderizeServer.Client()}
+
+	tests := []struct {
+		name       string
+		personName string
+		wantGender string
+		wantErr    bool
+	}{
+		{"Valid name John", "John", "male", false},
+		{"Valid name Mary", "Mary", "female", false},
+		{"Unknown gender", "Unknown", "unknown", false},
+		{"Empty gender", "EmptyGender", "", false},
+		{"Null gender", "NullGender", "", false},
+		{"Error case", "ErrorCase", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gender, err := genderize.Gender(context.Background(), tt.personName)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Genderize.Gender() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+			if gender.Gender != tt.wantGender || gender.Known() != (tt.wantGender != "") {
+				t.Errorf("Genderize.Gender() = %+v, want gender %q", gender, tt.wantGender)
+			}
+			if gender.Known() && (gender.Probability != 0.75 || gender.Count != 500) {
+				t.Errorf("Genderize.Gender() = %+v, want probability 0.75 and 500 samples", gender)
+			}
+		})
+	}
+}
+
+func TestNationalizeNationality(t *testing.T) {
+	nationalizeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			t.Error("Name parameter is missing")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var response map[string]interface{}
+		switch name {
+		case "John":
+			response = map[string]interface{}{
+				"name": name,
+				"country": []map[string]interface{}{
+					{"country_id": "US", "probability": 0.8},
+					{"country_id": "GB", "probability": 0.1},
+				},
+			}
+		case "Boris":
+			response = map[string]interface{}{
+				"name": name,
+				"country": []map[string]interface{}{
+					{"country_id": "RU", "probability": 0.7},
+					{"country_id": "BG", "probability": 0.2},
+				},
+			}
+		case "ErrorCase":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "EmptyCountries":
+			response = map[string]interface{}{
+				"name":    name,
+				"country": []map[string]interface{}{},
+			}
+		default:
+			response = map[string]interface{}{
+				"name": name,
+				"country": []map[string]interface{}{
+					{"country_id": "XX", "probability": 0.5},
+				},
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer nationalizeServer.Close()
+
+	nationalize := &Nationalize{BaseURL: nationalizeServer.URL, Client: nationalizeServer.Client()}
+
+	tests := []struct {
+		name            string
+		personName      string
+		wantNationality string
+		wantProbability float64
+		wantErr         bool
+	}{
+		{"Valid name John", "John", "US", 0.8, false},
+		{"Valid name Boris", "Boris", "RU", 0.7, false},
+		{"Default nationality", "Unknown", "XX", 0.5, false},
+		{"Empty countries list", "EmptyCountries", "", 0, false},
+		{"Error case", "ErrorCase", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nationality, err := nationalize.Nationality(context.Background(), tt.personName)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Nationalize.Nationality() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && (nationality.CountryID != tt.wantNationality || nationality.Probability != tt.wantProbability) {
+				t.Errorf("Nationalize.Nationality() = %+v, want %v (%.2f)", nationality, tt.wantNationality, tt.wantProbability)
+			}
+		})
+	}
+}
+
+func TestNationalizeRanksCountries(t *testing.T) {
+	nationalizeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": r.URL.Query().Get("name"),
+			"country": []map[string]interface{}{
+				{"country_id": "BG", "probability": 0.2},
+				{"country_id": "RU", "probability": 0.7},
+				{"country_id": "UA", "probability": 0.1},
+			},
+		})
+	}))
+	defer nationalizeServer.Close()
+
+	nationalize := &Nationalize{BaseURL: nationalizeServer.URL, Client: nationalizeServer.Client()}
+
+	nationality, err := nationalize.Nationality(context.Background(), "Boris")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []CountryProbability{
+		{CountryID: "RU", Probability: 0.7},
+		{CountryID: "BG", Probability: 0.2},
+		{CountryID: "UA", Probability: 0.1},
+	}
+	if !reflect.DeepEqual(nationality.Countries, want) {
+		t.Errorf("Countries = %+v, want %+v", nationality.Countries, want)
+	}
+	if nationality.CountryID != "RU" {
+		t.Errorf("CountryID = %q, want %q", nationality.CountryID, "RU")
+	}
+}
+
+func TestProvidersBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := r.URL.Query()["name[]"]
+		if len(names) == 0 {
+			t.Error("name[] parameter is missing")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		response := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			entry := map[string]interface{}{"name": name, "count": 10}
+			switch r.URL.Path {
+			case "/agify/":
+				entry["age"] = len(name)
+			case "/genderize/":
+				entry["gender"] = "female"
+				entry["probability"] = 0.9
+			case "/nationalize/":
+				entry["country"] = []map[string]interface{}{
+					{"country_id": "FR", "probability": 0.2},
+					{"country_id": "BE", "probability": 0.4},
+				}
+			}
+			if name == "Nobody" {
+				entry["age"] = nil
+				entry["gender"] = nil
+				entry["country"] = []map[string]interface{}{}
+			}
+			response = append(response, entry)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	names := []string{"Claire", "Nobody"}
+	ctx := context.Background()
+
+	ages, err := (&Agify{BaseURL: server.URL + "/agify", Client: server.Client()}).AgeBatch(ctx, names)
+	if err != nil {
+		t.Fatalf("AgeBatch() error = %v", err)
+	}
+	if !ages[0].Known() || *ages[0].Age != 6 || ages[1].Known() {
+		t.Errorf("AgeBatch() = %+v", ages)
+	}
+
+	genders, err := (&Genderize{BaseURL: server.URL + "/genderize", Client: server.Client()}).GenderBatch(ctx, names)
+	if err != nil {
+		t.Fatalf("GenderBatch() error = %v", err)
+	}
+	if genders[0].Gender != "female" || genders[1].Known() {
+		t.Errorf("GenderBatch() = %+v", genders)
+	}
+
+	nationalities, err := (&Nationalize{BaseURL: server.URL + "/nationalize", Client: server.Client()}).NationalityBatch(ctx, names)
+	if err != nil {
+		t.Fatalf("NationalityBatch() error = %v", err)
+	}
+	if nationalities[0].CountryID != "BE" || nationalities[1].Known() {
+		t.Errorf("NationalityBatch() = %+v", nationalities)
+	}
+
+	tooMany := make([]string, MaxBatchSize+1)
+	if _, err := (&Agify{BaseURL: server.URL + "/agify", Client: server.Client()}).AgeBatch(ctx, tooMany); err == nil {
+		t.Errorf("Expected error for %d names, got nil", len(tooMany))
+	}
+}
+
+func TestProvidersSendCountryID(t *testing.T) {
+	var countries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		countries = append(countries, r.URL.Query().Get("country_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if names := r.URL.Query()["name[]"]; names != nil {
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"name": names[0], "gender": "female", "probability": 0.8, "count": 3}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "Andrea", "age": 41, "count": 3})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	agify := &Agify{BaseURL: server.URL, Client: server.Client()}
+	genderize := &Genderize{BaseURL: server.URL, Client: server.Client()}
+
+	if _, err := agify.LocalizedAge(ctx, "Andrea", "IT"); err != nil {
+		t.Fatalf("LocalizedAge() error = %v", err)
+	}
+	if _, err := agify.Age(ctx, "Andrea"); err != nil {
+		t.Fatalf("Age() error = %v", err)
+	}
+	if _, err := genderize.LocalizedGenderBatch(ctx, []string{"Andrea"}, "US"); err != nil {
+		t.Fatalf("LocalizedGenderBatch() error = %v", err)
+	}
+
+	if want := []string{"IT", "", "US"}; !reflect.DeepEqual(countries, want) {
+		t.Errorf("country_id parameters = %q, want %q", countries, want)
+	}
+}
+
+func TestProvidersEncodeName(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Query().Get("name"))
+		received = append(received, r.URL.Query()["name[]"]...)
+
+		w.Header().Set("Content-Type", "application/json")
+		if names := r.URL.Query()["name[]"]; names != nil {
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"name": names[0], "age": 30, "count": 1}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "x", "age": 30, "count": 1})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	agify := &Agify{BaseURL: server.URL, Client: server.Client()}
+	name := "Anne Marie&country_id=US#Жанна"
+
+	if _, err := agify.Age(ctx, name); err != nil {
+		t.Fatalf("Age() error = %v", err)
+	}
+	if _, err := agify.AgeBatch(ctx, []string{name}); err != nil {
+		t.Fatalf("AgeBatch() error = %v", err)
+	}
+
+	if want := []string{name, "", name}; !reflect.DeepEqual(received, want) {
+		t.Errorf("Received names %q, want %q", received, want)
+	}
+}
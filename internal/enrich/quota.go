@@ -0,0 +1,111 @@
+package enrich
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate-limit headers sent by agify, genderize and nationalize.
+const (
+	HeaderRateLimitRemaining = "X-Rate-Limit-Remaining"
+	HeaderRateLimitReset     = "X-Rate-Limit-Reset"
+)
+
+// DefaultQuotaReset is assumed when a provider answers 429 without telling
+// when its quota resets.
+const DefaultQuotaReset = time.Hour
+
+// ErrQuotaExhausted is matched by every *QuotaError.
+var ErrQuotaExhausted = errors.New("provider quota exhausted")
+
+// QuotaError reports that a provider refused, or would refuse, a request
+// because its quota is used up until Reset. StatusCode is 429 when the
+// provider refused it and zero when it was not sent.
+type QuotaError struct {
+	Provider   string
+	StatusCode int
+	Reset      time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota exhausted, resets in %s", e.Provider, e.RetryAfter().Round(time.Second))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExhausted
+}
+
+// RetryAfter returns how long to wait before the quota resets.
+func (e *QuotaError) RetryAfter() time.Duration {
+	return max(time.Until(e.Reset), 0)
+}
+
+// Quota tracks the remaining request quota of a single provider from its
+// rate-limit headers. It is safe for concurrent use.
+type Quota struct {
+	Provider string
+
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+func NewQuota(provider string) *Quota {
+	return &Quota{Provider: provider}
+}
+
+// Reserve takes n requests from the quota, or returns a *QuotaError when the
+// last known quota cannot cover them. Until the provider reported its quota,
+// every request is allowed.
+func (q *Quota) Reserve(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.known || !time.Now().Before(q.reset) {
+		return nil
+	}
+	if q.remaining < n {
+		return &QuotaError{Provider: q.Provider, Reset: q.reset}
+	}
+	q.remaining -= n
+	return nil
+}
+
+// Update records the quota reported by a provider response. A 429 response
+// exhausts the quota even if the headers are missing.
+func (q *Quota) Update(resp *http.Response) {
+	remaining, remainingErr := strconv.Atoi(resp.Header.Get(HeaderRateLimitRemaining))
+	resetSeconds, resetErr := strconv.Atoi(resp.Header.Get(HeaderRateLimitReset))
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		q.known = true
+		q.remaining = 0
+		q.reset = time.Now().Add(DefaultQuotaReset)
+		if resetErr == nil {
+			q.reset = time.Now().Add(time.Duration(resetSeconds) * time.Second)
+		}
+		return
+	}
+
+	if remainingErr != nil || resetErr != nil {
+		return
+	}
+	q.known = true
+	q.remaining = remaining
+	q.reset = time.Now().Add(time.Duration(resetSeconds) * time.Second)
+}
+
+// Exhausted returns the error for a request the provider refused with 429.
+func (q *Quota) Exhausted() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return &QuotaError{Provider: q.Provider, StatusCode: http.StatusTooManyRequests, Reset: q.reset}
+}
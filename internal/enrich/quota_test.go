@@ -0,0 +1,118 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	quotaResponse := func(status, remaining, reset int) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		if remaining >= 0 {
+			resp.Header.Set(HeaderRateLimitRemaining, strconv.Itoa(remaining))
+		}
+		if reset >= 0 {
+			resp.Header.Set(HeaderRateLimitReset, strconv.Itoa(reset))
+		}
+		return resp
+	}
+
+	t.Run("UnknownAllowsRequests", func(t *testing.T) {
+		quota := NewQuota("agify")
+		if err := quota.Reserve(MaxBatchSize); err != nil {
+			t.Errorf("Reserve() = %v, want nil", err)
+		}
+	})
+
+	t.Run("TracksRemaining", func(t *testing.T) {
+		quota := NewQuota("agify")
+		quota.Update(quotaResponse(http.StatusOK, 3, 60))
+
+		if err := quota.Reserve(2); err != nil {
+			t.Fatalf("Reserve(2) = %v, want nil", err)
+		}
+		err := quota.Reserve(2)
+		if !errors.Is(err, ErrQuotaExhausted) {
+			t.Fatalf("Reserve(2) = %v, want ErrQuotaExhausted", err)
+		}
+
+		var quotaErr *QuotaError
+		if !errors.As(err, &quotaErr) || quotaErr.Provider != "agify" {
+			t.Errorf("Expected *QuotaError for agify, got %v", err)
+		}
+		if retryAfter := quotaErr.RetryAfter(); retryAfter <= 0 || retryAfter > time.Minute {
+			t.Errorf("RetryAfter() = %v, want up to 1m", retryAfter)
+		}
+	})
+
+	t.Run("ResetRestoresQuota", func(t *testing.T) {
+		quota := NewQuota("genderize")
+		quota.Update(quotaResponse(http.StatusOK, 0, 0))
+
+		if err := quota.Reserve(1); err != nil {
+			t.Errorf("Reserve() after reset = %v, want nil", err)
+		}
+	})
+
+	t.Run("TooManyRequestsWithoutHeaders", func(t *testing.T) {
+		quota := NewQuota("nationalize")
+		quota.Update(quotaResponse(http.StatusTooManyRequests, -1, -1))
+
+		var quotaErr *QuotaError
+		if err := quota.Reserve(1); !errors.As(err, &quotaErr) {
+			t.Fatalf("Reserve() = %v, want *QuotaError", err)
+		}
+		if quotaErr.RetryAfter() < DefaultQuotaReset-time.Minute {
+			t.Errorf("RetryAfter() = %v, want about %v", quotaErr.RetryAfter(), DefaultQuotaReset)
+		}
+	})
+}
+
+func TestProviderRespectsQuota(t *testing.T) {
+	remaining := 1
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set(HeaderRateLimitReset, "120")
+		if remaining == 0 {
+			w.Header().Set(HeaderRateLimitRemaining, "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Request limit reached"})
+			return
+		}
+		remaining--
+		w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(remaining))
+		json.NewEncoder(w).Encode(map[string]interface{}{"age": 40, "count": 10})
+	}))
+	defer server.Close()
+
+	agify := &Agify{BaseURL: server.URL, Client: server.Client(), Quota: NewQuota("agify")}
+	ctx := context.Background()
+
+	if _, err := agify.Age(ctx, "John"); err != nil {
+		t.Fatalf("First request error = %v", err)
+	}
+
+	_, err := agify.Age(ctx, "John")
+	if !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("Second request error = %v, want ErrQuotaExhausted", err)
+	}
+	if requests != 1 {
+		t.Errorf("Provider received %d requests, want 1: the exhausted quota must not be spent", requests)
+	}
+
+	// Without tracking, the request is sent and the 429 itself is reported.
+	untracked := &Agify{BaseURL: server.URL, Client: server.Client()}
+	if _, err := untracked.Age(ctx, "John"); !errors.Is(err, ErrQuotaExhausted) {
+		t.Errorf("Untracked request error = %v, want ErrQuotaExhausted", err)
+	}
+	if requests != 2 {
+		t.Errorf("Provider received %d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,146 @@
+package enrich
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SourceRules is the Source of predictions made by Rules.
+const SourceRules = "rules"
+
+// Rules predicts the gender from the patronymic or surname and the
+// nationality from the surname, using well-known endings such as -ovich/-ovna
+// or -enko. Enricher applies them on top of the provider results: a rule
+// prediction replaces a provider answer that is unknown or failed, or less
+// probable than MinGenderProbability or MinNationalityProbability and than the
+// rule itself. Endings shared by several countries only answer for a
+// provider that did not.
+type Rules struct {
+	MinGenderProbability      float64
+	MinNationalityProbability float64
+}
+
+// NewRules returns the rules described by cfg, or nil when they are disabled.
+func NewRules(cfg Config) *Rules {
+	if !cfg.Rules {
+		return nil
+	}
+	return &Rules{
+		MinGenderProbability:      cfg.MinGenderProbability,
+		MinNationalityProbability: cfg.MinNationalityProbability,
+	}
+}
+
+// suffixRule maps names ending in one of suffixes to value.
+type suffixRule struct {
+	suffixes    []string
+	value       string
+	probability float64
+	// fallback rules are too ambiguous to correct a provider; they only
+	// answer when the provider knows nothing.
+	fallback bool
+}
+
+// Patronymics, including the Turkic -ogly/-kyzy forms, leave little doubt
+// about the gender.
+var patronymicGenderRules = []suffixRule{
+	{suffixes: []string{"ovich", "evich", "ich", "ogly", "oglu", "uly", "ович", "евич", "ич", "оглы", "улы"}, value: "male", probability: 0.99},
+	{suffixes: []string{"ovna", "evna", "ichna", "kyzy", "qizi", "овна", "евна", "ична", "кызы", "кизи"}, value: "female", probability: 0.99},
+}
+
+// Slavic surnames agree with the gender of their bearer.
+var surnameGenderRules = []suffixRule{
+	{suffixes: []string{"ova", "eva", "skaya", "ska", "cka", "ová", "ова", "ева", "ёва", "ина", "ская", "цкая"}, value: "female", probability: 0.9},
+	{suffixes: []string{"ov", "ev", "sky", "skiy", "skii", "ski", "cki", "ов", "ев", "ёв", "ин", "ский", "цкий"}, value: "male", probability: 0.9},
+}
+
+// Surname endings typical of a country. Most are shared by neighbouring
+// countries, hence the modest probabilities. The -ov/-ev/-in endings are as
+// common in Bulgaria, Azerbaijan and Central Asia (Ivanov, Aliyev, Nurlanov)
+// as in Russia, so they never replace a country the provider knows.
+var surnameNationalityRules = []suffixRule{
+	{suffixes: []string{"shvili", "dze", "швили", "дзе"}, value: "GE", probability: 0.9},
+	{suffixes: []string{"escu", "eanu"}, value: "RO", probability: 0.85},
+	{suffixes: []string{"yan", "ян"}, value: "AM", probability: 0.8},
+	{suffixes: []string{"enko", "chuk", "yuk", "енко", "чук", "юк"}, value: "UA", probability: 0.7},
+	{suffixes: []string{"oglu", "oğlu"}, value: "TR", probability: 0.7},
+	{suffixes: []string{"wicz", "czyk", "ski", "ska", "cki", "cka"}, value: "PL", probability: 0.6},
+	{suffixes: []string{"ović", "ovic", "ević", "evic"}, value: "RS", probability: 0.5},
+	{suffixes: []string{"ov", "ova", "ev", "eva", "ов", "ова", "ев", "ева", "ин", "ина", "ский", "ская"}, value: "RU", probability: 0.3, fallback: true},
+}
+
+// match returns the first rule with a suffix that word ends in. The rest of
+// the word must be at least two letters long, so that short names such as
+// "Ich" do not match.
+func match(rules []suffixRule, word string) (suffixRule, bool) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	for _, rule := range rules {
+		for _, suffix := range rule.suffixes {
+			if strings.HasSuffix(word, suffix) && utf8.RuneCountInString(word)-utf8.RuneCountInString(suffix) >= 2 {
+				return rule, true
+			}
+		}
+	}
+	return suffixRule{}, false
+}
+
+// Gender predicts the gender from the patronymic or, failing that, the surname.
+func (r *Rules) Gender(surname, patronymic string) GenderPrediction {
+	rule, ok := match(patronymicGenderRules, patronymic)
+	if !ok {
+		rule, ok = match(surnameGenderRules, surname)
+	}
+	if !ok {
+		return GenderPrediction{Source: SourceRules}
+	}
+	return GenderPrediction{Gender: rule.value, Probability: rule.probability, Source: SourceRules}
+}
+
+// Nationality predicts the nationality from the surname.
+func (r *Rules) Nationality(surname string) NationalityPrediction {
+	prediction, _ := r.nationality(surname)
+	return prediction
+}
+
+// nationality is Nationality that also reports whether the prediction is a
+// fallback, see suffixRule.
+func (r *Rules) nationality(surname string) (NationalityPrediction, bool) {
+	rule, ok := match(surnameNationalityRules, surname)
+	if !ok {
+		return NationalityPrediction{Source: SourceRules}, false
+	}
+	return NationalityPrediction{
+		CountryID:   rule.value,
+		Probability: rule.probability,
+		Countries:   []CountryProbability{{CountryID: rule.value, Probability: rule.probability}},
+		Source:      SourceRules,
+	}, rule.fallback
+}
+
+// apply combines the rule predictions for q with result, the provider
+// answers. A rule also answers for a provider that failed, so the field need
+// not wait for a retry. A nil r leaves result unchanged.
+func (r *Rules) apply(q Query, result Result) Result {
+	if r == nil {
+		return result
+	}
+
+	gender := r.Gender(q.Surname, q.Patronymic)
+	if gender.Known() && (!result.Gender.Known() ||
+		result.Gender.Probability < r.MinGenderProbability && result.Gender.Probability < gender.Probability) {
+		result.Gender = gender
+	}
+
+	nationality, fallback := r.nationality(q.Surname)
+	if nationality.Known() && (!result.Nationality.Known() || !fallback &&
+		result.Nationality.Probability < r.MinNationalityProbability && result.Nationality.Probability < nationality.Probability) {
+		// The providers' distribution is kept after the rule's country.
+		for _, country := range result.Nationality.Countries {
+			if country.CountryID != nationality.CountryID {
+				nationality.Countries = append(nationality.Countries, country)
+			}
+		}
+		result.Nationality = nationality
+	}
+	return result
+}
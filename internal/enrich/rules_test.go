@@ -0,0 +1,193 @@
+package enrich
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRulesGender(t *testing.T) {
+	rules := &Rules{}
+
+	tests := []struct {
+		surname, patronymic string
+		want                string
+	}{
+		{surname: "Ivanov", patronymic: "Sergeevich", want: "male"},
+		{surname: "Ivanova", patronymic: "Sergeevna", want: "female"},
+		{surname: "Petrova", patronymic: "Ilyinichna", want: "female"},
+		{surname: "Кузнецова", patronymic: "Андреевна", want: "female"},
+		{surname: "Aliyev", patronymic: "Ramiz oglu", want: "male"},
+		{surname: "Kowalska", want: "female"},
+		{surname: "Smith", want: ""},
+		{surname: "Ov", patronymic: "Ich", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.surname+" "+tt.patronymic, func(t *testing.T) {
+			got := rules.Gender(tt.surname, tt.patronymic)
+			if got.Gender != tt.want || got.Source != SourceRules {
+				t.Errorf("Gender(%q, %q) = %+v, want %q", tt.surname, tt.patronymic, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesNationality(t *testing.T) {
+	rules := &Rules{}
+
+	tests := map[string]string{
+		"Shevchenko":   "UA",
+		"Beridze":      "GE",
+		"Popescu":      "RO",
+		"Wiśniewski":   "PL",
+		"Hovhannisyan": "AM",
+		"Ivanov":       "RU",
+		"Smith":        "",
+	}
+
+	for surname, want := range tests {
+		if got := rules.Nationality(surname); got.CountryID != want {
+			t.Errorf("Nationality(%q) = %+v, want %q", surname, got, want)
+		}
+	}
+}
+
+func TestEnricherRules(t *testing.T) {
+	weak := GenderPrediction{Gender: "female", Probability: 0.55, Count: 40, Source: ProviderGenderize}
+	strong := GenderPrediction{Gender: "female", Probability: 0.95, Count: 4000, Source: ProviderGenderize}
+	nationality := NationalityPrediction{
+		CountryID:   "BG",
+		Probability: 0.1,
+		Countries:   []CountryProbability{{CountryID: "BG", Probability: 0.1}, {CountryID: "RU", Probability: 0.08}},
+		Source:      ProviderNationalize,
+	}
+	rules := &Rules{MinGenderProbability: 0.8, MinNationalityProbability: 0.2}
+	query := Query{Name: "Sasha", Surname: "Shevchenko", Patronymic: "Petrovich"}
+
+	t.Run("OverridesLowConfidence", func(t *testing.T) {
+		cache := &memoryCache{entries: make(map[string]Result)}
+		enricher := &Enricher{
+			Providers: Providers{
+				Age:         stubAge{age: john.Age},
+				Gender:      stubGender{gender: weak},
+				Nationality: stubNationality{nationality: nationality},
+			},
+			Cache: cache,
+			Rules: rules,
+		}
+
+		result, err := enricher.Enrich(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if result.Gender.Gender != "male" || result.Gender.Source != SourceRules {
+			t.Errorf("Gender = %+v, want male from the rules", result.Gender)
+		}
+		wantCountries := []CountryProbability{
+			{CountryID: "UA", Probability: 0.7}, {CountryID: "BG", Probability: 0.1}, {CountryID: "RU", Probability: 0.08},
+		}
+		if result.Nationality.CountryID != "UA" || !reflect.DeepEqual(result.Nationality.Countries, wantCountries) {
+			t.Errorf("Nationality = %+v, want UA followed by the provider distribution", result.Nationality)
+		}
+		// The cache keeps the provider answers, another surname may apply.
+		if cached := cache.entries[query.cacheKey()]; !reflect.DeepEqual(cached.Gender, weak) {
+			t.Errorf("Cached gender = %+v, want %+v", cached.Gender, weak)
+		}
+	})
+
+	t.Run("KeepsConfidentAnswer", func(t *testing.T) {
+		enricher := &Enricher{
+			Providers: Providers{
+				Age:         stubAge{age: john.Age},
+				Gender:      stubGender{gender: strong},
+				Nationality: stubNationality{nationality: john.Nationality},
+			},
+			Rules: rules,
+		}
+
+		result, err := enricher.Enrich(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(result.Gender, strong) || !reflect.DeepEqual(result.Nationality, john.Nationality) {
+			t.Errorf("Result = %+v, want the provider answers", result)
+		}
+	})
+
+	t.Run("AnswersForFailedProvider", func(t *testing.T) {
+		enricher := &Enricher{
+			Providers: Providers{
+				Age:         stubAge{age: john.Age},
+				Gender:      stubGender{err: &ProviderError{Provider: ProviderGenderize, Err: ErrProviderUnavailable}},
+				Nationality: stubNationality{nationality: john.Nationality},
+			},
+			Rules: rules,
+		}
+
+		result, err := enricher.Enrich(context.Background(), query)
+		if err == nil {
+			t.Fatalf("Expected error, got nil")
+		}
+		if result.Gender.Gender != "male" || result.Gender.Source != SourceRules {
+			t.Errorf("Gender = %+v, want male from the rules", result.Gender)
+		}
+	})
+	t.Run("SharedSurnameEndings", func(t *testing.T) {
+		tests := []struct {
+			surname string
+			country string
+		}{
+			{surname: "Aliyev", country: "AZ"},
+			{surname: "Ivanov", country: "BG"},
+			{surname: "Nurlanov", country: "KZ"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.surname, func(t *testing.T) {
+				provided := NationalityPrediction{
+					CountryID:   tt.country,
+					Probability: 0.1,
+					Countries:   []CountryProbability{{CountryID: tt.country, Probability: 0.1}},
+					Source:      ProviderNationalize,
+				}
+				enricher := &Enricher{
+					Providers: Providers{
+						Age:         stubAge{age: john.Age},
+						Gender:      stubGender{gender: strong},
+						Nationality: stubNationality{nationality: provided},
+					},
+					Rules: rules,
+				}
+
+				result, err := enricher.Enrich(context.Background(), Query{Name: "Sasha", Surname: tt.surname})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result.Nationality, provided) {
+					t.Errorf("Nationality = %+v, want the provider answer %s", result.Nationality, tt.country)
+				}
+			})
+		}
+	})
+
+	t.Run("SharedSurnameEndingWithoutAnswer", func(t *testing.T) {
+		enricher := &Enricher{
+			Providers: Providers{
+				Age:         stubAge{age: john.Age},
+				Gender:      stubGender{gender: strong},
+				Nationality: stubNationality{nationality: NationalityPrediction{Source: ProviderNationalize}},
+			},
+			Rules: rules,
+		}
+
+		result, err := enricher.Enrich(context.Background(), Query{Name: "Sasha", Surname: "Ivanov"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Nationality.CountryID != "RU" || result.Nationality.Source != SourceRules {
+			t.Errorf("Nationality = %+v, want RU from the rules", result.Nationality)
+		}
+	})
+}
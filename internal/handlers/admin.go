@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"NameEnricher/internal/enrich"
+	"NameEnricher/internal/models"
+	"NameEnricher/pkg/logger"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// PurgeEnrichmentCacheHandler godoc
+// @Summary Purge the enrichment cache
+// @Description Delete cached age, gender and nationality results so that the next request for a name calls the providers again
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param expired_only query boolean false "Only delete entries whose TTL has passed"
+// @Success 200 {object} map[string]int64 "Number of purged entries"
+// @Failure 400 {object} map[string]string "Invalid expired_only value"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/enrichment-cache [delete]
+func PurgeEnrichmentCacheHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Log.Info("Processing purge enrichment cache request")
+
+		expiredOnly := false
+		if expiredOnlyStr := c.Query("expired_only"); expiredOnlyStr != "" {
+			val, err := strconv.ParseBool(expiredOnlyStr)
+			if err != nil {
+				logger.Log.Errorf("Invalid expired_only value: %s - %v", expiredOnlyStr, err)
+				c.JSON(http.StatusBadRequest, gin.H{"error Wrong expired_only format": err.Error()})
+				return
+			}
+			expiredOnly = val
+		}
+
+		purged, err := models.PurgeNameEnrichmentCache(db, c.Request.Context(), expiredOnly)
+		if err != nil {
+			logger.Log.Errorf("Failed to purge enrichment cache: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during purge": err.Error()})
+			return
+		}
+
+		logger.Log.Infof("Successfully purged %d enrichment cache entries", purged)
+		c.JSON(http.StatusOK, gin.H{"purged": purged})
+	}
+}
+
+// GetProvidersHandler godoc
+// @Summary Get provider health
+// @Description Get the circuit breaker state of every enrichment provider. An open circuit fails requests without calling the provider until retry_at.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {array} enrich.BreakerStatus "Circuit breaker state per provider"
+// @Router /admin/providers [get]
+func GetProvidersHandler(enricher *enrich.Enricher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Log.Info("Processing get providers request")
+
+		breakers := enricher.Providers.Breakers()
+		statuses := make([]enrich.BreakerStatus, 0, len(breakers))
+		for _, breaker := range breakers {
+			status := breaker.Status()
+			if status.State != enrich.CircuitClosed {
+				logger.Log.Warnf("Provider %s circuit is %s: %s", status.Provider, status.State, status.LastError)
+			}
+			statuses = append(statuses, status)
+		}
+
+		c.JSON(http.StatusOK, statuses)
+	}
+}
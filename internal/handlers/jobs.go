@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"NameEnricher/internal/enrich"
+	"NameEnricher/internal/models"
+	"NameEnricher/internal/worker"
+	"NameEnricher/pkg/logger"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// createPersonPayload is the input of a create_person job.
+type createPersonPayload struct {
+	Person  models.PersonCreateRequest `json:"person"`
+	Partial bool                       `json:"partial"`
+}
+
+// GetJobHandler godoc
+// @Summary Get a job
+// @Description Get the status of a background job. Once a create job is done, the created person is included.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path integer true "Job ID"
+// @Success 200 {object} models.JobResponse "Successfully retrieved job"
+// @Failure 400 {object} map[string]string "Invalid request - Bad ID format"
+// @Failure 404 {object} map[string]string "Job not found - The specified ID does not exist"
+// @Failure 500 {object} map[string]string "Internal server error - Database connection issues or query problems"
+// @Router /jobs/{id} [get]
+func GetJobHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		logger.Log.Infof("Processing get job request for ID: %s", idStr)
+
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || id <= 0 {
+			logger.Log.Errorf("Invalid ID format: %s - %v", idStr, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Wrong ID format: " + idStr})
+			return
+		}
+
+		job, found, err := models.GetJob(db, c.Request.Context(), id)
+		if err != nil {
+			logger.Log.Errorf("Failed to get job ID %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during getting": err.Error()})
+			return
+		}
+		if !found {
+			logger.Log.Infof("Job with ID %d not found", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("job with id=%d not found", id)})
+			return
+		}
+
+		response := models.JobResponse{Job: job}
+		if job.Status == models.JobDone && job.PersonID != 0 {
+			persons, err := models.GetPersons(c.Request.Context(), db, models.PersonFilter{ID: job.PersonID})
+			if err != nil {
+				logger.Log.Errorf("Failed to get person ID %d for job %d: %v", job.PersonID, id, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error during getting": err.Error()})
+				return
+			}
+			if len(persons) > 0 {
+				response.Person = &persons[0]
+			}
+		}
+
+		logger.Log.Infof("Successfully retrieved job with ID %d", id)
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// CreatePersonsJob returns the batch job handler behind POST /persons:async.
+// It runs the same creation as CreatePersonHandler, enriching every claimed
+// job's name in shared provider requests, and records each person on its job
+// in the transaction that creates it.
+func CreatePersonsJob(db *sql.DB, enricher *enrich.Enricher) func(ctx context.Context, jobs []models.Job) []error {
+	return func(ctx context.Context, jobs []models.Job) []error {
+		errs := make([]error, len(jobs))
+
+		// indexes maps every request below back to its position in jobs.
+		var (
+			indexes  []int
+			requests []models.PersonCreateRequest
+			partial  []bool
+		)
+		for i, job := range jobs {
+			if job.PersonID != 0 {
+				// An earlier attempt already created the person.
+				continue
+			}
+
+			var payload createPersonPayload
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				errs[i] = fmt.Errorf("decoding payload: %w", err)
+				continue
+			}
+			indexes = append(indexes, i)
+			requests = append(requests, payload.Person)
+			partial = append(partial, payload.Partial)
+		}
+		if len(indexes) == 0 {
+			return errs
+		}
+
+		queries := make([]enrich.Query, len(requests))
+		for i, request := range requests {
+			queries[i] = personQuery(request)
+		}
+		results, enrichErrs := enricher.EnrichBatch(ctx, queries)
+
+		for i, index := range indexes {
+			createdPerson, err := storePerson(ctx, db, requests[i], results[i], enrichErrs[i], partial[i], jobs[index].ID)
+			if errors.Is(err, models.ErrJobPersonSet) {
+				logger.Log.Infof("Job %d already created its person", jobs[index].ID)
+				continue
+			}
+			if errors.Is(err, enrich.ErrInvalidName) {
+				// A rejected name is rejected again on every retry.
+				errs[index] = worker.Permanent(err)
+				continue
+			}
+			if err != nil {
+				errs[index] = err
+				continue
+			}
+
+			logger.Log.Infof("Job %d created person with ID %d", jobs[index].ID, createdPerson.ID)
+		}
+		return errs
+	}
+}
+
+// ReEnrichPersonsJob returns the batch job handler that queries the providers
+// again for persons and stores the outcome with models.UpdatePerson. The names
+// of all claimed jobs are enriched in shared provider requests.
+//
+// Fields that are not known yet take whatever the providers answer. Known
+// fields are only refreshed by a new known prediction, so a provider that no
+// longer recognises a name never erases data, and fields set by a client are
+// never refreshed. A provider failure keeps the field pending and fails the
+// attempt so it is retried; on the last attempt the field is marked failed
+// instead.
+func ReEnrichPersonsJob(db *sql.DB, enricher *enrich.Enricher) func(ctx context.Context, jobs []models.Job) []error {
+	return func(ctx context.Context, jobs []models.Job) []error {
+		errs := make([]error, len(jobs))
+
+		// indexes maps every person below back to its position in jobs.
+		var (
+			indexes []int
+			persons []models.Person
+		)
+		for i, job := range jobs {
+			found, err := models.GetPersons(ctx, db, models.PersonFilter{ID: job.PersonID})
+			if err != nil {
+				errs[i] = fmt.Errorf("loading person: %w", err)
+				continue
+			}
+			if len(found) == 0 {
+				logger.Log.Infof("Person with ID %d no longer exists, skipping job %d", job.PersonID, job.ID)
+				continue
+			}
+			indexes = append(indexes, i)
+			persons = append(persons, found[0])
+		}
+		if len(indexes) == 0 {
+			return errs
+		}
+
+		queries := make([]enrich.Query, len(persons))
+		for i, person := range persons {
+			queries[i] = enrich.Query{
+				Name:       person.Name,
+				CountryID:  person.CountryHint,
+				Surname:    person.Surname,
+				Patronymic: person.Patronymic,
+			}
+		}
+		results, enrichErrs := enricher.EnrichBatch(ctx, queries)
+
+		for i, index := range indexes {
+			errs[index] = reEnrichPerson(ctx, db, jobs[index], persons[i], results[i], enrichErrs[i])
+		}
+		return errs
+	}
+}
+
+// reEnrichPerson stores the outcome of re-enriching a single person. It
+// returns enrichErr when a provider failed, so the job is retried.
+func reEnrichPerson(ctx context.Context, db *sql.DB, job models.Job, person models.Person, result enrich.Result, enrichErr error) error {
+	failed := make(map[string]bool)
+	var aggregated *enrich.Error
+	if errors.As(enrichErr, &aggregated) {
+		for _, field := range retryableFields(aggregated, result) {
+			failed[field] = true
+		}
+	} else if enrichErr != nil {
+		return enrichErr
+	}
+
+	// Values a client set are not the providers' to replace, so their
+	// predictions and failures are dropped.
+	if person.EnrichmentSource.Age == models.SourceClient {
+		result.Age = enrich.AgePrediction{}
+		delete(failed, enrich.FieldAge)
+	}
+	if person.EnrichmentSource.Gender == models.SourceClient {
+		result.Gender = enrich.GenderPrediction{}
+		delete(failed, enrich.FieldGender)
+	}
+	if person.EnrichmentSource.Nationality == models.SourceClient {
+		result.Nationality = enrich.NationalityPrediction{}
+		delete(failed, enrich.FieldNationality)
+	}
+	if len(failed) == 0 {
+		// Only rejected names, rule answers or client values, which a retry
+		// cannot change.
+		enrichErr = nil
+	}
+
+	patch, err := reEnrichmentPatch(ctx, db, person, result, failed, job.LastAttempt())
+	if err != nil {
+		return err
+	}
+	if _, err := models.UpdatePerson(ctx, person.ID, patch, db); err != nil {
+		return fmt.Errorf("updating person: %w", err)
+	}
+
+	if !failed[enrich.FieldGender] && result.Gender.Known() {
+		err := models.ReplacePersonProviderPredictions(db, ctx, person.ID, enrich.FieldGender, genderBreakdown(result.Gender))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !failed[enrich.FieldNationality] && result.Nationality.Known() {
+		candidates := make([]models.NationalityCandidate, 0, len(result.Nationality.Countries))
+		for _, country := range result.Nationality.Countries {
+			candidates = append(candidates, models.NationalityCandidate{CountryID: country.CountryID, Probability: country.Probability})
+		}
+		if err := models.ReplacePersonNationalityCandidates(db, ctx, person.ID, candidates); err != nil {
+			return err
+		}
+		err := models.ReplacePersonProviderPredictions(db, ctx, person.ID, enrich.FieldNationality, nationalityBreakdown(result.Nationality))
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Infof("Re-enriched person with ID %d", person.ID)
+	return enrichErr
+}
+
+// reEnrichmentPatch builds the update for a single re-enrichment attempt.
+func reEnrichmentPatch(ctx context.Context, db *sql.DB, person models.Person, result enrich.Result, failed map[string]bool, lastAttempt bool) (models.PersonPatch, error) {
+	var patch models.PersonPatch
+
+	// status picks the new status of a field that did not get a known prediction.
+	status := func(field string, current models.EnrichmentStatus) *models.EnrichmentStatus {
+		next := models.EnrichmentUnknown
+		if failed[field] {
+			next = models.EnrichmentPending
+			if lastAttempt {
+				next = models.EnrichmentFailed
+			}
+		}
+		if current == models.EnrichmentOK || current == next {
+			return nil
+		}
+		return &next
+	}
+
+	known := models.EnrichmentOK
+	if !failed[enrich.FieldAge] && result.Age.Known() {
+		patch.Age = result.Age.Age
+		patch.AgeCount = &result.Age.Count
+		patch.AgeStatus = &known
+		patch.AgeSource = &result.Age.Source
+	} else {
+		patch.AgeStatus = status(enrich.FieldAge, person.EnrichmentStatus.Age)
+	}
+
+	if !failed[enrich.FieldGender] && result.Gender.Known() {
+		gender, err := models.GetOrCreateGender(db, ctx, result.Gender.Gender)
+		if err != nil {
+			return models.PersonPatch{}, fmt.Errorf("resolving gender '%s': %w", result.Gender.Gender, err)
+		}
+		patch.GenderID = &gender.ID
+		patch.GenderProbability = &result.Gender.Probability
+		patch.GenderStatus = &known
+		patch.GenderSource = &result.Gender.Source
+	} else {
+		patch.GenderStatus = status(enrich.FieldGender, person.EnrichmentStatus.Gender)
+	}
+
+	if !failed[enrich.FieldNationality] && result.Nationality.Known() {
+		nationality, err := models.GetOrCreateNationality(db, ctx, result.Nationality.CountryID)
+		if err != nil {
+			return models.PersonPatch{}, fmt.Errorf("resolving nationality '%s': %w", result.Nationality.CountryID, err)
+		}
+		patch.NationalityID = &nationality.ID
+		patch.NationalityProbability = &result.Nationality.Probability
+		patch.NationalityStatus = &known
+		patch.NationalitySource = &result.Nationality.Source
+	} else {
+		patch.NationalityStatus = status(enrich.FieldNationality, person.EnrichmentStatus.Nationality)
+	}
+
+	if len(failed) == 0 || lastAttempt {
+		now := time.Now()
+		patch.EnrichedAt = &now
+	}
+
+	return patch, nil
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"NameEnricher/internal/enrich"
+	"NameEnricher/internal/models"
+	"NameEnricher/internal/worker"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func jobRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "kind", "person_id", "status", "attempts", "max_attempts", "run_at",
+		"last_error", "created_at", "updated_at", "payload"})
+}
+
+func addJobRow(rows *sqlmock.Rows, job models.Job) *sqlmock.Rows {
+	var personID, lastError interface{}
+	if job.PersonID != 0 {
+		personID = job.PersonID
+	}
+	if job.LastError != "" {
+		lastError = job.LastError
+	}
+	now := time.Now()
+	return rows.AddRow(job.ID, job.Kind, personID, job.Status, job.Attempts, job.MaxAttempts, now,
+		lastError, now, now, []byte(job.Payload))
+}
+
+func TestReEnrichPerson(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("KeepsClientValues", func(t *testing.T) {
+		person := models.Person{
+			ID:          1,
+			Name:        "John",
+			Surname:     "Doe",
+			Age:         intPtr(30),
+			Gender:      &models.Gender{ID: 1, Name: "male"},
+			Nationality: &models.Nationality{ID: 2, Name: "US"},
+			EnrichmentStatus: models.PersonEnrichment{
+				Age:         models.EnrichmentOK,
+				Gender:      models.EnrichmentOK,
+				Nationality: models.EnrichmentOK,
+			},
+			EnrichmentSource: models.PersonEnrichmentSource{
+				Age:         models.SourceClient,
+				Gender:      models.SourceClient,
+				Nationality: models.SourceClient,
+			},
+		}
+		result := enrich.Result{
+			Age:         enrich.AgePrediction{Age: intPtr(55), Count: 100, Source: "agify"},
+			Nationality: enrich.NationalityPrediction{CountryID: "IE", Probability: 0.4, Source: "nationalize"},
+		}
+		enrichErr := &enrich.Error{Failures: map[string]error{enrich.FieldGender: enrich.ErrProviderUnavailable}}
+
+		selRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(person.ID, person.Name, person.Surname, person.Patronymic, *person.Age, person.Gender.ID, person.Nationality.ID)
+		mock.ExpectQuery("^SELECT id, name, surname, patronymic, age, gender_id, nationality_id FROM persons WHERE id = \\$1$").
+			WithArgs(person.ID).
+			WillReturnRows(selRows)
+		updateRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(person.ID, person.Name, person.Surname, person.Patronymic, *person.Age, person.Gender.ID, person.Nationality.ID)
+		mock.ExpectBegin()
+		mock.ExpectQuery("^UPDATE persons SET enriched_at = \\$1 WHERE id = \\$2 RETURNING").
+			WithArgs(sqlmock.AnyArg(), person.ID).
+			WillReturnRows(updateRows)
+		mock.ExpectCommit()
+		expectPersonReload(mock, person)
+
+		job := models.Job{ID: 7, Kind: models.JobKindReEnrich, PersonID: person.ID, Attempts: 1, MaxAttempts: 5}
+		if err := reEnrichPerson(ctx, db, job, person, result, enrichErr); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestCreatePersonsJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	// expectCreate mocks storing john for a job; recorded reports whether the
+	// job still had no person.
+	expectCreate := func(jobID int64, personID uint, name string, recorded bool) {
+		mock.ExpectQuery("^INSERT INTO genders").
+			WithArgs("male").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "male"))
+		mock.ExpectQuery("^INSERT INTO nationalities").
+			WithArgs("US").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "US"))
+		mock.ExpectBegin()
+		mock.ExpectQuery("^INSERT INTO persons").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+				AddRow(personID, name, "", "", 47, 1, 2))
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates").
+			WithArgs(personID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("^INSERT INTO person_nationality_candidates").
+			WithArgs(personID, "US", 0.4, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		if !recorded {
+			mock.ExpectExec("^UPDATE jobs SET person_id = \\$1").
+				WithArgs(personID, jobID).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+			return
+		}
+		mock.ExpectExec("^UPDATE jobs SET person_id = \\$1").
+			WithArgs(personID, jobID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectPersonReload(mock, models.Person{
+			ID:          personID,
+			Name:        name,
+			Age:         intPtr(47),
+			Gender:      &models.Gender{ID: 1, Name: "male"},
+			Nationality: &models.Nationality{ID: 2, Name: "US"},
+			EnrichmentStatus: models.PersonEnrichment{
+				Age:         models.EnrichmentOK,
+				Gender:      models.EnrichmentOK,
+				Nationality: models.EnrichmentOK,
+			},
+		})
+	}
+	payload := func(name string) json.RawMessage {
+		data, err := json.Marshal(createPersonPayload{Person: models.PersonCreateRequest{Name: name}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return data
+	}
+
+	jobs := []models.Job{
+		{ID: 1, Kind: models.JobKindCreatePerson, PersonID: 9, Payload: payload("Anna")},
+		{ID: 2, Kind: models.JobKindCreatePerson, Payload: json.RawMessage("{")},
+		{ID: 3, Kind: models.JobKindCreatePerson, Payload: payload("John")},
+		{ID: 4, Kind: models.JobKindCreatePerson, Payload: payload("Jack")},
+	}
+	expectCreate(3, 10, "John", true)
+	expectCreate(4, 11, "Jack", false)
+
+	errs := CreatePersonsJob(db, stubEnricher(john, nil))(context.Background(), jobs)
+
+	if len(errs) != len(jobs) {
+		t.Fatalf("Expected %d errors, got %d", len(jobs), len(errs))
+	}
+	for i, err := range errs {
+		if wantErr := jobs[i].ID == 2; (err != nil) != wantErr {
+			t.Errorf("Job %d: unexpected error %v", jobs[i].ID, err)
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(errs[1], &syntaxErr) {
+		t.Errorf("Expected a payload decoding error, got %v", errs[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreatePersonsJobInvalidName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	invalidErr := &enrich.ProviderError{Provider: "agify", StatusCode: http.StatusUnprocessableEntity, Err: enrich.ErrInvalidName}
+	cfg := worker.DefaultConfig()
+	w := worker.New(db, cfg)
+	w.HandleBatch(models.JobKindCreatePerson, CreatePersonsJob(db, stubEnricher(john, map[string]error{enrich.FieldAge: invalidErr})))
+
+	payload, err := json.Marshal(createPersonPayload{Person: models.PersonCreateRequest{Name: "X Æ A-12"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mock.ExpectQuery("^UPDATE jobs SET status = 'running'").
+		WithArgs(sqlmock.AnyArg(), cfg.BatchSize).
+		WillReturnRows(addJobRow(jobRows(), models.Job{ID: 4, Kind: models.JobKindCreatePerson, Status: models.JobRunning, Attempts: 1, MaxAttempts: 5, Payload: payload}))
+	mock.ExpectExec("^UPDATE jobs SET status").
+		WithArgs(models.JobFailed, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	processed, err := w.ProcessNext(context.Background())
+	if err != nil || processed != 1 {
+		t.Errorf("ProcessNext() = %v, %v; want 1, nil", processed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreatePersonAsyncHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("Queued", func(t *testing.T) {
+		payload, err := json.Marshal(createPersonPayload{
+			Person:  models.PersonCreateRequest{Name: "John", Surname: "Doe"},
+			Partial: true,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rows := addJobRow(jobRows(), models.Job{ID: 5, Kind: models.JobKindCreatePerson, Status: models.JobQueued, MaxAttempts: 5, Payload: payload})
+		mock.ExpectQuery("^INSERT INTO jobs \\(kind, payload\\)").
+			WithArgs(models.JobKindCreatePerson, string(payload)).
+			WillReturnRows(rows)
+
+		w := serve(CustomMethodsHandler("method", map[string]gin.HandlerFunc{":async": CreatePersonAsyncHandler(db, false)}),
+			http.MethodPost, "/persons:method", "/persons:async?partial=true",
+			`{"name": " John ", "surname": "Doe"}`)
+
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body)
+		}
+		if location := w.Header().Get("Location"); location != "/jobs/5" {
+			t.Errorf("Expected Location /jobs/5, got %q", location)
+		}
+		var job models.Job
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if job.ID != 5 || job.Status != models.JobQueued {
+			t.Errorf("Expected queued job 5, got %+v", job)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		w := serve(CustomMethodsHandler("method", map[string]gin.HandlerFunc{":async": CreatePersonAsyncHandler(db, false)}),
+			http.MethodPost, "/persons:method", "/persons:async",
+			`{"surname": "Doe"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestGetJobHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("DoneWithPerson", func(t *testing.T) {
+		person := models.Person{
+			ID:      1,
+			Name:    "John",
+			Surname: "Doe",
+			Age:     intPtr(47),
+			EnrichmentStatus: models.PersonEnrichment{
+				Age:         models.EnrichmentOK,
+				Gender:      models.EnrichmentUnknown,
+				Nationality: models.EnrichmentUnknown,
+			},
+			EnrichmentSource: models.PersonEnrichmentSource{Age: "agify"},
+		}
+		rows := addJobRow(jobRows(), models.Job{ID: 5, Kind: models.JobKindCreatePerson, PersonID: person.ID, Status: models.JobDone, Attempts: 1, MaxAttempts: 5})
+		mock.ExpectQuery("^SELECT id, kind, person_id, (.+) FROM jobs WHERE id = \\$1$").
+			WithArgs(int64(5)).
+			WillReturnRows(rows)
+		expectPersonReload(mock, person)
+
+		w := serve(GetJobHandler(db), http.MethodGet, "/jobs/:id", "/jobs/5", "")
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		var response models.JobResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Status != models.JobDone || response.Person == nil || response.Person.ID != person.ID {
+			t.Errorf("Expected done job with person %d, got %+v", person.ID, response)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("Queued", func(t *testing.T) {
+		rows := addJobRow(jobRows(), models.Job{ID: 6, Kind: models.JobKindCreatePerson, Status: models.JobQueued, MaxAttempts: 5})
+		mock.ExpectQuery("^SELECT id, kind, person_id, (.+) FROM jobs WHERE id = \\$1$").
+			WithArgs(int64(6)).
+			WillReturnRows(rows)
+
+		w := serve(GetJobHandler(db), http.MethodGet, "/jobs/:id", "/jobs/6", "")
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		var response models.JobResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Status != models.JobQueued || response.Person != nil {
+			t.Errorf("Expected queued job without person, got %+v", response)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, kind, person_id, (.+) FROM jobs WHERE id = \\$1$").
+			WithArgs(int64(7)).
+			WillReturnRows(jobRows())
+
+		w := serve(GetJobHandler(db), http.MethodGet, "/jobs/:id", "/jobs/7", "")
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		w := serve(GetJobHandler(db), http.MethodGet, "/jobs/:id", "/jobs/abc", "")
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"NameEnricher/internal/models"
+	"NameEnricher/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// GetNameAliasesHandler godoc
+// @Summary List name aliases
+// @Description Get a list of name aliases with optional filtering
+// @Tags name-aliases
+// @Accept json
+// @Produce json
+// @Param id query integer false "Alias ID"
+// @Param alias query string false "Part of the alias, ignoring case"
+// @Param canonical query string false "Canonical name, ignoring case"
+// @Param page query integer false "Page number for pagination"
+// @Param limit query integer false "Number of items per page"
+// @Success 200 {array} models.NameAlias "Successfully retrieved alias list"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /name-aliases [get]
+func GetNameAliasesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Log.Info("Processing get name aliases request")
+		filter := models.NameAliasFilter{}
+
+		if idStr := c.Query("id"); idStr != "" {
+			if idVal, err := strconv.Atoi(idStr); err == nil && idVal > 0 {
+				filter.ID = idVal
+				logger.Log.Debugf("Filtering by ID: %d", idVal)
+			}
+		}
+
+		if alias := c.Query("alias"); alias != "" {
+			filter.Alias = alias
+			logger.Log.Debugf("Filtering by alias: %s", alias)
+		}
+
+		if canonical := c.Query("canonical"); canonical != "" {
+			filter.Canonical = canonical
+			logger.Log.Debugf("Filtering by canonical name: %s", canonical)
+		}
+
+		if pageStr := c.Query("page"); pageStr != "" {
+			if pageVal, err := strconv.Atoi(pageStr); err == nil && pageVal > 0 {
+				filter.Page = pageVal
+				logger.Log.Debugf("Filtering by page: %d", pageVal)
+			}
+		}
+
+		if limitStr := c.Query("limit"); limitStr != "" {
+			if limitVal, err := strconv.Atoi(limitStr); err == nil && limitVal > 0 {
+				filter.Limit = limitVal
+				logger.Log.Debugf("Filtering by limit: %d", limitVal)
+			}
+		}
+
+		logger.Log.Debugf("Executing GetNameAliases with filter: %+v", filter)
+		aliases, err := models.GetNameAliases(db, c.Request.Context(), filter)
+		if err != nil {
+			logger.Log.Errorf("Failed to get name aliases: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during getting": err.Error()})
+			return
+		}
+
+		logger.Log.Infof("Successfully retrieved %d name aliases", len(aliases))
+		c.JSON(http.StatusOK, aliases)
+	}
+}
+
+// CreateNameAliasHandler godoc
+// @Summary Create a new name alias
+// @Description Map a variant of a given name, such as the diminutive "Sasha", to its canonical form "Aleksandr"
+// @Tags name-aliases
+// @Accept json
+// @Produce json
+// @Param alias body models.NameAliasCreateRequest true "Alias and its canonical name"
+// @Success 201 {object} models.NameAlias "Successfully created alias"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Internal server error - e.g. the alias already exists"
+// @Router /name-aliases [post]
+func CreateNameAliasHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Log.Info("Processing create name alias request")
+
+		var request struct {
+			Alias     string `json:"alias" binding:"required"`
+			Canonical string `json:"canonical" binding:"required"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Log.Errorf("Failed to bind JSON: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+			return
+		}
+		logger.Log.Debugf("Creating name alias %s for %s", request.Alias, request.Canonical)
+
+		alias, err := models.CreateNameAlias(db, c.Request.Context(), models.NameAliasCreateRequest{
+			Alias:     request.Alias,
+			Canonical: request.Canonical,
+		})
+		if errors.Is(err, models.ErrInvalidNameAlias) {
+			logger.Log.Errorf("Invalid name alias '%s': %v", request.Alias, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+			return
+		}
+		if err != nil {
+			logger.Log.Errorf("Failed to create name alias '%s': %v", request.Alias, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during creation": err.Error()})
+			return
+		}
+
+		logger.Log.Infof("Successfully created name alias '%s' with ID %d", alias.Alias, alias.ID)
+		c.JSON(http.StatusCreated, alias)
+	}
+}
+
+// UpdateNameAliasHandler godoc
+// @Summary Update a name alias
+// @Description Update an existing name alias by ID
+// @Tags name-aliases
+// @Accept json
+// @Produce json
+// @Param id path integer true "Alias ID"
+// @Param alias body models.PatchNameAlias true "Alias update data"
+// @Success 200 {object} models.NameAlias "Successfully updated alias"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /name-aliases/{id} [put]
+func UpdateNameAliasHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		logger.Log.Infof("Processing update name alias request for ID: %s", idStr)
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			logger.Log.Errorf("Invalid ID format: %s - %v", idStr, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error Wrong ID format": err.Error()})
+			return
+		}
+
+		var patch models.PatchNameAlias
+		if err := c.ShouldBindJSON(&patch); err != nil {
+			logger.Log.Errorf("Failed to bind JSON for name alias update: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+			return
+		}
+
+		logger.Log.Debugf("Updating name alias ID %d with patch: %+v", id, patch)
+
+		alias, err := models.UpdateNameAlias(db, c.Request.Context(), id, patch)
+		if errors.Is(err, models.ErrInvalidNameAlias) {
+			logger.Log.Errorf("Invalid update of name alias ID %d: %v", id, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+			return
+		}
+		if err != nil {
+			logger.Log.Errorf("Failed to update name alias ID %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during update": err.Error()})
+			return
+		}
+
+		logger.Log.Infof("Successfully updated name alias with ID %d", id)
+		c.JSON(http.StatusOK, alias)
+	}
+}
+
+// DeleteNameAliasHandler godoc
+// @Summary Delete a name alias
+// @Description Delete a name alias by its ID
+// @Tags name-aliases
+// @Accept json
+// @Produce json
+// @Param id path integer true "Alias ID"
+// @Success 200 {object} models.NameAlias "Successfully deleted alias"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /name-aliases/{id} [delete]
+func DeleteNameAliasHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		logger.Log.Infof("Processing delete name alias request for ID: %s", idStr)
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			logger.Log.Errorf("Invalid ID format: %s - %v", idStr, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error Wrong ID format": err.Error()})
+			return
+		}
+
+		logger.Log.Debugf("Deleting name alias with ID: %d", id)
+		alias, err := models.DeleteNameAlias(db, c.Request.Context(), id)
+		if err != nil {
+			logger.Log.Errorf("Failed to delete name alias ID %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during delete": err.Error()})
+			return
+		}
+
+		logger.Log.Infof("Successfully deleted name alias with ID %d", id)
+		c.JSON(http.StatusOK, alias)
+	}
+}
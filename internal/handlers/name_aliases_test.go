@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"NameEnricher/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func aliasRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "alias", "canonical"})
+}
+
+func TestNameAliasHandlers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("List", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE 1=1 AND canonical_key = \\$1 ORDER BY id$").
+			WithArgs("aleksandr").
+			WillReturnRows(aliasRows().AddRow(1, "Sasha", "Aleksandr").AddRow(2, "Alex", "Aleksandr"))
+
+		w := serve(GetNameAliasesHandler(db), http.MethodGet, "/name-aliases", "/name-aliases?canonical=ALEKSANDR", "")
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		var aliases []models.NameAlias
+		if err := json.Unmarshal(w.Body.Bytes(), &aliases); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(aliases) != 2 {
+			t.Errorf("Expected 2 aliases, got %v", aliases)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		mock.ExpectQuery("^INSERT INTO name_aliases").
+			WithArgs("Sasha", "Aleksandr", "sasha", "aleksandr").
+			WillReturnRows(aliasRows().AddRow(1, "Sasha", "Aleksandr"))
+
+		w := serve(CreateNameAliasHandler(db), http.MethodPost, "/name-aliases", "/name-aliases",
+			`{"alias": "Sasha", "canonical": "Aleksandr"}`)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+		}
+		var alias models.NameAlias
+		if err := json.Unmarshal(w.Body.Bytes(), &alias); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := (models.NameAlias{ID: 1, Alias: "Sasha", Canonical: "Aleksandr"}); alias != want {
+			t.Errorf("Expected alias %+v, got %+v", want, alias)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("CreateInvalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"alias": "Sasha"}`,
+			`{"alias": " ", "canonical": "Aleksandr"}`,
+			`{"alias": "SASHA", "canonical": "sasha"}`,
+		} {
+			w := serve(CreateNameAliasHandler(db), http.MethodPost, "/name-aliases", "/name-aliases", body)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d: %s", body, http.StatusBadRequest, w.Code, w.Body)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE id = \\$1$").
+			WithArgs(1).
+			WillReturnRows(aliasRows().AddRow(1, "Sasha", "Aleksandr"))
+		mock.ExpectQuery("^UPDATE name_aliases SET alias = \\$1, alias_key = \\$2 WHERE id = \\$3").
+			WithArgs("Shura", "shura", 1).
+			WillReturnRows(aliasRows().AddRow(1, "Shura", "Aleksandr"))
+
+		w := serve(UpdateNameAliasHandler(db), http.MethodPut, "/name-aliases/:id", "/name-aliases/1",
+			`{"alias": "Shura"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("UpdateToCanonical", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE id = \\$1$").
+			WithArgs(1).
+			WillReturnRows(aliasRows().AddRow(1, "Sasha", "Aleksandr"))
+
+		w := serve(UpdateNameAliasHandler(db), http.MethodPut, "/name-aliases/:id", "/name-aliases/1",
+			`{"canonical": "sasha"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mock.ExpectQuery("^DELETE FROM name_aliases WHERE id = \\$1").
+			WithArgs(1).
+			WillReturnRows(aliasRows().AddRow(1, "Shura", "Aleksandr"))
+
+		w := serve(DeleteNameAliasHandler(db), http.MethodDelete, "/name-aliases/:id", "/name-aliases/1", "")
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("DeleteInvalidID", func(t *testing.T) {
+		w := serve(DeleteNameAliasHandler(db), http.MethodDelete, "/name-aliases/:id", "/name-aliases/abc", "")
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+		}
+	})
+}
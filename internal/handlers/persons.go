@@ -2,12 +2,18 @@
 package handlers
 
 import (
+	"NameEnricher/internal/enrich"
 	"NameEnricher/internal/models"
 	"NameEnricher/pkg/logger"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 )
 
 // GetPersonsHandler godoc
@@ -17,12 +23,16 @@ import (
 // @Accept json
 // @Produce json
 // @Param id query integer false "Person ID"
-// @Param name query string false "Person name"
-// @Param surname query string false "Person surname"
+// @Param name query string false "Part of the person name, ignoring case and Unicode composition"
+// @Param surname query string false "Part of the person surname, ignoring case and Unicode composition"
+// @Param transliterate query boolean false "Match name and surname in any transliteration, e.g. Dmitriy finds Дмитрий"
+// @Param expand_aliases query boolean false "Also match the aliases and canonical form of the name, e.g. Sasha finds Aleksandr"
 // @Param age_from query integer false "Minimum age"
 // @Param age_to query integer false "Maximum age"
 // @Param gender_id query integer false "Gender ID"
 // @Param nationality_id query integer false "Nationality ID"
+// @Param min_gender_probability query number false "Minimum gender probability (0-1)"
+// @Param min_nationality_probability query number false "Minimum nationality probability (0-1)"
 // @Param Page query integer false "Page"
 // @Param Limit query integer false "LIMIT"
 // @Success 200 {array} models.Person "Successfully retrieved person list"
@@ -50,6 +60,20 @@ func GetPersonsHandler(db *sql.DB) gin.HandlerFunc {
 			logger.Log.Debugf("Filtering by surname: %s", surname)
 		}
 
+		if transliterateStr := c.Query("transliterate"); transliterateStr != "" {
+			if transliterateVal, err := strconv.ParseBool(transliterateStr); err == nil {
+				filter.MatchTransliterations = transliterateVal
+				logger.Log.Debugf("Matching transliterations: %t", transliterateVal)
+			}
+		}
+
+		if expandAliasesStr := c.Query("expand_aliases"); expandAliasesStr != "" {
+			if expandAliasesVal, err := strconv.ParseBool(expandAliasesStr); err == nil {
+				filter.ExpandAliases = expandAliasesVal
+				logger.Log.Debugf("Expanding name aliases: %t", expandAliasesVal)
+			}
+		}
+
 		if ageFromStr := c.Query("age_from"); ageFromStr != "" {
 			if ageFromVal, err := strconv.Atoi(ageFromStr); err == nil && ageFromVal > 0 {
 				filter.AgeFrom = ageFromVal
@@ -78,6 +102,20 @@ func GetPersonsHandler(db *sql.DB) gin.HandlerFunc {
 			}
 		}
 
+		if minGenderProbabilityStr := c.Query("min_gender_probability"); minGenderProbabilityStr != "" {
+			if minGenderProbabilityVal, err := strconv.ParseFloat(minGenderProbabilityStr, 64); err == nil && minGenderProbabilityVal > 0 {
+				filter.MinGenderProbability = minGenderProbabilityVal
+				logger.Log.Debugf("Filtering by min gender probability: %.2f", minGenderProbabilityVal)
+			}
+		}
+
+		if minNationalityProbabilityStr := c.Query("min_nationality_probability"); minNationalityProbabilityStr != "" {
+			if minNationalityProbabilityVal, err := strconv.ParseFloat(minNationalityProbabilityStr, 64); err == nil && minNationalityProbabilityVal > 0 {
+				filter.MinNationalityProbability = minNationalityProbabilityVal
+				logger.Log.Debugf("Filtering by min nationality probability: %.2f", minNationalityProbabilityVal)
+			}
+		}
+
 		if pageStr := c.Query("Page"); pageStr != "" {
 			if pageVal, err := strconv.Atoi(pageStr); err == nil && pageVal > 0 {
 				filter.Page = pageVal
@@ -105,115 +143,278 @@ func GetPersonsHandler(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
+// GetPersonHandler godoc
+// @Summary Get a person
+// @Description Get a single person by ID, optionally with related data
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param id path integer true "Person ID"
+// @Param include query string false "Comma-separated related data to include" Enums(nationality_candidates, provider_predictions)
+// @Success 200 {object} models.Person "Successfully retrieved person"
+// @Failure 400 {object} map[string]string "Invalid request - Bad ID format or unknown include value"
+// @Failure 404 {object} map[string]string "Person not found - The specified ID does not exist"
+// @Failure 500 {object} map[string]string "Internal server error - Database connection issues or query problems"
+// @Router /persons/{id} [get]
+func GetPersonHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		logger.Log.Infof("Processing get person request for ID: %s", idStr)
+
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil || id == 0 {
+			logger.Log.Errorf("Invalid ID format: %s - %v", idStr, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Wrong ID format: " + idStr})
+			return
+		}
+
+		includeCandidates, includePredictions := false, false
+		if include := c.Query("include"); include != "" {
+			for _, part := range strings.Split(include, ",") {
+				switch strings.TrimSpace(part) {
+				case "nationality_candidates":
+					includeCandidates = true
+				case "provider_predictions":
+					includePredictions = true
+				default:
+					logger.Log.Errorf("Unknown include value: %s", part)
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown include value: " + part})
+					return
+				}
+			}
+		}
+
+		persons, err := models.GetPersons(c.Request.Context(), db, models.PersonFilter{ID: uint(id)})
+		if err != nil {
+			logger.Log.Errorf("Failed to get person ID %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during getting": err.Error()})
+			return
+		}
+		if len(persons) == 0 {
+			logger.Log.Infof("Person with ID %d not found", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("person with id=%d not found", id)})
+			return
+		}
+		person := persons[0]
+
+		if includeCandidates {
+			person.NationalityCandidates, err = models.GetPersonNationalityCandidates(db, c.Request.Context(), person.ID)
+			if err != nil {
+				logger.Log.Errorf("Failed to get nationality candidates for person ID %d: %v", id, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error during getting nationality candidates": err.Error()})
+				return
+			}
+		}
+
+		if includePredictions {
+			person.ProviderPredictions, err = models.GetPersonProviderPredictions(db, c.Request.Context(), person.ID)
+			if err != nil {
+				logger.Log.Errorf("Failed to get provider predictions for person ID %d: %v", id, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error during getting provider predictions": err.Error()})
+				return
+			}
+		}
+
+		logger.Log.Infof("Successfully retrieved person with ID %d", id)
+		c.JSON(http.StatusOK, person)
+	}
+}
+
 // CreatePersonHandler godoc
 // @Summary Create a new person
-// @Description Create a new person with automatic enrichment of age, gender, and nationality
+// @Description Create a new person with automatic enrichment of age, gender, and nationality.
+// @Description With partial=true a person is stored even when some providers fail; the failed fields are null with status pending.
+// @Description An optional country_hint localizes the age and gender predictions to that country.
+// @Description Instead of name, surname and patronymic a full_name can be given; the parts parsed from it are returned in parsed_name.
 // @Tags persons
 // @Accept json
 // @Produce json
-// @Param person body models.PersonCreateRequest true "Person data (name is required for enrichment)"
-// @Success 201 {object} models.Person "Successfully created person"
+// @Param person body models.PersonCreateRequest true "Person data (name or full_name is required for enrichment)"
+// @Param partial query boolean false "Store the person even if some providers fail (defaults to ENRICH_ALLOW_PARTIAL)"
+// @Success 201 {object} models.Person "Successfully created and fully enriched person"
+// @Success 202 {object} models.Person "Person created, some fields are pending enrichment (see enrichment_status)"
 // @Failure 400 {object} map[string]string "Invalid request - Missing required fields or invalid data format"
-// @Failure 500 {object} map[string]string "Internal server error - External API failures, database errors, or enrichment failures"
+// @Failure 422 {object} map[string]interface{} "A provider rejected the name (reason invalid_name)"
+// @Failure 500 {object} map[string]interface{} "Internal server error - Database errors or unexpected enrichment failures"
+// @Failure 502 {object} map[string]interface{} "A provider is unreachable or answered with an error (reason provider_unavailable, listed per field in failed_fields)"
+// @Failure 503 {object} map[string]interface{} "Provider quota exhausted (reason quota_exhausted) - retry after the number of seconds in the Retry-After header"
+// @Header 503 {integer} Retry-After "Seconds until the provider quota resets"
 // @Router /persons [post]
-func CreatePersonHandler(db *sql.DB) gin.HandlerFunc {
+func CreatePersonHandler(db *sql.DB, enricher *enrich.Enricher, allowPartial bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger.Log.Info("Processing create person request")
 
-		var person models.Person
-		if err := c.ShouldBindJSON(&person); err != nil {
-			logger.Log.Errorf("Failed to bind JSON: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+		request, partial, ok := bindPersonCreate(c, allowPartial)
+		if !ok {
 			return
 		}
 
-		logger.Log.Debugf("Creating person with name: %s, surname: %s", person.Name, person.Surname)
-
-		age, err := ageFromExternalApi(person.Name)
+		createdPerson, err := createPerson(c.Request.Context(), db, enricher, request, partial)
 		if err != nil {
-			logger.Log.Errorf("Failed to get age for name %s: %v", person.Name, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error during getting age": err.Error()})
+			logger.Log.Errorf("Failed to create person %s: %v", request.Name, err)
+			var enrichErr *enrich.Error
+			if errors.As(err, &enrichErr) {
+				respondEnrichmentError(c, err)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error during creation": err.Error()})
 			return
 		}
-		logger.Log.Debugf("Retrieved age %d for name %s", age, person.Name)
-		person.Age = age
 
-		genderName, err := genderFromExternalApi(person.Name)
-		if err != nil {
-			logger.Log.Errorf("Failed to get gender for name %s: %v", person.Name, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error during getting gender": err.Error()})
+		if createdPerson.EnrichmentStatus.HasPending() {
+			logger.Log.Infof("Created person with ID %d, enrichment pending", createdPerson.ID)
+			c.JSON(http.StatusAccepted, createdPerson)
 			return
 		}
-		logger.Log.Debugf("Retrieved gender '%s' for name %s", genderName, person.Name)
 
-		logger.Log.Debugf("Looking up gender '%s' in database", genderName)
-		genders, err := models.GetGenders(db, c.Request.Context(), models.GenderFilter{Name: genderName})
-		if err != nil {
-			logger.Log.Errorf("Failed to check gender in database: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error during checking gender": err.Error()})
+		logger.Log.Infof("Successfully created person with ID %d", createdPerson.ID)
+		c.JSON(http.StatusCreated, createdPerson)
+	}
+}
+
+// CreatePersonAsyncHandler godoc
+// @Summary Create a new person asynchronously
+// @Description Validate and queue the creation of a person; enrichment runs in the background.
+// @Description Poll the job from the Location header until it is done to get the created person.
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param person body models.PersonCreateRequest true "Person data (name is required for enrichment)"
+// @Param partial query boolean false "Store the person even if some providers fail (defaults to ENRICH_ALLOW_PARTIAL)"
+// @Success 202 {object} models.Job "Creation queued"
+// @Header 202 {string} Location "/jobs/{id}"
+// @Failure 400 {object} map[string]string "Invalid request - Missing required fields or invalid data format"
+// @Failure 500 {object} map[string]string "Internal server error - Database connection issues"
+// @Router /persons:async [post]
+func CreatePersonAsyncHandler(db *sql.DB, allowPartial bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Log.Info("Processing async create person request")
+
+		request, partial, ok := bindPersonCreate(c, allowPartial)
+		if !ok {
 			return
 		}
 
-		var genderID int
-		if len(genders) == 0 {
-			logger.Log.Infof("Gender '%s' not found in database, creating new entry", genderName)
-			newGender, err := models.CreateGender(db, c.Request.Context(), genderName)
-			if err != nil {
-				logger.Log.Errorf("Failed to create gender '%s': %v", genderName, err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error during creating gender": err.Error()})
-				return
-			}
-			genderID = newGender.ID
-			logger.Log.Infof("Created new gender '%s' with ID %d", genderName, genderID)
-		} else {
-			genderID = genders[0].ID
-			logger.Log.Infof("Using existing gender '%s' with ID %d", genderName, genderID)
+		payload, err := json.Marshal(createPersonPayload{Person: request, Partial: partial})
+		if err != nil {
+			logger.Log.Errorf("Failed to encode job payload: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during queueing": err.Error()})
+			return
 		}
-		person.Gender.ID = genderID
 
-		nationalityCode, err := nationalityFromExternalApi(person.Name)
+		job, err := models.EnqueuePayloadJob(db, c.Request.Context(), models.JobKindCreatePerson, payload)
 		if err != nil {
-			logger.Log.Errorf("Failed to get nationality for name %s: %v", person.Name, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error during getting nationality": err.Error()})
+			logger.Log.Errorf("Failed to queue person creation: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error during queueing": err.Error()})
 			return
 		}
-		logger.Log.Debugf("Retrieved nationality '%s' for name %s", nationalityCode, person.Name)
 
-		logger.Log.Debugf("Looking up nationality '%s' in database", nationalityCode)
-		nationalities, err := models.GetNationalities(db, c.Request.Context(), models.NationalityFilter{Name: nationalityCode})
+		logger.Log.Infof("Queued creation of person %s as job %d", request.Name, job.ID)
+		c.Header("Location", fmt.Sprintf("/jobs/%d", job.ID))
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// bindPersonCreate reads and validates a person creation request together with
+// the partial query parameter. It writes the 400 response itself and reports
+// whether the request can proceed.
+func bindPersonCreate(c *gin.Context, allowPartial bool) (models.PersonCreateRequest, bool, bool) {
+	var request models.PersonCreateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Log.Errorf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+		return models.PersonCreateRequest{}, false, false
+	}
+	if err := validatePersonCreate(&request); err != nil {
+		logger.Log.Errorf("Invalid person: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+		return models.PersonCreateRequest{}, false, false
+	}
+
+	partial, ok := parsePartial(c, allowPartial)
+	if !ok {
+		return models.PersonCreateRequest{}, false, false
+	}
+	return request, partial, true
+}
+
+// validatePersonCreate checks request, fills the name parts from its full
+// name if given, cleans them up with models.CleanName and normalizes the
+// country hint to upper case.
+func validatePersonCreate(request *models.PersonCreateRequest) error {
+	if strings.TrimSpace(request.FullName) != "" {
+		if request.Name != "" || request.Surname != "" || request.Patronymic != "" {
+			return errors.New("full_name cannot be combined with name, surname or patronymic")
+		}
+		parsed, err := enrich.ParseFullName(request.FullName)
 		if err != nil {
-			logger.Log.Errorf("Failed to check nationality in database: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error during checking nationality": err.Error()})
-			return
+			return err
 		}
+		request.Name, request.Surname, request.Patronymic = parsed.Name, parsed.Surname, parsed.Patronymic
+		request.ParsedName = &parsed
+	}
+	request.Name = models.CleanName(request.Name)
+	request.Surname = models.CleanName(request.Surname)
+	request.Patronymic = models.CleanName(request.Patronymic)
+	if request.Name == "" {
+		return errors.New("name is required")
+	}
+	if request.CountryHint != "" {
+		request.CountryHint = strings.ToUpper(strings.TrimSpace(request.CountryHint))
+		if !countryCodePattern.MatchString(request.CountryHint) {
+			return fmt.Errorf("country_hint %q is not an ISO 3166-1 alpha-2 code", request.CountryHint)
+		}
+	}
+	return nil
+}
 
-		var nationalityID int
-		if len(nationalities) == 0 {
-			logger.Log.Infof("Nationality '%s' not found in database, creating new entry", nationalityCode)
-			newNationality, err := models.CreateNationality(db, c.Request.Context(), nationalityCode)
-			if err != nil {
-				logger.Log.Errorf("Failed to create nationality '%s': %v", nationalityCode, err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error during creating nationality": err.Error()})
-				return
-			}
-			nationalityID = newNationality.ID
-			logger.Log.Infof("Created new nationality '%s' with ID %d", nationalityCode, nationalityID)
-		} else {
-			nationalityID = nationalities[0].ID
-			logger.Log.Infof("Using existing nationality '%s' with ID %d", nationalityCode, nationalityID)
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// parsePartial reads the partial query parameter, falling back to
+// allowPartial. It writes the 400 response itself on a bad value.
+func parsePartial(c *gin.Context, allowPartial bool) (bool, bool) {
+	partialStr := c.Query("partial")
+	if partialStr == "" {
+		return allowPartial, true
+	}
+
+	partial, err := strconv.ParseBool(partialStr)
+	if err != nil {
+		logger.Log.Errorf("Invalid partial value: %s - %v", partialStr, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Wrong partial format: " + partialStr})
+		return false, false
+	}
+	return partial, true
+}
+
+// ParsePersonNameHandler godoc
+// @Summary Parse a full name
+// @Description Split a full name into name, surname and patronymic without storing anything.
+// @Description Surname-first orders ("Ivanov Ivan Petrovich", "Ivanov, Ivan") are detected from commas, patronymic endings and typical surname endings.
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param person body models.PersonParseRequest true "Full name"
+// @Success 200 {object} models.ParsedName "Parsed name parts with the detected order and a confidence score"
+// @Failure 400 {object} map[string]string "Invalid request - Invalid JSON format or empty full name"
+// @Router /persons/parse [post]
+func ParsePersonNameHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.PersonParseRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Log.Errorf("Failed to bind JSON: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
+			return
 		}
-		person.Nationality.ID = nationalityID
 
-		logger.Log.Debugf("Saving person to database")
-		createdPerson, err := models.CreatePerson(c.Request.Context(), person, db)
+		parsed, err := enrich.ParseFullName(request.FullName)
 		if err != nil {
-			logger.Log.Errorf("Failed to create person: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error during creation": err.Error()})
+			logger.Log.Errorf("Invalid full name %q: %v", request.FullName, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error during handling request": err.Error()})
 			return
 		}
-
-		logger.Log.Infof("Successfully created person with ID %d", createdPerson.ID)
-		c.JSON(http.StatusCreated, createdPerson)
+		logger.Log.Debugf("Parsed full name %q: %+v", request.FullName, parsed)
+		c.JSON(http.StatusOK, parsed)
 	}
 }
 
@@ -234,7 +435,6 @@ func UpdatePersonHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
 		logger.Log.Infof("Processing PUT update person request for ID: %s", idStr)
-		logger.Log.Infof(idStr)
 		id, err := strconv.ParseUint(idStr, 10, 64)
 		if err != nil {
 			logger.Log.Errorf("Invalid ID format: %s - %v", idStr, err)
@@ -247,9 +447,9 @@ func UpdatePersonHandler(db *sql.DB) gin.HandlerFunc {
 			Name          string `json:"name"`
 			Surname       string `json:"surname"`
 			Patronymic    string `json:"patronymic,omitempty"`
-			Age           int    `json:"age"`
-			GenderID      int    `json:"gender_id,omitempty"`
-			NationalityID int    `json:"nationality_id,omitempty"`
+			Age           *int   `json:"age"`
+			GenderID      *int   `json:"gender_id,omitempty"`
+			NationalityID *int   `json:"nationality_id,omitempty"`
 		}
 
 		if err := c.ShouldBindJSON(&requestData); err != nil {
@@ -265,8 +465,12 @@ func UpdatePersonHandler(db *sql.DB) gin.HandlerFunc {
 		person.Surname = requestData.Surname
 		person.Patronymic = requestData.Patronymic
 		person.Age = requestData.Age
-		person.Gender.ID = requestData.GenderID
-		person.Nationality.ID = requestData.NationalityID
+		if requestData.GenderID != nil {
+			person.Gender = &models.Gender{ID: *requestData.GenderID}
+		}
+		if requestData.NationalityID != nil {
+			person.Nationality = &models.Nationality{ID: *requestData.NationalityID}
+		}
 
 		// Continue with validation and update
 		updatedPerson, err := models.ReplacePerson(c.Request.Context(), person, db)
@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"NameEnricher/internal/enrich"
+	"NameEnricher/internal/models"
+	"NameEnricher/pkg/logger"
+	"context"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	exitCode := m.Run()
+
+	os.Exit(exitCode)
+}
+
+func personRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "age_count", "gender_id", "gender_name",
+		"gender_probability", "nationality_id", "nationality_name", "nationality_probability",
+		"age_status", "gender_status", "nationality_status", "country_hint",
+		"age_source", "gender_source", "nationality_source"})
+}
+
+func addPersonRow(rows *sqlmock.Rows, p models.Person) {
+	var age, genderID, genderName, nationalityID, nationalityName, countryHint interface{}
+	if p.Age != nil {
+		age = *p.Age
+	}
+	if p.Gender != nil {
+		genderID, genderName = p.Gender.ID, p.Gender.Name
+	}
+	if p.Nationality != nil {
+		nationalityID, nationalityName = p.Nationality.ID, p.Nationality.Name
+	}
+	if p.CountryHint != "" {
+		countryHint = p.CountryHint
+	}
+	sources := make([]interface{}, 3)
+	for i, source := range []string{p.EnrichmentSource.Age, p.EnrichmentSource.Gender, p.EnrichmentSource.Nationality} {
+		if source != "" {
+			sources[i] = source
+		}
+	}
+	rows.AddRow(p.ID, p.Name, p.Surname, p.Patronymic, age, p.AgeCount, genderID, genderName,
+		p.GenderProbability, nationalityID, nationalityName, p.NationalityProbability,
+		p.EnrichmentStatus.Age, p.EnrichmentStatus.Gender, p.EnrichmentStatus.Nationality, countryHint,
+		sources[0], sources[1], sources[2])
+}
+
+// expectPersonReload mocks the GetPersons call that follows every write.
+func expectPersonReload(mock sqlmock.Sqlmock, p models.Person) {
+	rows := personRows()
+	addPersonRow(rows, p)
+	mock.ExpectQuery(`WHERE 1=1 AND p.id = \$1$`).WithArgs(p.ID).WillReturnRows(rows)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// stubProvider answers every name with result, except for the fields listed
+// in failures.
+type stubProvider struct {
+	result   enrich.Result
+	failures map[string]error
+}
+
+func (s stubProvider) Age(_ context.Context, _ string) (enrich.AgePrediction, error) {
+	if err := s.failures[enrich.FieldAge]; err != nil {
+		return enrich.AgePrediction{}, err
+	}
+	return s.result.Age, nil
+}
+
+func (s stubProvider) Gender(_ context.Context, _ string) (enrich.GenderPrediction, error) {
+	if err := s.failures[enrich.FieldGender]; err != nil {
+		return enrich.GenderPrediction{}, err
+	}
+	return s.result.Gender, nil
+}
+
+func (s stubProvider) Nationality(_ context.Context, _ string) (enrich.NationalityPrediction, error) {
+	if err := s.failures[enrich.FieldNationality]; err != nil {
+		return enrich.NationalityPrediction{}, err
+	}
+	return s.result.Nationality, nil
+}
+
+func stubEnricher(result enrich.Result, failures map[string]error) *enrich.Enricher {
+	provider := stubProvider{result: result, failures: failures}
+	return enrich.NewEnricher(enrich.Providers{Age: provider, Gender: provider, Nationality: provider}, nil)
+}
+
+// serve sends a JSON request to handler registered on route and records the response.
+func serve(handler gin.HandlerFunc, method, route, target, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, route, handler)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+var john = enrich.Result{
+	Age:    enrich.AgePrediction{Age: intPtr(47), Count: 1200, Source: "agify"},
+	Gender: enrich.GenderPrediction{Gender: "male", Probability: 0.99, Count: 5000, Source: "genderize"},
+	Nationality: enrich.NationalityPrediction{CountryID: "US", Probability: 0.4, Source: "nationalize",
+		Countries: []enrich.CountryProbability{{CountryID: "US", Probability: 0.4}}},
+}
+
+func TestCreatePersonHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("PendingFields", func(t *testing.T) {
+		enricher := stubEnricher(john, map[string]error{enrich.FieldAge: enrich.ErrProviderUnavailable})
+
+		mock.ExpectQuery("^INSERT INTO genders").
+			WithArgs("male").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "male"))
+		mock.ExpectQuery("^INSERT INTO nationalities").
+			WithArgs("US").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "US"))
+		mock.ExpectBegin()
+		mock.ExpectQuery("^INSERT INTO persons").
+			WithArgs("John", "Doe", "", nil, 0, 1, 0.99, 2, 0.4,
+				models.EnrichmentPending, models.EnrichmentOK, models.EnrichmentOK, "",
+				"", "genderize", "nationalize", "john", "doe", "john", "doe").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+				AddRow(1, "John", "Doe", "", nil, 1, 2))
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates").
+			WithArgs(uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("^INSERT INTO person_nationality_candidates").
+			WithArgs(uint(1), "US", 0.4, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		person := models.Person{
+			ID:                     1,
+			Name:                   "John",
+			Surname:                "Doe",
+			Gender:                 &models.Gender{ID: 1, Name: "male"},
+			GenderProbability:      0.99,
+			Nationality:            &models.Nationality{ID: 2, Name: "US"},
+			NationalityProbability: 0.4,
+			EnrichmentStatus: models.PersonEnrichment{
+				Age:         models.EnrichmentPending,
+				Gender:      models.EnrichmentOK,
+				Nationality: models.EnrichmentOK,
+			},
+			EnrichmentSource: models.PersonEnrichmentSource{Gender: "genderize", Nationality: "nationalize"},
+		}
+		expectPersonReload(mock, person)
+		rows := addJobRow(jobRows(), models.Job{ID: 3, Kind: models.JobKindReEnrich, PersonID: 1, Status: models.JobQueued, MaxAttempts: 5})
+		mock.ExpectQuery("^INSERT INTO jobs").
+			WithArgs(models.JobKindReEnrich, uint(1)).
+			WillReturnRows(rows)
+
+		w := serve(CreatePersonHandler(db, enricher, false), http.MethodPost, "/persons", "/persons?partial=true",
+			`{"name": "John", "surname": "Doe"}`)
+
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body)
+		}
+		var created models.Person
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if created.EnrichmentStatus != person.EnrichmentStatus {
+			t.Errorf("Expected enrichment status %+v, got %+v", person.EnrichmentStatus, created.EnrichmentStatus)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("FullName", func(t *testing.T) {
+		enricher := stubEnricher(john, nil)
+
+		mock.ExpectQuery("^INSERT INTO genders").
+			WithArgs("male").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "male"))
+		mock.ExpectQuery("^INSERT INTO nationalities").
+			WithArgs("US").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "US"))
+		mock.ExpectBegin()
+		mock.ExpectQuery("^INSERT INTO persons").
+			WithArgs("Ivan", "Ivanov", "Petrovich", 47, 1200, 1, 0.99, 2, 0.4,
+				models.EnrichmentOK, models.EnrichmentOK, models.EnrichmentOK, "",
+				"agify", "genderize", "nationalize", "ivan", "ivanov", "ivan", "ivanov").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+				AddRow(2, "Ivan", "Ivanov", "Petrovich", 47, 1, 2))
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates").
+			WithArgs(uint(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("^INSERT INTO person_nationality_candidates").
+			WithArgs(uint(2), "US", 0.4, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectPersonReload(mock, models.Person{
+			ID:          2,
+			Name:        "Ivan",
+			Surname:     "Ivanov",
+			Patronymic:  "Petrovich",
+			Age:         intPtr(47),
+			Gender:      &models.Gender{ID: 1, Name: "male"},
+			Nationality: &models.Nationality{ID: 2, Name: "US"},
+			EnrichmentStatus: models.PersonEnrichment{
+				Age:         models.EnrichmentOK,
+				Gender:      models.EnrichmentOK,
+				Nationality: models.EnrichmentOK,
+			},
+		})
+
+		w := serve(CreatePersonHandler(db, enricher, false), http.MethodPost, "/persons", "/persons",
+			`{"full_name": "Ivanov  Ivan Petrovich"}`)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+		}
+		var created models.Person
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := models.ParsedName{Name: "Ivan", Surname: "Ivanov", Patronymic: "Petrovich", Order: enrich.OrderSurnameFirst, Confidence: 0.95}
+		if created.ParsedName == nil || *created.ParsedName != want {
+			t.Errorf("Expected parsed name %+v, got %+v", want, created.ParsedName)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("FullNameWithParts", func(t *testing.T) {
+		w := serve(CreatePersonHandler(db, stubEnricher(john, nil), false), http.MethodPost, "/persons", "/persons",
+			`{"full_name": "Ivanov Ivan", "name": "Ivan"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("QuotaExhausted", func(t *testing.T) {
+		quotaErr := &enrich.QuotaError{Provider: "genderize", StatusCode: http.StatusTooManyRequests, Reset: time.Now().Add(90 * time.Second)}
+		enricher := stubEnricher(john, map[string]error{enrich.FieldGender: quotaErr})
+
+		w := serve(CreatePersonHandler(db, enricher, false), http.MethodPost, "/persons", "/persons",
+			`{"name": "John", "surname": "Doe"}`)
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != "90" {
+			t.Errorf("Expected Retry-After 90, got %q", retryAfter)
+		}
+		var body struct {
+			Reason       string            `json:"reason"`
+			FailedFields map[string]string `json:"failed_fields"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if body.Reason != reasonQuotaExhausted {
+			t.Errorf("Expected reason %s, got %s", reasonQuotaExhausted, body.Reason)
+		}
+		if _, ok := body.FailedFields[enrich.FieldGender]; !ok || len(body.FailedFields) != 1 {
+			t.Errorf("Expected only gender in failed_fields, got %v", body.FailedFields)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		invalidErr := &enrich.ProviderError{Provider: "agify", StatusCode: http.StatusUnprocessableEntity, Err: enrich.ErrInvalidName, Message: "Invalid 'name' parameter"}
+		enricher := stubEnricher(john, map[string]error{enrich.FieldAge: invalidErr})
+
+		w := serve(CreatePersonHandler(db, enricher, false), http.MethodPost, "/persons", "/persons",
+			`{"name": "John", "surname": "Doe"}`)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body)
+		}
+		if w.Header().Get("Retry-After") != "" {
+			t.Errorf("Expected no Retry-After header, got %q", w.Header().Get("Retry-After"))
+		}
+		var body struct {
+			Reason       string            `json:"reason"`
+			FailedFields map[string]string `json:"failed_fields"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if body.Reason != reasonInvalidName {
+			t.Errorf("Expected reason %s, got %s", reasonInvalidName, body.Reason)
+		}
+		if body.FailedFields[enrich.FieldAge] != invalidErr.Error() {
+			t.Errorf("Expected age failure %q, got %v", invalidErr.Error(), body.FailedFields)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("FailsWithoutPartial", func(t *testing.T) {
+		enricher := stubEnricher(john, map[string]error{enrich.FieldAge: enrich.ErrProviderUnavailable})
+
+		w := serve(CreatePersonHandler(db, enricher, false), http.MethodPost, "/persons", "/persons",
+			`{"name": "John", "surname": "Doe"}`)
+
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadGateway, w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+// TestCreatePersonHandlerReplay creates a person with the providers answering
+// from testdata/providers.json, as they did when it was recorded.
+func TestCreatePersonHandlerReplay(t *testing.T) {
+	t.Setenv("ENRICH_CASSETTE_MODE", enrich.CassetteReplay)
+	t.Setenv("ENRICH_CASSETTE", "testdata/providers.json")
+	cfg, err := enrich.LoadConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	providers, err := enrich.NewProviders(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	enricher := enrich.NewEnricher(providers, nil)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("^INSERT INTO genders").
+		WithArgs("male").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "male"))
+	mock.ExpectQuery("^INSERT INTO nationalities").
+		WithArgs("UA").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "UA"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("^INSERT INTO persons").
+		WithArgs("Dmitriy", "Ivanov", "", 41, 12345, 1, 0.99, 3, 0.31,
+			models.EnrichmentOK, models.EnrichmentOK, models.EnrichmentOK, "",
+			"agify", "genderize", "nationalize", "dmitriy", "ivanov", "dmitriy", "ivanov").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(1, "Dmitriy", "Ivanov", "", 41, 1, 3))
+	mock.ExpectExec("^DELETE FROM person_nationality_candidates").
+		WithArgs(uint(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("^INSERT INTO person_nationality_candidates").
+		WithArgs(uint(1), "UA", 0.31, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("^INSERT INTO person_nationality_candidates").
+		WithArgs(uint(1), "RU", 0.27, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	person := models.Person{
+		ID:                     1,
+		Name:                   "Dmitriy",
+		Surname:                "Ivanov",
+		Age:                    intPtr(41),
+		AgeCount:               12345,
+		Gender:                 &models.Gender{ID: 1, Name: "male"},
+		GenderProbability:      0.99,
+		Nationality:            &models.Nationality{ID: 3, Name: "UA"},
+		NationalityProbability: 0.31,
+		EnrichmentStatus: models.PersonEnrichment{
+			Age:         models.EnrichmentOK,
+			Gender:      models.EnrichmentOK,
+			Nationality: models.EnrichmentOK,
+		},
+		EnrichmentSource: models.PersonEnrichmentSource{Age: "agify", Gender: "genderize", Nationality: "nationalize"},
+	}
+	expectPersonReload(mock, person)
+
+	w := serve(CreatePersonHandler(db, enricher, false), http.MethodPost, "/persons", "/persons",
+		`{"name": "Dmitriy", "surname": "Ivanov"}`)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	var created models.Person
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created.Age == nil || *created.Age != 41 || created.Nationality == nil || created.Nationality.Name != "UA" {
+		t.Errorf("Expected the recorded age 41 and nationality UA, got %+v", created)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestParsePersonNameHandler(t *testing.T) {
+	t.Run("SurnameFirst", func(t *testing.T) {
+		w := serve(ParsePersonNameHandler(), http.MethodPost, "/persons/parse", "/persons/parse",
+			`{"full_name": "Ivanov, Ivan"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		var parsed models.ParsedName
+		if err := json.Unmarshal(w.Body.Bytes(), &parsed); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if parsed.Name != "Ivan" || parsed.Surname != "Ivanov" || parsed.Order != enrich.OrderSurnameFirst {
+			t.Errorf("Expected Ivan Ivanov in surname-first order, got %+v", parsed)
+		}
+	})
+
+	t.Run("EmptyFullName", func(t *testing.T) {
+		w := serve(ParsePersonNameHandler(), http.MethodPost, "/persons/parse", "/persons/parse",
+			`{"full_name": "  "}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+		}
+	})
+}
@@ -1,106 +1,277 @@
 package handlers
 
 import (
+	"NameEnricher/internal/enrich"
+	"NameEnricher/internal/models"
 	"NameEnricher/pkg/logger"
-	"encoding/json"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
 )
 
-func ageFromExternalApi(name string) (int, error) {
-	logger.Log.Infof("Requesting age data for name: %s", name)
+// createPerson enriches and stores a new person. With partial set, providers
+// that fail leave their fields pending and a re-enrichment job is queued;
+// otherwise any provider failure aborts the creation with an *enrich.Error.
+func createPerson(ctx context.Context, db *sql.DB, enricher *enrich.Enricher, request models.PersonCreateRequest, partial bool) (models.Person, error) {
+	logger.Log.Debugf("Creating person with name: %s, surname: %s", request.Name, request.Surname)
 
-	apiUrl := fmt.Sprintf("https://api.agify.io/?name=%s", name)
-	resp, err := http.Get(apiUrl)
-	if err != nil {
-		logger.Log.Errorf("Failed to request age API: %v", err)
-		return 0, fmt.Errorf("failed to request age API: %w", err)
+	result, err := enricher.Enrich(ctx, personQuery(request))
+	return storePerson(ctx, db, request, result, err, partial, 0)
+}
+
+// personQuery returns the enrichment query for a person creation request.
+func personQuery(request models.PersonCreateRequest) enrich.Query {
+	return enrich.Query{
+		Name:       request.Name,
+		CountryID:  request.CountryHint,
+		Surname:    request.Surname,
+		Patronymic: request.Patronymic,
+	}
+}
+
+// storePerson saves a person from its enrichment outcome. err is the
+// enrichment error, if any. A non-zero jobID is the create_person job the
+// person is recorded on, see models.CreateJobPerson.
+func storePerson(ctx context.Context, db *sql.DB, request models.PersonCreateRequest, result enrich.Result, err error, partial bool, jobID int64) (models.Person, error) {
+	var enrichErr *enrich.Error
+	if err != nil && !(partial && errors.As(err, &enrichErr)) {
+		return models.Person{}, err
 	}
-	defer resp.Body.Close()
+	logger.Log.Debugf("Enriched name %s: %+v", request.Name, result)
 
-	logger.Log.Debugf("Received response from age API with status: %s", resp.Status)
+	person := models.Person{
+		Name:        request.Name,
+		Surname:     request.Surname,
+		Patronymic:  request.Patronymic,
+		CountryHint: request.CountryHint,
+	}
+	if err := applyEnrichment(ctx, db, &person, result); err != nil {
+		return models.Person{}, fmt.Errorf("applying enrichment: %w", err)
+	}
+	if enrichErr != nil {
+		logger.Log.Warnf("Storing name %s with pending fields: %v", person.Name, enrichErr)
+		markPending(&person, retryableFields(enrichErr, result))
+	}
 
-	var response struct {
-		Age  int    `json:"age"`
-		Name string `json:"name"`
+	logger.Log.Debugf("Saving person to database")
+	var createdPerson models.Person
+	if jobID != 0 {
+		createdPerson, err = models.CreateJobPerson(ctx, jobID, person, db)
+	} else {
+		createdPerson, err = models.CreatePerson(ctx, person, db)
 	}
+	if err != nil {
+		return models.Person{}, err
+	}
+	createdPerson.ParsedName = request.ParsedName
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		logger.Log.Errorf("Failed to decode age API response: %v", err)
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+	if createdPerson.EnrichmentStatus.HasPending() {
+		// The periodic sweep would pick the person up as well, queueing now just saves the wait.
+		if _, err := models.EnqueueJob(db, ctx, models.JobKindReEnrich, createdPerson.ID); err != nil {
+			logger.Log.Warnf("Failed to queue re-enrichment for person ID %d: %v", createdPerson.ID, err)
+		}
 	}
 
-	logger.Log.Infof("Successfully determined age %d for name: %s", response.Age, name)
-	return response.Age, nil
+	return createdPerson, nil
 }
 
-func genderFromExternalApi(name string) (string, error) {
-	logger.Log.Infof("Requesting gender data for name: %s", name)
+// applyEnrichment copies the provider predictions, their sources and the
+// per-provider breakdown of ensembles onto person and resolves the gender and
+// nationality reference rows. Fields without a prediction are left nil and
+// marked unknown, so no reference row is created for them.
+func applyEnrichment(ctx context.Context, db *sql.DB, person *models.Person, result enrich.Result) error {
+	person.Age = nil
+	person.Gender = nil
+	person.Nationality = nil
+	person.NationalityCandidates = nil
+	person.EnrichmentSource = models.PersonEnrichmentSource{}
+	person.ProviderPredictions = nil
 
-	apiUrl := fmt.Sprintf("https://api.genderize.io/?name=%s", name)
-	resp, err := http.Get(apiUrl)
-	if err != nil {
-		logger.Log.Errorf("Failed to request gender API: %v", err)
-		return "", fmt.Errorf("failed to request gender API: %w", err)
+	person.AgeCount = result.Age.Count
+	person.EnrichmentStatus.Age = models.EnrichmentUnknown
+	if result.Age.Known() {
+		person.Age = result.Age.Age
+		person.EnrichmentStatus.Age = models.EnrichmentOK
+		person.EnrichmentSource.Age = result.Age.Source
 	}
-	defer resp.Body.Close()
 
-	logger.Log.Debugf("Received response from gender API with status: %s", resp.Status)
+	person.GenderProbability = result.Gender.Probability
+	person.EnrichmentStatus.Gender = models.EnrichmentUnknown
+	if result.Gender.Known() {
+		gender, err := models.GetOrCreateGender(db, ctx, result.Gender.Gender)
+		if err != nil {
+			return fmt.Errorf("resolving gender '%s': %w", result.Gender.Gender, err)
+		}
+		logger.Log.Debugf("Using gender '%s' with ID %d", gender.Name, gender.ID)
+		person.Gender = &gender
+		person.EnrichmentStatus.Gender = models.EnrichmentOK
+		person.EnrichmentSource.Gender = result.Gender.Source
+		person.ProviderPredictions = append(person.ProviderPredictions, genderBreakdown(result.Gender)...)
+	}
 
-	var response struct {
-		Gender string `json:"gender"`
-		Name   string `json:"name"`
+	person.NationalityProbability = result.Nationality.Probability
+	person.EnrichmentStatus.Nationality = models.EnrichmentUnknown
+	if result.Nationality.Known() {
+		nationality, err := models.GetOrCreateNationality(db, ctx, result.Nationality.CountryID)
+		if err != nil {
+			return fmt.Errorf("resolving nationality '%s': %w", result.Nationality.CountryID, err)
+		}
+		logger.Log.Debugf("Using nationality '%s' with ID %d", nationality.Name, nationality.ID)
+		person.Nationality = &nationality
+		person.EnrichmentStatus.Nationality = models.EnrichmentOK
+		person.EnrichmentSource.Nationality = result.Nationality.Source
+		person.ProviderPredictions = append(person.ProviderPredictions, nationalityBreakdown(result.Nationality)...)
+	}
+	for _, country := range result.Nationality.Countries {
+		person.NationalityCandidates = append(person.NationalityCandidates, models.NationalityCandidate{
+			CountryID:   country.CountryID,
+			Probability: country.Probability,
+		})
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		logger.Log.Errorf("Failed to decode gender API response: %v", err)
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	return nil
+}
+
+// genderBreakdown returns the answers an ensemble combined into prediction,
+// one per provider that knew the gender. It is empty for a single provider.
+func genderBreakdown(prediction enrich.GenderPrediction) []models.ProviderPrediction {
+	var breakdown []models.ProviderPrediction
+	for _, member := range prediction.Members {
+		if !member.Known() {
+			continue
+		}
+		breakdown = append(breakdown, models.ProviderPrediction{
+			Field:       enrich.FieldGender,
+			Source:      member.Source,
+			Value:       member.Gender,
+			Probability: member.Probability,
+			Count:       member.Count,
+		})
 	}
+	return breakdown
+}
 
-	logger.Log.Infof("Successfully determined gender '%s' for name: %s", response.Gender, name)
-	return response.Gender, nil
+// nationalityBreakdown is the nationality counterpart of genderBreakdown,
+// with one entry per country of every provider's distribution.
+func nationalityBreakdown(prediction enrich.NationalityPrediction) []models.ProviderPrediction {
+	var breakdown []models.ProviderPrediction
+	for _, member := range prediction.Members {
+		countries := member.Countries
+		if len(countries) == 0 && member.Known() {
+			countries = []enrich.CountryProbability{{CountryID: member.CountryID, Probability: member.Probability}}
+		}
+		for _, country := range countries {
+			breakdown = append(breakdown, models.ProviderPrediction{
+				Field:       enrich.FieldNationality,
+				Source:      member.Source,
+				Value:       country.CountryID,
+				Probability: country.Probability,
+			})
+		}
+	}
+	return breakdown
 }
 
-func nationalityFromExternalApi(name string) (string, error) {
-	logger.Log.Infof("Requesting nationality data for name: %s", name)
+// markPending flags the fields whose provider failed so a background retry can
+// complete them later.
+func markPending(person *models.Person, fields []string) {
+	for _, field := range fields {
+		switch field {
+		case enrich.FieldAge:
+			person.EnrichmentStatus.Age = models.EnrichmentPending
+		case enrich.FieldGender:
+			person.EnrichmentStatus.Gender = models.EnrichmentPending
+		case enrich.FieldNationality:
+			person.EnrichmentStatus.Nationality = models.EnrichmentPending
+		}
+	}
+}
 
-	apiUrl := fmt.Sprintf("https://api.nationalize.io/?name=%s", name)
-	resp, err := http.Get(apiUrl)
-	if err != nil {
-		logger.Log.Errorf("Failed to request nationality API: %v", err)
-		return "", fmt.Errorf("failed to request nationality API: %w", err)
+// retryableFields returns the failed fields worth retrying. A provider that
+// rejected the name will reject it again, so those fields stay unknown, and a
+// field the rules answered for has nothing left to retry.
+func retryableFields(enrichErr *enrich.Error, result enrich.Result) []string {
+	known := map[string]bool{
+		enrich.FieldAge:         result.Age.Known(),
+		enrich.FieldGender:      result.Gender.Known(),
+		enrich.FieldNationality: result.Nationality.Known(),
 	}
-	defer resp.Body.Close()
+	var fields []string
+	for _, field := range enrichErr.Fields() {
+		if !known[field] && !errors.Is(enrichErr.Failures[field], enrich.ErrInvalidName) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
 
-	logger.Log.Debugf("Received response from nationality API with status: %s", resp.Status)
+// Reasons reported next to enrichment errors, so clients need not parse messages.
+const (
+	reasonInvalidName         = "invalid_name"
+	reasonQuotaExhausted      = "quota_exhausted"
+	reasonProviderUnavailable = "provider_unavailable"
+)
 
-	var response struct {
-		Country []struct {
-			CountryId   string  `json:"country_id"`
-			Probability float64 `json:"probability"`
-		} `json:"country"`
+// enrichmentErrorStatus maps an enrichment error to its HTTP status and reason.
+// When several providers failed differently, the failure the client can act
+// on wins: a rejected name, then an exhausted quota, then an unreachable provider.
+func enrichmentErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, enrich.ErrInvalidName):
+		return http.StatusUnprocessableEntity, reasonInvalidName
+	case errors.Is(err, enrich.ErrQuotaExhausted):
+		return http.StatusServiceUnavailable, reasonQuotaExhausted
+	case errors.Is(err, enrich.ErrProviderUnavailable):
+		return http.StatusBadGateway, reasonProviderUnavailable
+	default:
+		return http.StatusInternalServerError, ""
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		logger.Log.Errorf("Failed to decode nationality API response: %v", err)
-		return "", fmt.Errorf("failed to decode response: %w", err)
+// respondEnrichmentError writes a single response describing every field
+// whose providers failed while enriching a person, keyed by field name. When a provider quota is exhausted the
+// request can only succeed later, so the 503 carries Retry-After.
+func respondEnrichmentError(c *gin.Context, err error) {
+	status, reason := enrichmentErrorStatus(err)
+	var quotaErr *enrich.QuotaError
+	if status == http.StatusServiceUnavailable && errors.As(err, &quotaErr) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(quotaErr.RetryAfter().Seconds()))))
 	}
 
-	if len(response.Country) == 0 {
-		logger.Log.Warnf("No nationality data found for name: %s", name)
-		return "", fmt.Errorf("country not found for name: %s", name)
+	var enrichErr *enrich.Error
+	if !errors.As(err, &enrichErr) {
+		c.JSON(status, gin.H{"error during enrichment": err.Error(), "reason": reason})
+		return
 	}
 
-	var result string
-	maxProbability := float64(0)
-	for _, country := range response.Country {
-		if country.Probability > maxProbability {
-			maxProbability = country.Probability
-			result = country.CountryId
-		}
+	failed := make(map[string]string, len(enrichErr.Failures))
+	for field, failure := range enrichErr.Failures {
+		failed[field] = failure.Error()
 	}
 
-	logger.Log.Infof("Successfully determined nationality '%s' (probability: %.2f) for name: %s",
-		result, maxProbability, name)
-	return result, nil
+	c.JSON(status, gin.H{
+		"error during enrichment": enrichErr.Error(),
+		"reason":                  reason,
+		"failed_fields":           failed,
+	})
+}
+
+// CustomMethodsHandler serves custom methods such as POST /persons:async.
+// Gin cannot escape ':' in a path, so the method is registered as a parameter
+// ("/persons:method") and its value, including the colon, selects the handler.
+func CustomMethodsHandler(param string, methods map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler, ok := methods[c.Param(param)]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown method: " + c.Param(param)})
+			return
+		}
+		handler(c)
+	}
 }
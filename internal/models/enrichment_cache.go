@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NameEnrichmentCache holds the raw provider results for a normalized first name.
+type NameEnrichmentCache struct {
+	Name        string          `json:"name"`
+	Age         json.RawMessage `json:"age"`
+	Gender      json.RawMessage `json:"gender"`
+	Nationality json.RawMessage `json:"nationality"`
+	FetchedAt   time.Time       `json:"fetched_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+}
+
+// GetNameEnrichmentCache returns the cached entry for name if it has not expired yet.
+func GetNameEnrichmentCache(db *sql.DB, ctx context.Context, name string) (NameEnrichmentCache, bool, error) {
+	var entry NameEnrichmentCache
+	err := db.QueryRowContext(ctx,
+		"SELECT name, age, gender, nationality, fetched_at, expires_at FROM name_enrichment_cache WHERE name = $1 AND expires_at > now()",
+		name).Scan(
+		&entry.Name,
+		&entry.Age,
+		&entry.Gender,
+		&entry.Nationality,
+		&entry.FetchedAt,
+		&entry.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NameEnrichmentCache{}, false, nil
+		}
+		return NameEnrichmentCache{}, false, fmt.Errorf("error reading enrichment cache: %w", err)
+	}
+	return entry, true, nil
+}
+
+// SaveNameEnrichmentCache inserts or refreshes the cached entry for entry.Name.
+func SaveNameEnrichmentCache(db *sql.DB, ctx context.Context, entry NameEnrichmentCache) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO name_enrichment_cache (name, age, gender, nationality, fetched_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (name) DO UPDATE SET age = EXCLUDED.age, gender = EXCLUDED.gender, nationality = EXCLUDED.nationality,
+fetched_at = EXCLUDED.fetched_at, expires_at = EXCLUDED.expires_at`,
+		entry.Name,
+		string(entry.Age),
+		string(entry.Gender),
+		string(entry.Nationality),
+		entry.FetchedAt,
+		entry.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error saving enrichment cache: %w", err)
+	}
+	return nil
+}
+
+// PurgeNameEnrichmentCache deletes cached entries and returns how many were removed.
+// When expiredOnly is set, entries that are still fresh are kept.
+func PurgeNameEnrichmentCache(db *sql.DB, ctx context.Context, expiredOnly bool) (int64, error) {
+	query := "DELETE FROM name_enrichment_cache"
+	if expiredOnly {
+		query += " WHERE expires_at <= now()"
+	}
+
+	res, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("error purging enrichment cache: %w", err)
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error purging enrichment cache: %w", err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"testing"
+	"time"
+)
+
+func TestGetNameEnrichmentCache(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("Hit", func(t *testing.T) {
+		fetchedAt := time.Now()
+		rows := sqlmock.NewRows([]string{"name", "age", "gender", "nationality", "fetched_at", "expires_at"}).
+			AddRow("john", []byte(`35`), []byte(`"male"`), []byte(`"US"`), fetchedAt, fetchedAt.Add(time.Hour))
+
+		mock.ExpectQuery("^SELECT name, age, gender, nationality, fetched_at, expires_at FROM name_enrichment_cache WHERE name = \\$1 AND expires_at > now\\(\\)$").
+			WithArgs("john").
+			WillReturnRows(rows)
+
+		entry, found, err := GetNameEnrichmentCache(db, ctx, "john")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !found {
+			t.Fatalf("Expected cache hit")
+		}
+		if string(entry.Age) != `35` || string(entry.Gender) != `"male"` || string(entry.Nationality) != `"US"` {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+	})
+
+	t.Run("Miss", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT name, age, gender, nationality, fetched_at, expires_at FROM name_enrichment_cache").
+			WithArgs("mary").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "age", "gender", "nationality", "fetched_at", "expires_at"}))
+
+		_, found, err := GetNameEnrichmentCache(db, ctx, "mary")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if found {
+			t.Errorf("Expected cache miss")
+		}
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT name, age, gender, nationality, fetched_at, expires_at FROM name_enrichment_cache").
+			WithArgs("error").
+			WillReturnError(errors.New("database error"))
+
+		_, _, err := GetNameEnrichmentCache(db, ctx, "error")
+		if err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestSaveNameEnrichmentCache(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	fetchedAt := time.Now()
+	entry := NameEnrichmentCache{
+		Name:        "john",
+		Age:         json.RawMessage(`35`),
+		Gender:      json.RawMessage(`"male"`),
+		Nationality: json.RawMessage(`"US"`),
+		FetchedAt:   fetchedAt,
+		ExpiresAt:   fetchedAt.Add(time.Hour),
+	}
+
+	mock.ExpectExec("^INSERT INTO name_enrichment_cache").
+		WithArgs("john", `35`, `"male"`, `"US"`, entry.FetchedAt, entry.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := SaveNameEnrichmentCache(db, context.Background(), entry); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPurgeNameEnrichmentCache(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("All", func(t *testing.T) {
+		mock.ExpectExec("^DELETE FROM name_enrichment_cache$").
+			WillReturnResult(sqlmock.NewResult(0, 5))
+
+		purged, err := PurgeNameEnrichmentCache(db, ctx, false)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if purged != 5 {
+			t.Errorf("Purged = %d, want 5", purged)
+		}
+	})
+
+	t.Run("ExpiredOnly", func(t *testing.T) {
+		mock.ExpectExec("^DELETE FROM name_enrichment_cache WHERE expires_at <= now\\(\\)$").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		purged, err := PurgeNameEnrichmentCache(db, ctx, true)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if purged != 2 {
+			t.Errorf("Purged = %d, want 2", purged)
+		}
+	})
+}
@@ -151,3 +151,18 @@ func CreateGender(db *sql.DB, ctx context.Context, name string) (Gender, error)
 	}
 	return createdGender, nil
 }
+
+// GetOrCreateGender returns the gender with exactly this name, creating it if needed.
+func GetOrCreateGender(db *sql.DB, ctx context.Context, name string) (Gender, error) {
+	var gender Gender
+	err := db.QueryRowContext(ctx,
+		"INSERT INTO genders (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING id, name",
+		name).Scan(
+		&gender.ID,
+		&gender.Name,
+	)
+	if err != nil {
+		return Gender{}, fmt.Errorf("error getting or creating gender: %w", err)
+	}
+	return gender, nil
+}
@@ -254,3 +254,42 @@ func TestDeleteGender(t *testing.T) {
 		}
 	})
 }
+
+func TestGetOrCreateGender(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("Successful", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "male")
+
+		mock.ExpectQuery("^INSERT INTO genders \\(name\\) VALUES \\(\\$1\\) ON CONFLICT \\(name\\) DO UPDATE").
+			WithArgs("male").
+			WillReturnRows(rows)
+
+		result, err := GetOrCreateGender(db, ctx, "male")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := Gender{ID: 3, Name: "male"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		mock.ExpectQuery("^INSERT INTO genders").
+			WithArgs("male").
+			WillReturnError(errors.New("database connection error"))
+
+		_, err := GetOrCreateGender(db, ctx, "male")
+		if err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
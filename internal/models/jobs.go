@@ -0,0 +1,241 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JobStatus is the lifecycle state of a queued job.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+const (
+	// JobKindReEnrich re-runs the enrichment providers for a person.
+	JobKindReEnrich = "re_enrich"
+	// JobKindCreatePerson enriches and stores a person described by the job payload.
+	JobKindCreatePerson = "create_person"
+)
+
+// Job is a unit of background work stored in the jobs table. PersonID is the
+// person the job works on; for create_person jobs it is set once the person exists.
+type Job struct {
+	ID          int64     `json:"id"`
+	Kind        string    `json:"kind"`
+	PersonID    uint      `json:"person_id,omitempty"`
+	Status      JobStatus `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	RunAt       time.Time `json:"run_at"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Payload holds the job input when it is not just a person reference.
+	Payload json.RawMessage `json:"-" swaggerignore:"true"`
+}
+
+// JobResponse need for swagger; Person is set once a finished job produced one.
+type JobResponse struct {
+	Job
+	Person *Person `json:"person,omitempty"`
+}
+
+// LastAttempt reports whether a failure of the current attempt exhausts the job.
+func (j Job) LastAttempt() bool {
+	return j.Attempts >= j.MaxAttempts
+}
+
+const jobColumns = "id, kind, person_id, status, attempts, max_attempts, run_at, last_error, created_at, updated_at, payload"
+
+// scanJob reads a job from a *sql.Row or the current row of *sql.Rows.
+func scanJob(row interface{ Scan(dest ...any) error }) (Job, error) {
+	var (
+		job       Job
+		personID  sql.NullInt64
+		lastError sql.NullString
+		payload   []byte
+	)
+	err := row.Scan(
+		&job.ID,
+		&job.Kind,
+		&personID,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&lastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&payload,
+	)
+	if err != nil {
+		return Job{}, err
+	}
+	job.PersonID = uint(personID.Int64)
+	job.LastError = lastError.String
+	job.Payload = payload
+	return job, nil
+}
+
+// EnqueueJob queues a job of kind for a person. If the person already has an
+// open job of that kind, that job is returned instead of creating another.
+func EnqueueJob(db *sql.DB, ctx context.Context, kind string, personID uint) (Job, error) {
+	job, err := scanJob(db.QueryRowContext(ctx,
+		`INSERT INTO jobs (kind, person_id) VALUES ($1, $2)
+ON CONFLICT (kind, person_id) WHERE status IN ('queued', 'running') DO UPDATE SET updated_at = now()
+RETURNING `+jobColumns,
+		kind, personID))
+	if err != nil {
+		return Job{}, fmt.Errorf("error enqueuing job: %w", err)
+	}
+	return job, nil
+}
+
+// EnqueuePayloadJob queues a job of kind whose input is payload.
+func EnqueuePayloadJob(db *sql.DB, ctx context.Context, kind string, payload json.RawMessage) (Job, error) {
+	job, err := scanJob(db.QueryRowContext(ctx,
+		"INSERT INTO jobs (kind, payload) VALUES ($1, $2) RETURNING "+jobColumns,
+		kind, string(payload)))
+	if err != nil {
+		return Job{}, fmt.Errorf("error enqueuing job: %w", err)
+	}
+	return job, nil
+}
+
+// GetJob returns the job with id.
+func GetJob(db *sql.DB, ctx context.Context, id int64) (Job, bool, error) {
+	job, err := scanJob(db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Job{}, false, nil
+		}
+		return Job{}, false, fmt.Errorf("error reading job: %w", err)
+	}
+	return job, true, nil
+}
+
+// ErrJobPersonSet is returned by CreateJobPerson when an earlier attempt of
+// the job already created its person.
+var ErrJobPersonSet = errors.New("job already created a person")
+
+// setJobPerson records the person a job created as part of tx. The job row
+// lock makes concurrent attempts of the same job wait for each other, so only
+// the first one records a person.
+func setJobPerson(ctx context.Context, tx *sql.Tx, id int64, personID uint) error {
+	res, err := tx.ExecContext(ctx,
+		"UPDATE jobs SET person_id = $1, updated_at = now() WHERE id = $2 AND person_id IS NULL",
+		personID, id)
+	if err != nil {
+		return fmt.Errorf("error updating job: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if count == 0 {
+		return ErrJobPersonSet
+	}
+	return nil
+}
+
+// EnqueueReEnrichmentJobs queues a re-enrichment job for every person with a
+// pending field, a field that failed before failedBefore, or an enrichment
+// older than staleBefore. Persons whose fields were all set by a client have
+// nothing to refresh and are not considered stale. It returns the number of
+// jobs created.
+func EnqueueReEnrichmentJobs(db *sql.DB, ctx context.Context, staleBefore, failedBefore time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx,
+		`INSERT INTO jobs (kind, person_id)
+SELECT $1, p.id FROM persons p
+WHERE 'pending' IN (p.age_status, p.gender_status, p.nationality_status)
+OR ('failed' IN (p.age_status, p.gender_status, p.nationality_status) AND p.enriched_at < $2)
+OR (p.enriched_at < $3 AND (p.age_source IS DISTINCT FROM $4 OR p.gender_source IS DISTINCT FROM $4 OR p.nationality_source IS DISTINCT FROM $4))
+ON CONFLICT (kind, person_id) WHERE status IN ('queued', 'running') DO NOTHING`,
+		JobKindReEnrich, failedBefore, staleBefore, SourceClient)
+	if err != nil {
+		return 0, fmt.Errorf("error enqueuing re-enrichment jobs: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting affected rows: %w", err)
+	}
+	return count, nil
+}
+
+// ClaimJobs locks up to limit due jobs and marks them running. Jobs left
+// running for longer than lockTimeout are assumed abandoned by a crashed worker
+// and are claimed again. SKIP LOCKED lets several replicas claim concurrently.
+func ClaimJobs(db *sql.DB, ctx context.Context, lockTimeout time.Duration, limit int) ([]Job, error) {
+	rows, err := db.QueryContext(ctx,
+		`UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_at = now(), updated_at = now()
+WHERE id IN (
+SELECT id FROM jobs
+WHERE (status = 'queued' AND run_at <= now()) OR (status = 'running' AND locked_at < $1)
+ORDER BY run_at, id
+FOR UPDATE SKIP LOCKED
+LIMIT $2)
+RETURNING `+jobColumns,
+		time.Now().Add(-lockTimeout), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// CompleteJob marks a job done.
+func CompleteJob(db *sql.DB, ctx context.Context, id int64) error {
+	return finishJob(db, ctx, id, JobDone, time.Now(), "")
+}
+
+// RetryJob puts a job back in the queue to run again at runAt.
+func RetryJob(db *sql.DB, ctx context.Context, id int64, runAt time.Time, lastError string) error {
+	return finishJob(db, ctx, id, JobQueued, runAt, lastError)
+}
+
+// FailJob marks a job failed for good.
+func FailJob(db *sql.DB, ctx context.Context, id int64, lastError string) error {
+	return finishJob(db, ctx, id, JobFailed, time.Now(), lastError)
+}
+
+func finishJob(db *sql.DB, ctx context.Context, id int64, status JobStatus, runAt time.Time, lastError string) error {
+	res, err := db.ExecContext(ctx,
+		"UPDATE jobs SET status = $1, run_at = $2, last_error = NULLIF($3, ''), locked_at = NULL, updated_at = now() WHERE id = $4",
+		status, runAt, lastError, id)
+	if err != nil {
+		return fmt.Errorf("error updating job: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("job with id=%d not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var jobColumnNames = []string{"id", "kind", "person_id", "status", "attempts", "max_attempts", "run_at", "last_error", "created_at", "updated_at", "payload"}
+
+func TestEnqueueJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Queued", func(t *testing.T) {
+		rows := sqlmock.NewRows(jobColumnNames).
+			AddRow(1, JobKindReEnrich, 7, JobQueued, 0, 5, now, nil, now, now, nil)
+		mock.ExpectQuery("^INSERT INTO jobs \\(kind, person_id\\) VALUES \\(\\$1, \\$2\\)\\s+ON CONFLICT \\(kind, person_id\\) WHERE status IN \\('queued', 'running'\\) DO UPDATE").
+			WithArgs(JobKindReEnrich, uint(7)).
+			WillReturnRows(rows)
+
+		job, err := EnqueueJob(db, ctx, JobKindReEnrich, 7)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := Job{ID: 1, Kind: JobKindReEnrich, PersonID: 7, Status: JobQueued, MaxAttempts: 5, RunAt: now, CreatedAt: now, UpdatedAt: now}
+		if !reflect.DeepEqual(job, expected) {
+			t.Errorf("Job = %+v, want %+v", job, expected)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery("^INSERT INTO jobs").
+			WithArgs(JobKindReEnrich, uint(8)).
+			WillReturnError(errors.New("database error"))
+
+		if _, err := EnqueueJob(db, ctx, JobKindReEnrich, 8); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestClaimJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Claimed", func(t *testing.T) {
+		rows := sqlmock.NewRows(jobColumnNames).
+			AddRow(3, JobKindReEnrich, 9, JobRunning, 2, 5, now, "timeout", now, now, nil).
+			AddRow(4, JobKindReEnrich, 10, JobRunning, 1, 5, now, nil, now, now, nil)
+		mock.ExpectQuery("^UPDATE jobs SET status = 'running', attempts = attempts \\+ 1.*FOR UPDATE SKIP LOCKED\\s+LIMIT \\$2").
+			WithArgs(sqlmock.AnyArg(), 10).
+			WillReturnRows(rows)
+
+		jobs, err := ClaimJobs(db, ctx, time.Minute, 10)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(jobs) != 2 {
+			t.Fatalf("Claimed %d jobs, want 2", len(jobs))
+		}
+		if jobs[0].ID != 3 || jobs[0].Attempts != 2 || jobs[0].LastError != "timeout" || jobs[0].LastAttempt() {
+			t.Errorf("Unexpected job: %+v", jobs[0])
+		}
+		if jobs[1].PersonID != 10 {
+			t.Errorf("Unexpected job: %+v", jobs[1])
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		mock.ExpectQuery("^UPDATE jobs SET status = 'running'").
+			WithArgs(sqlmock.AnyArg(), 10).
+			WillReturnRows(sqlmock.NewRows(jobColumnNames))
+
+		jobs, err := ClaimJobs(db, ctx, time.Minute, 10)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if len(jobs) != 0 {
+			t.Errorf("Expected no jobs, got %d", len(jobs))
+		}
+	})
+}
+
+func TestRetryJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	runAt := time.Now().Add(time.Minute)
+
+	t.Run("Retried", func(t *testing.T) {
+		mock.ExpectExec("^UPDATE jobs SET status = \\$1, run_at = \\$2, last_error = NULLIF\\(\\$3, ''\\), locked_at = NULL, updated_at = now\\(\\) WHERE id = \\$4$").
+			WithArgs(JobQueued, runAt, "provider down", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if err := RetryJob(db, ctx, 3, runAt, "provider down"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(JobQueued, runAt, "provider down", int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		if err := RetryJob(db, ctx, 4, runAt, "provider down"); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestEnqueueReEnrichmentJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	staleBefore := time.Now().Add(-24 * time.Hour)
+	failedBefore := time.Now().Add(-time.Hour)
+	mock.ExpectExec("^INSERT INTO jobs \\(kind, person_id\\)\\s+SELECT \\$1, p.id FROM persons p.*p.enriched_at < \\$3 AND \\(p.age_source IS DISTINCT FROM \\$4").
+		WithArgs(JobKindReEnrich, failedBefore, staleBefore, SourceClient).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	count, err := EnqueueReEnrichmentJobs(db, context.Background(), staleBefore, failedBefore)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("count = %d, want 3", count)
+	}
+}
+
+func TestEnqueuePayloadJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	payload := `{"person":{"name":"John","surname":"Doe"},"partial":false}`
+	rows := sqlmock.NewRows(jobColumnNames).
+		AddRow(5, JobKindCreatePerson, nil, JobQueued, 0, 5, now, nil, now, now, []byte(payload))
+	mock.ExpectQuery("^INSERT INTO jobs \\(kind, payload\\) VALUES \\(\\$1, \\$2\\) RETURNING").
+		WithArgs(JobKindCreatePerson, payload).
+		WillReturnRows(rows)
+
+	job, err := EnqueuePayloadJob(db, context.Background(), JobKindCreatePerson, []byte(payload))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if job.ID != 5 || job.PersonID != 0 || string(job.Payload) != payload {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+}
+
+func TestGetJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Found", func(t *testing.T) {
+		rows := sqlmock.NewRows(jobColumnNames).
+			AddRow(5, JobKindCreatePerson, 12, JobDone, 1, 5, now, nil, now, now, []byte(`{}`))
+		mock.ExpectQuery("^SELECT id, kind, person_id, status, attempts, max_attempts, run_at, last_error, created_at, updated_at, payload FROM jobs WHERE id = \\$1$").
+			WithArgs(int64(5)).
+			WillReturnRows(rows)
+
+		job, found, err := GetJob(db, ctx, 5)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !found || job.Status != JobDone || job.PersonID != 12 {
+			t.Errorf("Unexpected job: %+v (found %v)", job, found)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT .* FROM jobs WHERE id = \\$1$").
+			WithArgs(int64(6)).
+			WillReturnRows(sqlmock.NewRows(jobColumnNames))
+
+		_, found, err := GetJob(db, ctx, 6)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if found {
+			t.Errorf("Expected job not to be found")
+		}
+	})
+}
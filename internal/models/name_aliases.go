@@ -0,0 +1,234 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// NameAlias maps a variant of a given name, such as the diminutive "Sasha",
+// to its canonical form "Aleksandr". Aliases are resolved in a single step:
+// the canonical form of an alias is not itself looked up.
+type NameAlias struct {
+	ID        int    `json:"id"`
+	Alias     string `json:"alias" example:"Sasha"`
+	Canonical string `json:"canonical" example:"Aleksandr"`
+}
+
+// ErrInvalidNameAlias is returned when an alias or its canonical name is
+// blank, or when both are the same name.
+var ErrInvalidNameAlias = errors.New("invalid name alias")
+
+type NameAliasCreateRequest struct {
+	Alias     string `json:"alias" example:"Sasha"`
+	Canonical string `json:"canonical" example:"Aleksandr"`
+}
+
+type NameAliasFilter struct {
+	ID        int    `json:"id,omitempty"`
+	Alias     string `json:"alias,omitempty"`
+	Canonical string `json:"canonical,omitempty"`
+	Page      int    `json:"page,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+type PatchNameAlias struct {
+	Alias     *string `json:"alias,omitempty"`
+	Canonical *string `json:"canonical,omitempty"`
+}
+
+func GetNameAliases(db *sql.DB, ctx context.Context, filter NameAliasFilter) ([]NameAlias, error) {
+	var aliases []NameAlias
+	query := "SELECT id, alias, canonical FROM name_aliases WHERE 1=1"
+
+	var args []interface{}
+	var conditions []string
+	paramCounter := 1
+
+	if filter.ID > 0 {
+		conditions = append(conditions, fmt.Sprintf("id = $%d", paramCounter))
+		args = append(args, filter.ID)
+		paramCounter++
+	}
+	if key := NameKey(filter.Alias); key != "" {
+		conditions = append(conditions, fmt.Sprintf("alias_key LIKE $%d", paramCounter))
+		args = append(args, likePattern(key))
+		paramCounter++
+	}
+	if key := NameKey(filter.Canonical); key != "" {
+		conditions = append(conditions, fmt.Sprintf("canonical_key = $%d", paramCounter))
+		args = append(args, key)
+		paramCounter++
+	}
+
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	query += " ORDER BY id"
+
+	if filter.Page > 0 && filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramCounter)
+		args = append(args, filter.Limit)
+		paramCounter++
+		query += fmt.Sprintf(" OFFSET $%d", paramCounter)
+		args = append(args, (filter.Page-1)*filter.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var alias NameAlias
+		if err = rows.Scan(&alias.ID, &alias.Alias, &alias.Canonical); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		aliases = append(aliases, alias)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through results: %w", err)
+	}
+
+	return aliases, nil
+}
+
+// validateNameAlias checks an alias as it is about to be stored.
+func validateNameAlias(alias, canonical string) error {
+	aliasKey, canonicalKey := NameKey(alias), NameKey(canonical)
+	if aliasKey == "" || canonicalKey == "" {
+		return fmt.Errorf("%w: alias and canonical name must not be blank", ErrInvalidNameAlias)
+	}
+	if aliasKey == canonicalKey {
+		return fmt.Errorf("%w: alias and canonical name must differ", ErrInvalidNameAlias)
+	}
+	return nil
+}
+
+func CreateNameAlias(db *sql.DB, ctx context.Context, request NameAliasCreateRequest) (NameAlias, error) {
+	alias, canonical := CleanName(request.Alias), CleanName(request.Canonical)
+	if err := validateNameAlias(alias, canonical); err != nil {
+		return NameAlias{}, err
+	}
+	var createdAlias NameAlias
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO name_aliases (alias, canonical, alias_key, canonical_key) VALUES ($1, $2, $3, $4)
+RETURNING id, alias, canonical`,
+		alias, canonical, NameKey(alias), NameKey(canonical)).Scan(
+		&createdAlias.ID,
+		&createdAlias.Alias,
+		&createdAlias.Canonical,
+	)
+	if err != nil {
+		return NameAlias{}, fmt.Errorf("error inserting name alias: %w", err)
+	}
+	return createdAlias, nil
+}
+
+func UpdateNameAlias(db *sql.DB, ctx context.Context, id int, patch PatchNameAlias) (NameAlias, error) {
+	var currentAlias NameAlias
+	err := db.QueryRowContext(ctx, "SELECT id, alias, canonical FROM name_aliases WHERE id = $1", id).Scan(
+		&currentAlias.ID,
+		&currentAlias.Alias,
+		&currentAlias.Canonical,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NameAlias{}, fmt.Errorf("record with id=%d not found", id)
+		}
+		return NameAlias{}, fmt.Errorf("error while receiving data: %w", err)
+	}
+
+	merged := currentAlias
+	if patch.Alias != nil {
+		merged.Alias = CleanName(*patch.Alias)
+	}
+	if patch.Canonical != nil {
+		merged.Canonical = CleanName(*patch.Canonical)
+	}
+	if err := validateNameAlias(merged.Alias, merged.Canonical); err != nil {
+		return NameAlias{}, err
+	}
+
+	query := "UPDATE name_aliases SET"
+	var args []interface{}
+	paramCounter := 1
+	needUpdate := false
+
+	if patch.Alias != nil {
+		alias := merged.Alias
+		query += fmt.Sprintf(" alias = $%d, alias_key = $%d,", paramCounter, paramCounter+1)
+		args = append(args, alias, NameKey(alias))
+		paramCounter += 2
+		needUpdate = true
+	}
+
+	if patch.Canonical != nil {
+		canonical := merged.Canonical
+		query += fmt.Sprintf(" canonical = $%d, canonical_key = $%d,", paramCounter, paramCounter+1)
+		args = append(args, canonical, NameKey(canonical))
+		paramCounter += 2
+		needUpdate = true
+	}
+
+	if !needUpdate {
+		return currentAlias, nil
+	}
+
+	query = query[:len(query)-1]
+	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, alias, canonical", paramCounter)
+	args = append(args, id)
+
+	var updatedAlias NameAlias
+	err = db.QueryRowContext(ctx, query, args...).Scan(
+		&updatedAlias.ID,
+		&updatedAlias.Alias,
+		&updatedAlias.Canonical,
+	)
+	if err != nil {
+		return NameAlias{}, fmt.Errorf("error during update: %w", err)
+	}
+
+	return updatedAlias, nil
+}
+
+func DeleteNameAlias(db *sql.DB, ctx context.Context, id int) (NameAlias, error) {
+	var deletedAlias NameAlias
+	err := db.QueryRowContext(ctx, "DELETE FROM name_aliases WHERE id = $1 RETURNING id, alias, canonical", id).Scan(
+		&deletedAlias.ID,
+		&deletedAlias.Alias,
+		&deletedAlias.Canonical,
+	)
+	if err != nil {
+		return NameAlias{}, fmt.Errorf("error deleting name alias: %w", err)
+	}
+	return deletedAlias, nil
+}
+
+// ResolveNameAlias returns the canonical form of name, and false when name
+// is not a known alias.
+func ResolveNameAlias(db *sql.DB, ctx context.Context, name string) (string, bool, error) {
+	var canonical string
+	err := db.QueryRowContext(ctx, "SELECT canonical FROM name_aliases WHERE alias_key = $1", NameKey(name)).Scan(&canonical)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error resolving name alias: %w", err)
+	}
+	return canonical, true, nil
+}
+
+// aliasGroupCondition returns an SQL condition matching the persons whose
+// name_key is $param or belongs to the same alias group: its canonical form
+// and every other alias of that form.
+func aliasGroupCondition(param int) string {
+	return fmt.Sprintf(`p.name_key IN (
+SELECT $%[1]d::text
+UNION SELECT canonical_key FROM name_aliases WHERE alias_key = $%[1]d
+UNION SELECT alias_key FROM name_aliases WHERE canonical_key = $%[1]d
+   OR canonical_key IN (SELECT canonical_key FROM name_aliases WHERE alias_key = $%[1]d))`, param)
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+)
+
+func TestGetNameAliases(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	aliases := []NameAlias{
+		{ID: 1, Alias: "Sasha", Canonical: "Aleksandr"},
+		{ID: 2, Alias: "Alex", Canonical: "Aleksandr"},
+	}
+
+	t.Run("GetAllAliases", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "alias", "canonical"})
+		for _, a := range aliases {
+			rows.AddRow(a.ID, a.Alias, a.Canonical)
+		}
+
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE 1=1 ORDER BY id$").
+			WillReturnRows(rows)
+
+		result, err := GetNameAliases(db, ctx, NameAliasFilter{})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, aliases) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, aliases)
+		}
+	})
+
+	t.Run("FilterByAliasAndCanonical", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "alias", "canonical"}).
+			AddRow(1, "Sasha", "Aleksandr")
+
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE 1=1 AND alias_key LIKE \\$1 AND canonical_key = \\$2 ORDER BY id LIMIT \\$3 OFFSET \\$4$").
+			WithArgs("%sash%", "aleksandr", 10, 0).
+			WillReturnRows(rows)
+
+		result, err := GetNameAliases(db, ctx, NameAliasFilter{Alias: "SASH", Canonical: "Aleksandr", Page: 1, Limit: 10})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := []NameAlias{aliases[0]}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases").
+			WillReturnError(errors.New("database connection error"))
+
+		_, err := GetNameAliases(db, ctx, NameAliasFilter{})
+		if err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestCreateNameAlias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	rows := sqlmock.NewRows([]string{"id", "alias", "canonical"}).AddRow(1, "Sasha", "Aleksandr")
+	mock.ExpectQuery("^INSERT INTO name_aliases").
+		WithArgs("Sasha", "Aleksandr", "sasha", "aleksandr").
+		WillReturnRows(rows)
+
+	result, err := CreateNameAlias(db, ctx, NameAliasCreateRequest{Alias: " Sasha", Canonical: "Aleksandr "})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected := NameAlias{ID: 1, Alias: "Sasha", Canonical: "Aleksandr"}
+	if result != expected {
+		t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+	}
+
+	for _, request := range []NameAliasCreateRequest{
+		{Alias: "  ", Canonical: "Aleksandr"},
+		{Alias: "Sasha", Canonical: "\t"},
+		{Alias: "SASHA", Canonical: "sasha"},
+	} {
+		if _, err := CreateNameAlias(db, ctx, request); !errors.Is(err, ErrInvalidNameAlias) {
+			t.Errorf("CreateNameAlias(%q) error = %v, want ErrInvalidNameAlias", request, err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateNameAlias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("UpdateCanonical", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE id = \\$1$").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "alias", "canonical"}).AddRow(1, "Sasha", "Aleksandr"))
+		mock.ExpectQuery("^UPDATE name_aliases SET canonical = \\$1, canonical_key = \\$2 WHERE id = \\$3 RETURNING id, alias, canonical$").
+			WithArgs("Alexander", "alexander", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "alias", "canonical"}).AddRow(1, "Sasha", "Alexander"))
+
+		canonical := "Alexander"
+		result, err := UpdateNameAlias(db, ctx, 1, PatchNameAlias{Canonical: &canonical})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := NameAlias{ID: 1, Alias: "Sasha", Canonical: "Alexander"}
+		if result != expected {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
+	t.Run("SameAsCanonical", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE id = \\$1$").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "alias", "canonical"}).AddRow(1, "Sasha", "Aleksandr"))
+
+		alias := "aleksandr"
+		if _, err := UpdateNameAlias(db, ctx, 1, PatchNameAlias{Alias: &alias}); !errors.Is(err, ErrInvalidNameAlias) {
+			t.Errorf("Expected ErrInvalidNameAlias, got %v", err)
+		}
+	})
+
+	t.Run("BlankCanonical", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE id = \\$1$").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "alias", "canonical"}).AddRow(1, "Sasha", "Aleksandr"))
+
+		canonical := " "
+		if _, err := UpdateNameAlias(db, ctx, 1, PatchNameAlias{Canonical: &canonical}); !errors.Is(err, ErrInvalidNameAlias) {
+			t.Errorf("Expected ErrInvalidNameAlias, got %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT id, alias, canonical FROM name_aliases WHERE id = \\$1$").
+			WithArgs(2).
+			WillReturnError(sql.ErrNoRows)
+
+		alias := "Shura"
+		if _, err := UpdateNameAlias(db, ctx, 2, PatchNameAlias{Alias: &alias}); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDeleteNameAlias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("^DELETE FROM name_aliases WHERE id = \\$1 RETURNING id, alias, canonical$").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "alias", "canonical"}).AddRow(1, "Sasha", "Aleksandr"))
+
+	result, err := DeleteNameAlias(db, context.Background(), 1)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if result.ID != 1 {
+		t.Errorf("Deleted alias %v, want ID 1", result)
+	}
+}
+
+func TestResolveNameAlias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("Found", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT canonical FROM name_aliases WHERE alias_key = \\$1$").
+			WithArgs("sasha").
+			WillReturnRows(sqlmock.NewRows([]string{"canonical"}).AddRow("Aleksandr"))
+
+		canonical, found, err := ResolveNameAlias(db, ctx, "SASHA")
+		if err != nil || !found || canonical != "Aleksandr" {
+			t.Errorf("ResolveNameAlias() = %q, %t, %v, want Aleksandr", canonical, found, err)
+		}
+	})
+
+	t.Run("NotAnAlias", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT canonical FROM name_aliases WHERE alias_key = \\$1$").
+			WithArgs("john").
+			WillReturnError(sql.ErrNoRows)
+
+		_, found, err := ResolveNameAlias(db, ctx, "John")
+		if err != nil || found {
+			t.Errorf("ResolveNameAlias() found = %t, err = %v, want not found", found, err)
+		}
+	})
+}
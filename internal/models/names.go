@@ -0,0 +1,122 @@
+package models
+
+import (
+	"NameEnricher/internal/translit"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// CleanName returns name in Unicode NFC with surrounding whitespace trimmed
+// and inner runs of whitespace collapsed to a single space, so that the same
+// name typed on different keyboards is stored and sent to the providers alike.
+func CleanName(name string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(name)), " ")
+}
+
+// NameKey returns the case-folded form of CleanName(name). Names are compared
+// by key: the enrichment cache is keyed by it and persons are searched by the
+// name_key and surname_key columns.
+func NameKey(name string) string {
+	return norm.NFC.String(cases.Fold().String(CleanName(name)))
+}
+
+// StripDiacritics removes the accents of Latin letters, so that "José" becomes
+// "Jose". Letters that are not decomposable, such as "ł" or "ø", are kept, and
+// so are the marks of other scripts: "й" is a letter of its own, not an
+// accented "и".
+func StripDiacritics(name string) string {
+	var b strings.Builder
+	latin := false
+	for _, r := range norm.NFD.String(name) {
+		if unicode.Is(unicode.Mn, r) {
+			if latin {
+				continue
+			}
+		} else {
+			latin = unicode.Is(unicode.Latin, r)
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// variantSeparator separates the keys stored in the name_variants and
+// surname_variants columns. Keys never contain it, see CleanName.
+const variantSeparator = "\n"
+
+// variantKeys returns the key of name followed by the keys of its
+// transliterations, see translit.Variants.
+func variantKeys(name string) []string {
+	keys := []string{NameKey(name)}
+	for _, variant := range translit.Variants(CleanName(name)) {
+		if key := NameKey(variant); !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// nameVariants returns the value of the name_variants or surname_variants
+// column for name.
+func nameVariants(name string) string {
+	return strings.Join(variantKeys(name), variantSeparator)
+}
+
+// FillPersonNames computes the name keys and name variants of the persons
+// stored before they were introduced, in batches of batchSize. Each person is
+// read and updated once for all four columns. It returns the number of persons
+// updated.
+func FillPersonNames(db *sql.DB, ctx context.Context, batchSize int) (int, error) {
+	filled := 0
+	for {
+		rows, err := db.QueryContext(ctx,
+			`SELECT id, name, surname FROM persons
+			WHERE name_key IS NULL OR surname_key IS NULL OR name_variants IS NULL OR surname_variants IS NULL
+			ORDER BY id LIMIT $1`,
+			batchSize)
+		if err != nil {
+			return filled, fmt.Errorf("error selecting persons without name keys: %w", err)
+		}
+
+		var persons []Person
+		for rows.Next() {
+			var person Person
+			if err := rows.Scan(&person.ID, &person.Name, &person.Surname); err != nil {
+				rows.Close()
+				return filled, fmt.Errorf("error scanning person: %w", err)
+			}
+			persons = append(persons, person)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return filled, fmt.Errorf("error iterating through persons: %w", err)
+		}
+
+		for _, person := range persons {
+			if _, err := db.ExecContext(ctx,
+				"UPDATE persons SET name_key = $1, surname_key = $2, name_variants = $3, surname_variants = $4 WHERE id = $5",
+				NameKey(person.Name), NameKey(person.Surname), nameVariants(person.Name), nameVariants(person.Surname),
+				person.ID); err != nil {
+				return filled, fmt.Errorf("error storing name keys of person %d: %w", person.ID, err)
+			}
+			filled++
+		}
+		if len(persons) < batchSize {
+			return filled, nil
+		}
+	}
+}
+
+// likePattern returns a LIKE pattern matching values that contain key, with
+// the LIKE wildcards in key escaped.
+func likePattern(key string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(key) + "%"
+}
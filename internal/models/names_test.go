@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+)
+
+func TestNameKey(t *testing.T) {
+	tests := map[string]string{
+		"  John   Paul ": "john paul",
+		"JOSÉ":          "josé",
+		"Jose\u0301":     "josé",
+		"Straße":         "strasse",
+		"АННА":           "анна",
+		"":               "",
+	}
+
+	for name, want := range tests {
+		if got := NameKey(name); got != want {
+			t.Errorf("NameKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestStripDiacritics(t *testing.T) {
+	tests := map[string]string{
+		"José":       "Jose",
+		"Zoë Müller": "Zoe Muller",
+		"Łukasz":     "Łukasz",
+		"Андрей":     "Андрей",
+		"Ёлкин":      "Ёлкин",
+	}
+
+	for name, want := range tests {
+		if got := StripDiacritics(name); got != want {
+			t.Errorf("StripDiacritics(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	if got, want := likePattern(`50%_off\`), `%50\%\_off\\%`; got != want {
+		t.Errorf("likePattern() = %q, want %q", got, want)
+	}
+}
+
+func TestVariantKeys(t *testing.T) {
+	if got, want := variantKeys("Юлия"), []string{"юлия", "yuliya", "iuliia", "ûliâ"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("variantKeys() = %q, want %q", got, want)
+	}
+	if got, want := variantKeys("Julia"), []string{"julia"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("variantKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestFillPersonNames(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("^SELECT id, name, surname FROM persons\\s+WHERE name_key IS NULL OR surname_key IS NULL OR name_variants IS NULL OR surname_variants IS NULL").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "surname"}).
+			AddRow(1, "JOSE\u0301", " Straße ").
+			AddRow(2, "Юлия", "Ким"))
+	mock.ExpectExec("^UPDATE persons SET name_key = \\$1, surname_key = \\$2, name_variants = \\$3, surname_variants = \\$4 WHERE id = \\$5$").
+		WithArgs("josé", "strasse", "josé", "strasse", uint(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("^UPDATE persons SET name_key").
+		WithArgs("юлия", "ким", "юлия\nyuliya\niuliia\nûliâ", "ким\nkim", uint(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("^SELECT id, name, surname FROM persons").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "surname"}))
+
+	filled, err := FillPersonNames(db, context.Background(), 2)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if filled != 2 {
+		t.Errorf("Filled %d persons, want 2", filled)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -151,3 +151,18 @@ func CreateNationality(db *sql.DB, ctx context.Context, name string) (Nationalit
 	}
 	return createdNationality, nil
 }
+
+// GetOrCreateNationality returns the nationality with exactly this name, creating it if needed.
+func GetOrCreateNationality(db *sql.DB, ctx context.Context, name string) (Nationality, error) {
+	var nationality Nationality
+	err := db.QueryRowContext(ctx,
+		"INSERT INTO nationalities (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING id, name",
+		name).Scan(
+		&nationality.ID,
+		&nationality.Name,
+	)
+	if err != nil {
+		return Nationality{}, fmt.Errorf("error getting or creating nationality: %w", err)
+	}
+	return nationality, nil
+}
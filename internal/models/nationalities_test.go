@@ -273,3 +273,42 @@ func TestDeleteNationality(t *testing.T) {
 		}
 	})
 }
+
+func TestGetOrCreateNationality(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("Successful", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "KZ")
+
+		mock.ExpectQuery("^INSERT INTO nationalities \\(name\\) VALUES \\(\\$1\\) ON CONFLICT \\(name\\) DO UPDATE").
+			WithArgs("KZ").
+			WillReturnRows(rows)
+
+		result, err := GetOrCreateNationality(db, ctx, "KZ")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := Nationality{ID: 3, Name: "KZ"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		mock.ExpectQuery("^INSERT INTO nationalities").
+			WithArgs("KZ").
+			WillReturnError(errors.New("database connection error"))
+
+		_, err := GetOrCreateNationality(db, ctx, "KZ")
+		if err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// NationalityCandidate is one country from the ranked nationality distribution of a person.
+type NationalityCandidate struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+	Rank        int     `json:"rank"`
+}
+
+// GetPersonNationalityCandidates returns the candidates of a person ordered by rank.
+func GetPersonNationalityCandidates(db *sql.DB, ctx context.Context, personID uint) ([]NationalityCandidate, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT country_id, probability, rank FROM person_nationality_candidates WHERE person_id = $1 ORDER BY rank",
+		personID)
+	if err != nil {
+		return nil, fmt.Errorf("query execution error: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []NationalityCandidate{}
+	for rows.Next() {
+		var candidate NationalityCandidate
+		if err = rows.Scan(&candidate.CountryID, &candidate.Probability, &candidate.Rank); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through results: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// replacePersonNationalityCandidates stores candidates for a person inside tx,
+// removing whatever was stored before. Ranks are assigned from the slice order.
+func replacePersonNationalityCandidates(ctx context.Context, tx *sql.Tx, personID uint, candidates []NationalityCandidate) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM person_nationality_candidates WHERE person_id = $1", personID); err != nil {
+		return fmt.Errorf("error deleting nationality candidates: %w", err)
+	}
+
+	for i, candidate := range candidates {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO person_nationality_candidates (person_id, country_id, probability, rank) VALUES ($1, $2, $3, $4)",
+			personID, candidate.CountryID, candidate.Probability, i+1)
+		if err != nil {
+			return fmt.Errorf("error inserting nationality candidate: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReplacePersonNationalityCandidates stores a fresh ranked distribution for a person.
+func ReplacePersonNationalityCandidates(db *sql.DB, ctx context.Context, personID uint, candidates []NationalityCandidate) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = replacePersonNationalityCandidates(ctx, tx, personID, candidates); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing nationality candidates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+)
+
+func TestGetPersonNationalityCandidates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("RankedCandidates", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"country_id", "probability", "rank"}).
+			AddRow("RU", 0.7, 1).
+			AddRow("BG", 0.2, 2)
+
+		mock.ExpectQuery("^SELECT country_id, probability, rank FROM person_nationality_candidates WHERE person_id = \\$1 ORDER BY rank$").
+			WithArgs(uint(1)).
+			WillReturnRows(rows)
+
+		result, err := GetPersonNationalityCandidates(db, ctx, 1)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := []NationalityCandidate{
+			{CountryID: "RU", Probability: 0.7, Rank: 1},
+			{CountryID: "BG", Probability: 0.2, Rank: 2},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
+	t.Run("NoCandidates", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT country_id, probability, rank FROM person_nationality_candidates").
+			WithArgs(uint(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"country_id", "probability", "rank"}))
+
+		result, err := GetPersonNationalityCandidates(db, ctx, 2)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if result == nil || len(result) != 0 {
+			t.Errorf("Expected empty list, got %v", result)
+		}
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT country_id, probability, rank FROM person_nationality_candidates").
+			WithArgs(uint(3)).
+			WillReturnError(errors.New("database connection error"))
+
+		_, err := GetPersonNationalityCandidates(db, ctx, 3)
+		if err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
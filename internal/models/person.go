@@ -5,28 +5,120 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
+// EnrichmentStatus describes the outcome of enriching a single person field.
+type EnrichmentStatus string
+
+const (
+	// EnrichmentOK means the field holds a value predicted by a provider or set by a client.
+	EnrichmentOK EnrichmentStatus = "ok"
+	// EnrichmentUnknown means the provider answered but had no prediction, the field is null.
+	EnrichmentUnknown EnrichmentStatus = "unknown"
+	// EnrichmentPending means the provider could not be reached yet, the field is null
+	// until a retry completes it.
+	EnrichmentPending EnrichmentStatus = "pending"
+	// EnrichmentFailed means the provider could not be reached and no further retry
+	// is planned, the field is null.
+	EnrichmentFailed EnrichmentStatus = "failed"
+)
+
+// PersonEnrichment holds the enrichment status of every enriched person field.
+type PersonEnrichment struct {
+	Age         EnrichmentStatus `json:"age"`
+	Gender      EnrichmentStatus `json:"gender"`
+	Nationality EnrichmentStatus `json:"nationality"`
+}
+
+// HasPending reports whether any field still waits for a retry.
+func (e PersonEnrichment) HasPending() bool {
+	return e.Age == EnrichmentPending || e.Gender == EnrichmentPending || e.Nationality == EnrichmentPending
+}
+
+// SourceClient is the enrichment source of values set by a client rather than
+// predicted by a provider.
+const SourceClient = "client"
+
+// PersonEnrichmentSource names the provider that predicted each enriched
+// person field, prefixed with "cache:" when its answer came from the
+// enrichment cache, or SourceClient. It is empty for fields without a value.
+type PersonEnrichmentSource struct {
+	Age         string `json:"age,omitempty"`
+	Gender      string `json:"gender,omitempty"`
+	Nationality string `json:"nationality,omitempty"`
+}
+
+// sourceOf returns SourceClient when a value is present and "" otherwise.
+func sourceOf(present bool) string {
+	if present {
+		return SourceClient
+	}
+	return ""
+}
+
+// Person is a stored person. Age, Gender and Nationality are nil when they
+// could not be enriched; EnrichmentStatus tells why.
 type Person struct {
-	ID          uint        `json:"id"`
-	Name        string      `json:"name"`
-	Surname     string      `json:"surname"`
-	Patronymic  string      `json:"patronymic,omitempty"`
-	Age         int         `json:"age"`
-	Gender      Gender      `json:"gender"`
-	Nationality Nationality `json:"nationality"`
+	ID                     uint             `json:"id"`
+	Name                   string           `json:"name"`
+	Surname                string           `json:"surname"`
+	Patronymic             string           `json:"patronymic,omitempty"`
+	Age                    *int             `json:"age"`
+	AgeCount               int              `json:"age_count"`
+	Gender                 *Gender          `json:"gender"`
+	GenderProbability      float64          `json:"gender_probability"`
+	Nationality            *Nationality     `json:"nationality"`
+	NationalityProbability float64          `json:"nationality_probability"`
+	EnrichmentStatus       PersonEnrichment `json:"enrichment_status"`
+	// EnrichmentSource audits where every enriched value came from.
+	EnrichmentSource PersonEnrichmentSource `json:"enrichment_source"`
+	// CountryHint is the country the age and gender were localized to, as
+	// given when the person was created.
+	CountryHint string `json:"country_hint,omitempty"`
+	// NationalityCandidates is only loaded on request, see GetPersonNationalityCandidates.
+	NationalityCandidates []NationalityCandidate `json:"nationality_candidates,omitempty"`
+	// ProviderPredictions is only loaded on request, see GetPersonProviderPredictions.
+	ProviderPredictions []ProviderPrediction `json:"provider_predictions,omitempty"`
+	// ParsedName is only set in creation responses for persons given as a full name.
+	ParsedName *ParsedName `json:"parsed_name,omitempty"`
+}
+
+// genderID returns the gender reference of p, or nil when the gender is unknown.
+func (p Person) genderID() *int {
+	if p.Gender == nil {
+		return nil
+	}
+	return &p.Gender.ID
+}
+
+// nationalityID returns the nationality reference of p, or nil when the nationality is unknown.
+func (p Person) nationalityID() *int {
+	if p.Nationality == nil {
+		return nil
+	}
+	return &p.Nationality.ID
 }
 
 type PersonFilter struct {
-	ID            uint
-	Name          string
-	Surname       string
-	AgeFrom       int
-	AgeTo         int
-	GenderID      int
-	NationalityID int
-	Page          int
-	Limit         int
+	ID                        uint
+	Name                      string
+	Surname                   string
+	AgeFrom                   int
+	AgeTo                     int
+	GenderID                  int
+	NationalityID             int
+	MinGenderProbability      float64
+	MinNationalityProbability float64
+	Page                      int
+	Limit                     int
+	// MatchTransliterations makes Name and Surname match any transliteration,
+	// so that "Dmitriy" finds "Дмитрий" and the other way round.
+	MatchTransliterations bool
+	// ExpandAliases makes Name also match the names of its alias group, so
+	// that "Sasha" finds "Aleksandr" and "Alex", see NameAlias.
+	ExpandAliases bool
 }
 
 type PersonPatch struct {
@@ -36,6 +128,20 @@ type PersonPatch struct {
 	Age           *int    `json:"age,omitempty"`
 	GenderID      *int    `json:"gender_id,omitempty"`
 	NationalityID *int    `json:"nationality_id,omitempty"`
+
+	// The fields below are set by the re-enrichment worker and cannot be sent by clients.
+	AgeCount               *int              `json:"-" swaggerignore:"true"`
+	AgeStatus              *EnrichmentStatus `json:"-" swaggerignore:"true"`
+	GenderProbability      *float64          `json:"-" swaggerignore:"true"`
+	GenderStatus           *EnrichmentStatus `json:"-" swaggerignore:"true"`
+	NationalityProbability *float64          `json:"-" swaggerignore:"true"`
+	NationalityStatus      *EnrichmentStatus `json:"-" swaggerignore:"true"`
+	EnrichedAt             *time.Time        `json:"-" swaggerignore:"true"`
+	// AgeSource, GenderSource and NationalitySource name the provider that
+	// answered; an empty string clears the source of a field without a value.
+	AgeSource         *string `json:"-" swaggerignore:"true"`
+	GenderSource      *string `json:"-" swaggerignore:"true"`
+	NationalitySource *string `json:"-" swaggerignore:"true"`
 }
 
 // PersonCreateRequest need for swagger
@@ -43,18 +149,45 @@ type PersonCreateRequest struct {
 	Name       string `json:"name"`
 	Surname    string `json:"surname"`
 	Patronymic string `json:"patronymic,omitempty"`
+	// CountryHint is an ISO 3166-1 alpha-2 code used to localize the age and gender predictions.
+	CountryHint string `json:"country_hint,omitempty" example:"US"`
+	// FullName replaces name, surname and patronymic, which are parsed from it.
+	FullName string `json:"full_name,omitempty" example:"Ivanov Ivan Petrovich"`
+
+	// ParsedName is set when the other fields were parsed from FullName.
+	ParsedName *ParsedName `json:"-" swaggerignore:"true"`
 }
 
-func GetPersons(ctx context.Context, db *sql.DB, filter PersonFilter) ([]Person, error) {
-	var persons []Person
+// PersonParseRequest need for swagger
+type PersonParseRequest struct {
+	FullName string `json:"full_name" example:"Ivanov Ivan Petrovich"`
+}
 
-	query := `SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
+// ParsedName is a full name split into its parts. Order is "name_first" or
+// "surname_first" and Confidence, between 0 and 1, tells how sure the parser
+// is about it.
+type ParsedName struct {
+	Name       string  `json:"name"`
+	Surname    string  `json:"surname"`
+	Patronymic string  `json:"patronymic,omitempty"`
+	Order      string  `json:"order" example:"surname_first"`
+	Confidence float64 `json:"confidence" example:"0.95"`
+}
+
+const selectPersons = `SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.age_count, p.gender_id, g.name as gender_name,
+p.gender_probability, p.nationality_id, n.name as nationality_name, p.nationality_probability,
+p.age_status, p.gender_status, p.nationality_status, p.country_hint,
+p.age_source, p.gender_source, p.nationality_source
 FROM persons p
 LEFT JOIN nationalities n ON n.id = p.nationality_id
 LEFT JOIN genders g ON g.id = p.gender_id
 WHERE 1=1`
 
+func GetPersons(ctx context.Context, db *sql.DB, filter PersonFilter) ([]Person, error) {
+	var persons []Person
+
+	query := selectPersons
+
 	var args []interface{}
 	var conditions []string
 
@@ -66,16 +199,42 @@ WHERE 1=1`
 		paramCounter++
 	}
 
-	if filter.Name != "" {
-		conditions = append(conditions, fmt.Sprintf("p.name ILIKE $%d", paramCounter))
-		args = append(args, "%"+filter.Name+"%")
-		paramCounter++
+	// Names are matched by key, so neither case nor Unicode composition matters.
+	nameConditions := []struct {
+		value, keyColumn, variantsColumn string
+		expandAliases                    bool
+	}{
+		{filter.Name, "p.name_key", "p.name_variants", filter.ExpandAliases},
+		{filter.Surname, "p.surname_key", "p.surname_variants", false},
 	}
+	for _, c := range nameConditions {
+		if NameKey(c.value) == "" {
+			continue
+		}
 
-	if filter.Surname != "" {
-		conditions = append(conditions, fmt.Sprintf("p.surname ILIKE $%d", paramCounter))
-		args = append(args, "%"+filter.Surname+"%")
-		paramCounter++
+		var alternatives []string
+		if !filter.MatchTransliterations {
+			alternatives = append(alternatives, fmt.Sprintf("%s LIKE $%d", c.keyColumn, paramCounter))
+			args = append(args, likePattern(NameKey(c.value)))
+			paramCounter++
+		} else {
+			for _, key := range variantKeys(c.value) {
+				alternatives = append(alternatives, fmt.Sprintf("%s LIKE $%d", c.variantsColumn, paramCounter))
+				args = append(args, likePattern(key))
+				paramCounter++
+			}
+		}
+		if c.expandAliases {
+			alternatives = append(alternatives, aliasGroupCondition(paramCounter))
+			args = append(args, NameKey(c.value))
+			paramCounter++
+		}
+
+		if len(alternatives) == 1 {
+			conditions = append(conditions, alternatives[0])
+		} else {
+			conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+		}
 	}
 
 	if filter.AgeTo > 0 {
@@ -102,6 +261,18 @@ WHERE 1=1`
 		paramCounter++
 	}
 
+	if filter.MinGenderProbability > 0 {
+		conditions = append(conditions, fmt.Sprintf("p.gender_probability >= $%d", paramCounter))
+		args = append(args, filter.MinGenderProbability)
+		paramCounter++
+	}
+
+	if filter.MinNationalityProbability > 0 {
+		conditions = append(conditions, fmt.Sprintf("p.nationality_probability >= $%d", paramCounter))
+		args = append(args, filter.MinNationalityProbability)
+		paramCounter++
+	}
+
 	for _, condition := range conditions {
 		query += " AND " + condition
 	}
@@ -120,18 +291,7 @@ WHERE 1=1`
 	defer rows.Close()
 
 	for rows.Next() {
-		var person Person
-		err = rows.Scan(
-			&person.ID,
-			&person.Name,
-			&person.Surname,
-			&person.Patronymic,
-			&person.Age,
-			&person.Gender.ID,
-			&person.Gender.Name,
-			&person.Nationality.ID,
-			&person.Nationality.Name,
-		)
+		person, err := scanPerson(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
@@ -145,6 +305,113 @@ WHERE 1=1`
 	return persons, nil
 }
 
+// scanPerson reads a row produced by selectPersons.
+func scanPerson(rows *sql.Rows) (Person, error) {
+	var person Person
+	var age, genderID, nationalityID sql.NullInt64
+	var genderName, nationalityName, countryHint sql.NullString
+	var ageSource, genderSource, nationalitySource sql.NullString
+	err := rows.Scan(
+		&person.ID,
+		&person.Name,
+		&person.Surname,
+		&person.Patronymic,
+		&age,
+		&person.AgeCount,
+		&genderID,
+		&genderName,
+		&person.GenderProbability,
+		&nationalityID,
+		&nationalityName,
+		&person.NationalityProbability,
+		&person.EnrichmentStatus.Age,
+		&person.EnrichmentStatus.Gender,
+		&person.EnrichmentStatus.Nationality,
+		&countryHint,
+		&ageSource,
+		&genderSource,
+		&nationalitySource,
+	)
+	if err != nil {
+		return Person{}, err
+	}
+
+	person.CountryHint = countryHint.String
+	person.EnrichmentSource = PersonEnrichmentSource{
+		Age:         ageSource.String,
+		Gender:      genderSource.String,
+		Nationality: nationalitySource.String,
+	}
+	person.Age = nullableInt(age)
+	if genderID.Valid {
+		person.Gender = &Gender{ID: int(genderID.Int64), Name: genderName.String}
+	}
+	if nationalityID.Valid {
+		person.Nationality = &Nationality{ID: int(nationalityID.Int64), Name: nationalityName.String}
+	}
+	return person, nil
+}
+
+// scanPersonColumns reads the id, name, surname, patronymic, age, gender_id
+// and nationality_id columns selected or returned by the write queries.
+func scanPersonColumns(row *sql.Row) (Person, error) {
+	var person Person
+	var age, genderID, nationalityID sql.NullInt64
+	err := row.Scan(
+		&person.ID,
+		&person.Name,
+		&person.Surname,
+		&person.Patronymic,
+		&age,
+		&genderID,
+		&nationalityID,
+	)
+	if err != nil {
+		return Person{}, err
+	}
+
+	person.Age = nullableInt(age)
+	if genderID.Valid {
+		person.Gender = &Gender{ID: int(genderID.Int64)}
+	}
+	if nationalityID.Valid {
+		person.Nationality = &Nationality{ID: int(nationalityID.Int64)}
+	}
+	return person, nil
+}
+
+func nullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// enrichmentStatus returns the statuses to store for p, deriving the ones
+// that are not set from whether the field has a value.
+func (p Person) enrichmentStatus() PersonEnrichment {
+	status := p.EnrichmentStatus
+	if status.Age == "" {
+		status.Age = statusOf(p.Age != nil)
+	}
+	if status.Gender == "" {
+		status.Gender = statusOf(p.Gender != nil)
+	}
+	if status.Nationality == "" {
+		status.Nationality = statusOf(p.Nationality != nil)
+	}
+	return status
+}
+
+// statusOf returns EnrichmentOK when a value is present and EnrichmentUnknown otherwise.
+func statusOf(present bool) EnrichmentStatus {
+	if present {
+		return EnrichmentOK
+	}
+	return EnrichmentUnknown
+}
+
 func DeletePersonByID(ctx context.Context, id uint, db *sql.DB) (int, error) {
 	var deletedId int
 	query := "DELETE FROM persons WHERE id = $1 RETURNING id"
@@ -156,18 +423,9 @@ func DeletePersonByID(ctx context.Context, id uint, db *sql.DB) (int, error) {
 }
 
 func UpdatePerson(ctx context.Context, id uint, patch PersonPatch, db *sql.DB) (Person, error) {
-	var currentPerson Person
-	err := db.QueryRowContext(ctx,
+	currentPerson, err := scanPersonColumns(db.QueryRowContext(ctx,
 		"SELECT id, name, surname, patronymic, age, gender_id, nationality_id FROM persons WHERE id = $1",
-		id).Scan(
-		&currentPerson.ID,
-		&currentPerson.Name,
-		&currentPerson.Surname,
-		&currentPerson.Patronymic,
-		&currentPerson.Age,
-		&currentPerson.Gender.ID,
-		&currentPerson.Nationality.ID,
-	)
+		id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Person{}, fmt.Errorf("record with id=%d not found", id)
@@ -181,16 +439,16 @@ func UpdatePerson(ctx context.Context, id uint, patch PersonPatch, db *sql.DB) (
 	needUpdate := false
 
 	if patch.Name != nil {
-		query += fmt.Sprintf(" name = $%d,", paramCounter)
-		args = append(args, *patch.Name)
-		paramCounter++
+		query += fmt.Sprintf(" name = $%d, name_key = $%d, name_variants = $%d,", paramCounter, paramCounter+1, paramCounter+2)
+		args = append(args, *patch.Name, NameKey(*patch.Name), nameVariants(*patch.Name))
+		paramCounter += 3
 		needUpdate = true
 	}
 
 	if patch.Surname != nil {
-		query += fmt.Sprintf(" surname = $%d,", paramCounter)
-		args = append(args, *patch.Surname)
-		paramCounter++
+		query += fmt.Sprintf(" surname = $%d, surname_key = $%d, surname_variants = $%d,", paramCounter, paramCounter+1, paramCounter+2)
+		args = append(args, *patch.Surname, NameKey(*patch.Surname), nameVariants(*patch.Surname))
+		paramCounter += 3
 		needUpdate = true
 	}
 
@@ -201,8 +459,20 @@ func UpdatePerson(ctx context.Context, id uint, patch PersonPatch, db *sql.DB) (
 		needUpdate = true
 	}
 
+	// A value set without an explicit status counts as known, and without an
+	// explicit source as set by the client. Client values carry no provider
+	// confidence, so the sample count and probability are reset with them.
 	if patch.Age != nil {
 		query += fmt.Sprintf(" age = $%d,", paramCounter)
+		if patch.AgeCount == nil {
+			query += " age_count = 0,"
+		}
+		if patch.AgeStatus == nil {
+			query += fmt.Sprintf(" age_status = '%s',", EnrichmentOK)
+		}
+		if patch.AgeSource == nil {
+			query += fmt.Sprintf(" age_source = '%s',", SourceClient)
+		}
 		args = append(args, *patch.Age)
 		paramCounter++
 		needUpdate = true
@@ -210,6 +480,15 @@ func UpdatePerson(ctx context.Context, id uint, patch PersonPatch, db *sql.DB) (
 
 	if patch.GenderID != nil {
 		query += fmt.Sprintf(" gender_id = $%d,", paramCounter)
+		if patch.GenderProbability == nil {
+			query += " gender_probability = 0,"
+		}
+		if patch.GenderStatus == nil {
+			query += fmt.Sprintf(" gender_status = '%s',", EnrichmentOK)
+		}
+		if patch.GenderSource == nil {
+			query += fmt.Sprintf(" gender_source = '%s',", SourceClient)
+		}
 		args = append(args, *patch.GenderID)
 		paramCounter++
 		needUpdate = true
@@ -217,11 +496,90 @@ func UpdatePerson(ctx context.Context, id uint, patch PersonPatch, db *sql.DB) (
 
 	if patch.NationalityID != nil {
 		query += fmt.Sprintf(" nationality_id = $%d,", paramCounter)
+		if patch.NationalityProbability == nil {
+			query += " nationality_probability = 0,"
+		}
+		if patch.NationalityStatus == nil {
+			query += fmt.Sprintf(" nationality_status = '%s',", EnrichmentOK)
+		}
+		if patch.NationalitySource == nil {
+			query += fmt.Sprintf(" nationality_source = '%s',", SourceClient)
+		}
 		args = append(args, *patch.NationalityID)
 		paramCounter++
 		needUpdate = true
 	}
 
+	if patch.AgeCount != nil {
+		query += fmt.Sprintf(" age_count = $%d,", paramCounter)
+		args = append(args, *patch.AgeCount)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.AgeStatus != nil {
+		query += fmt.Sprintf(" age_status = $%d,", paramCounter)
+		args = append(args, *patch.AgeStatus)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.GenderProbability != nil {
+		query += fmt.Sprintf(" gender_probability = $%d,", paramCounter)
+		args = append(args, *patch.GenderProbability)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.GenderStatus != nil {
+		query += fmt.Sprintf(" gender_status = $%d,", paramCounter)
+		args = append(args, *patch.GenderStatus)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.NationalityProbability != nil {
+		query += fmt.Sprintf(" nationality_probability = $%d,", paramCounter)
+		args = append(args, *patch.NationalityProbability)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.NationalityStatus != nil {
+		query += fmt.Sprintf(" nationality_status = $%d,", paramCounter)
+		args = append(args, *patch.NationalityStatus)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.AgeSource != nil {
+		query += fmt.Sprintf(" age_source = NULLIF($%d, ''),", paramCounter)
+		args = append(args, *patch.AgeSource)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.GenderSource != nil {
+		query += fmt.Sprintf(" gender_source = NULLIF($%d, ''),", paramCounter)
+		args = append(args, *patch.GenderSource)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.NationalitySource != nil {
+		query += fmt.Sprintf(" nationality_source = NULLIF($%d, ''),", paramCounter)
+		args = append(args, *patch.NationalitySource)
+		paramCounter++
+		needUpdate = true
+	}
+
+	if patch.EnrichedAt != nil {
+		query += fmt.Sprintf(" enriched_at = $%d,", paramCounter)
+		args = append(args, *patch.EnrichedAt)
+		paramCounter++
+		needUpdate = true
+	}
+
 	if !needUpdate {
 		return currentPerson, nil
 	}
@@ -230,20 +588,37 @@ func UpdatePerson(ctx context.Context, id uint, patch PersonPatch, db *sql.DB) (
 	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, name, surname, patronymic, age, gender_id, nationality_id", paramCounter)
 	args = append(args, id)
 
-	var updatedPerson Person
-	err = db.QueryRowContext(ctx, query, args...).Scan(
-		&updatedPerson.ID,
-		&updatedPerson.Name,
-		&updatedPerson.Surname,
-		&updatedPerson.Patronymic,
-		&updatedPerson.Age,
-		&updatedPerson.Gender.ID,
-		&updatedPerson.Nationality.ID,
-	)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Person{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updatedPerson, err := scanPersonColumns(tx.QueryRowContext(ctx, query, args...))
 
 	if err != nil {
 		return Person{}, fmt.Errorf("error during update: %w", err)
 	}
+
+	// The provider's ranking and the ensemble breakdown do not describe a
+	// value the client chose.
+	if patch.GenderID != nil && (patch.GenderSource == nil || *patch.GenderSource == SourceClient) {
+		if err = deletePersonProviderPredictions(ctx, tx, id, predictionFieldGender); err != nil {
+			return Person{}, err
+		}
+	}
+	if patch.NationalityID != nil && (patch.NationalitySource == nil || *patch.NationalitySource == SourceClient) {
+		if err = replacePersonNationalityCandidates(ctx, tx, id, nil); err != nil {
+			return Person{}, err
+		}
+		if err = deletePersonProviderPredictions(ctx, tx, id, predictionFieldNationality); err != nil {
+			return Person{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Person{}, fmt.Errorf("error committing update: %w", err)
+	}
 	fullDataPerson, err := GetPersons(ctx, db, PersonFilter{ID: updatedPerson.ID})
 
 	if err != nil {
@@ -257,29 +632,76 @@ func UpdatePerson(ctx context.Context, id uint, patch PersonPatch, db *sql.DB) (
 	return updatedPerson, nil
 }
 
+// CreatePerson inserts a person together with its nationality candidates and
+// per-provider predictions.
 func CreatePerson(ctx context.Context, person Person, db *sql.DB) (Person, error) {
-	var createdPerson Person
-	err := db.QueryRowContext(ctx,
-		"INSERT INTO persons (name, surname, patronymic, age, gender_id, nationality_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, name, surname, patronymic, age, gender_id, nationality_id",
+	return createPerson(ctx, person, db, 0)
+}
+
+// CreateJobPerson is CreatePerson for the create_person job jobID. The person
+// is recorded on the job in the same transaction, so a retried job cannot
+// create it twice: if an earlier attempt already did, nothing is inserted and
+// ErrJobPersonSet is returned.
+func CreateJobPerson(ctx context.Context, jobID int64, person Person, db *sql.DB) (Person, error) {
+	return createPerson(ctx, person, db, jobID)
+}
+
+func createPerson(ctx context.Context, person Person, db *sql.DB, jobID int64) (Person, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Person{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	status := person.enrichmentStatus()
+	createdPerson, err := scanPersonColumns(tx.QueryRowContext(ctx,
+		`INSERT INTO persons (name, surname, patronymic, age, age_count, gender_id, gender_probability, nationality_id, nationality_probability,
+age_status, gender_status, nationality_status, country_hint, age_source, gender_source, nationality_source,
+name_key, surname_key, name_variants, surname_variants)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''), NULLIF($16, ''),
+$17, $18, $19, $20)
+RETURNING id, name, surname, patronymic, age, gender_id, nationality_id`,
 		person.Name,
 		person.Surname,
 		person.Patronymic,
 		person.Age,
-		person.Gender.ID,
-		person.Nationality.ID,
-	).Scan(
-		&createdPerson.ID,
-		&createdPerson.Name,
-		&createdPerson.Surname,
-		&createdPerson.Patronymic,
-		&createdPerson.Age,
-		&createdPerson.Gender.ID,
-		&createdPerson.Nationality.ID,
-	)
+		person.AgeCount,
+		person.genderID(),
+		person.GenderProbability,
+		person.nationalityID(),
+		person.NationalityProbability,
+		status.Age,
+		status.Gender,
+		status.Nationality,
+		person.CountryHint,
+		person.EnrichmentSource.Age,
+		person.EnrichmentSource.Gender,
+		person.EnrichmentSource.Nationality,
+		NameKey(person.Name),
+		NameKey(person.Surname),
+		nameVariants(person.Name),
+		nameVariants(person.Surname),
+	))
 	if err != nil {
 		return Person{}, fmt.Errorf("error inserting person: %w", err)
 	}
 
+	if err = replacePersonNationalityCandidates(ctx, tx, createdPerson.ID, person.NationalityCandidates); err != nil {
+		return Person{}, err
+	}
+	if err = insertPersonProviderPredictions(ctx, tx, createdPerson.ID, person.ProviderPredictions); err != nil {
+		return Person{}, err
+	}
+	if jobID != 0 {
+		if err = setJobPerson(ctx, tx, jobID, createdPerson.ID); err != nil {
+			return Person{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Person{}, fmt.Errorf("error committing person: %w", err)
+	}
+
 	fullDataPerson, err := GetPersons(ctx, db, PersonFilter{ID: createdPerson.ID})
 
 	if err != nil {
@@ -306,40 +728,75 @@ func ReplacePerson(ctx context.Context, person Person, db *sql.DB) (Person, erro
 		return Person{}, fmt.Errorf("person with id=%d not found", person.ID)
 	}
 
-	// Replace the person's data
+	// Replace the person's data, every field that is set counts as known and
+	// as set by the client. The provider sample count and probabilities no
+	// longer describe the values, so they are reset
 	query := `UPDATE persons SET 
 		name = $1, 
 		surname = $2, 
 		patronymic = $3, 
 		age = $4, 
 		gender_id = $5, 
-		nationality_id = $6 
-	WHERE id = $7 
+		nationality_id = $6,
+		age_count = 0,
+		gender_probability = 0,
+		nationality_probability = 0,
+		age_status = $7,
+		gender_status = $8,
+		nationality_status = $9,
+		age_source = NULLIF($10, ''),
+		gender_source = NULLIF($11, ''),
+		nationality_source = NULLIF($12, ''),
+		name_key = $13,
+		surname_key = $14,
+		name_variants = $15,
+		surname_variants = $16
+	WHERE id = $17 
 	RETURNING id, name, surname, patronymic, age, gender_id, nationality_id`
 
-	var updatedPerson Person
-	err = db.QueryRowContext(ctx, query,
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Person{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updatedPerson, err := scanPersonColumns(tx.QueryRowContext(ctx, query,
 		person.Name,
 		person.Surname,
 		person.Patronymic,
 		person.Age,
-		person.Gender.ID,
-		person.Nationality.ID,
+		person.genderID(),
+		person.nationalityID(),
+		statusOf(person.Age != nil),
+		statusOf(person.Gender != nil),
+		statusOf(person.Nationality != nil),
+		sourceOf(person.Age != nil),
+		sourceOf(person.Gender != nil),
+		sourceOf(person.Nationality != nil),
+		NameKey(person.Name),
+		NameKey(person.Surname),
+		nameVariants(person.Name),
+		nameVariants(person.Surname),
 		person.ID,
-	).Scan(
-		&updatedPerson.ID,
-		&updatedPerson.Name,
-		&updatedPerson.Surname,
-		&updatedPerson.Patronymic,
-		&updatedPerson.Age,
-		&updatedPerson.Gender.ID,
-		&updatedPerson.Nationality.ID,
-	)
+	))
 
 	if err != nil {
 		return Person{}, fmt.Errorf("error replacing person: %w", err)
 	}
 
+	// Every value is now the client's or unknown, which neither the provider's
+	// ranking nor the ensemble breakdown describes.
+	if err = replacePersonNationalityCandidates(ctx, tx, person.ID, nil); err != nil {
+		return Person{}, err
+	}
+	if err = deletePersonProviderPredictions(ctx, tx, person.ID, ""); err != nil {
+		return Person{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Person{}, fmt.Errorf("error committing replacement: %w", err)
+	}
+
 	fullDataPerson, err := GetPersons(ctx, db, PersonFilter{ID: updatedPerson.ID})
 
 	if err != nil {
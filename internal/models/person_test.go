@@ -6,7 +6,9 @@ import (
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"reflect"
+	"regexp"
 	"testing"
+	"time"
 )
 
 func TestGetPersons(t *testing.T) {
@@ -24,45 +26,46 @@ func TestGetPersons(t *testing.T) {
 			Name:       "John",
 			Surname:    "Doe",
 			Patronymic: "Smith",
-			Age:        30,
-			Gender: Gender{
+			Age:        intPtr(30),
+			AgeCount:   5000,
+			Gender: &Gender{
 				ID:   1,
 				Name: "Male",
 			},
-			Nationality: Nationality{
+			GenderProbability: 0.99,
+			Nationality: &Nationality{
 				ID:   1,
 				Name: "American",
 			},
+			NationalityProbability: 0.35,
 		},
 		{
 			ID:         2,
 			Name:       "Jane",
 			Surname:    "Smith",
 			Patronymic: "Doe",
-			Age:        25,
-			Gender: Gender{
+			Age:        intPtr(25),
+			AgeCount:   800,
+			Gender: &Gender{
 				ID:   2,
 				Name: "Female",
 			},
-			Nationality: Nationality{
+			GenderProbability: 0.6,
+			Nationality: &Nationality{
 				ID:   2,
 				Name: "Canadian",
 			},
+			NationalityProbability: 0.12,
 		},
 	}
 
 	t.Run("GetAllPersons", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "gender_name", "nationality_id", "nationality_name"})
+		rows := personRows()
 		for _, p := range persons {
-			rows.AddRow(p.ID, p.Name, p.Surname, p.Patronymic, p.Age, p.Gender.ID, p.Gender.Name, p.Nationality.ID, p.Nationality.Name)
+			addPersonRow(rows, p)
 		}
 
-		mock.ExpectQuery(`^SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
-FROM persons p
-LEFT JOIN nationalities n ON n.id = p.nationality_id
-LEFT JOIN genders g ON g.id = p.gender_id
-WHERE 1=1$`).WillReturnRows(rows)
+		mock.ExpectQuery("^" + regexp.QuoteMeta(selectPersons) + `$`).WillReturnRows(rows)
 
 		result, err := GetPersons(ctx, db, PersonFilter{})
 		if err != nil {
@@ -75,18 +78,12 @@ WHERE 1=1$`).WillReturnRows(rows)
 	})
 
 	t.Run("FilterByName", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "gender_name", "nationality_id", "nationality_name"})
-		rows.AddRow(persons[0].ID, persons[0].Name, persons[0].Surname, persons[0].Patronymic, persons[0].Age,
-			persons[0].Gender.ID, persons[0].Gender.Name, persons[0].Nationality.ID, persons[0].Nationality.Name)
-
-		mock.ExpectQuery(`^SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
-FROM persons p
-LEFT JOIN nationalities n ON n.id = p.nationality_id
-LEFT JOIN genders g ON g.id = p.gender_id
-WHERE 1=1 AND p.name ILIKE \$1$`).WithArgs("%John%").WillReturnRows(rows)
-
-		result, err := GetPersons(ctx, db, PersonFilter{Name: "John"})
+		rows := personRows()
+		addPersonRow(rows, persons[0])
+
+		mock.ExpectQuery("^" + regexp.QuoteMeta(selectPersons) + ` AND p.name_key LIKE \$1$`).WithArgs("%john%").WillReturnRows(rows)
+
+		result, err := GetPersons(ctx, db, PersonFilter{Name: " JOHN "})
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -98,16 +95,10 @@ WHERE 1=1 AND p.name ILIKE \$1$`).WithArgs("%John%").WillReturnRows(rows)
 	})
 
 	t.Run("FilterBySurname", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "gender_name", "nationality_id", "nationality_name"})
-		rows.AddRow(persons[1].ID, persons[1].Name, persons[1].Surname, persons[1].Patronymic, persons[1].Age,
-			persons[1].Gender.ID, persons[1].Gender.Name, persons[1].Nationality.ID, persons[1].Nationality.Name)
+		rows := personRows()
+		addPersonRow(rows, persons[1])
 
-		mock.ExpectQuery(`^SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
-FROM persons p
-LEFT JOIN nationalities n ON n.id = p.nationality_id
-LEFT JOIN genders g ON g.id = p.gender_id
-WHERE 1=1 AND p.surname ILIKE \$1$`).WithArgs("%Smith%").WillReturnRows(rows)
+		mock.ExpectQuery("^" + regexp.QuoteMeta(selectPersons) + ` AND p.surname_key LIKE \$1$`).WithArgs("%smith%").WillReturnRows(rows)
 
 		result, err := GetPersons(ctx, db, PersonFilter{Surname: "Smith"})
 		if err != nil {
@@ -120,17 +111,51 @@ WHERE 1=1 AND p.surname ILIKE \$1$`).WithArgs("%Smith%").WillReturnRows(rows)
 		}
 	})
 
+	t.Run("FilterByTransliteration", func(t *testing.T) {
+		rows := personRows()
+		addPersonRow(rows, persons[0])
+
+		mock.ExpectQuery("^"+regexp.QuoteMeta(selectPersons)+
+			` AND \(p.name_variants LIKE \$1 OR p.name_variants LIKE \$2 OR p.name_variants LIKE \$3 OR p.name_variants LIKE \$4 OR p.name_variants LIKE \$5\)$`).
+			WithArgs("%дмитрий%", "%dmitriy%", "%dmitry%", "%dmitrii%", "%dmitrij%").
+			WillReturnRows(rows)
+
+		result, err := GetPersons(ctx, db, PersonFilter{Name: "Дмитрий", MatchTransliterations: true})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := []Person{persons[0]}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
+	t.Run("FilterByAliases", func(t *testing.T) {
+		rows := personRows()
+		addPersonRow(rows, persons[0])
+
+		mock.ExpectQuery("^"+regexp.QuoteMeta(selectPersons)+
+			regexp.QuoteMeta(" AND (p.name_key LIKE $1 OR "+aliasGroupCondition(2)+")")+"$").
+			WithArgs("%sasha%", "sasha").
+			WillReturnRows(rows)
+
+		result, err := GetPersons(ctx, db, PersonFilter{Name: "Sasha", ExpandAliases: true})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := []Person{persons[0]}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
 	t.Run("FilterByAgeRange", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "gender_name", "nationality_id", "nationality_name"})
-		rows.AddRow(persons[1].ID, persons[1].Name, persons[1].Surname, persons[1].Patronymic, persons[1].Age,
-			persons[1].Gender.ID, persons[1].Gender.Name, persons[1].Nationality.ID, persons[1].Nationality.Name)
+		rows := personRows()
+		addPersonRow(rows, persons[1])
 
-		mock.ExpectQuery(`^SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
-FROM persons p
-LEFT JOIN nationalities n ON n.id = p.nationality_id
-LEFT JOIN genders g ON g.id = p.gender_id
-WHERE 1=1 AND p.age <= \$1 AND p.age >= \$2$`).WithArgs(26, 20).WillReturnRows(rows)
+		mock.ExpectQuery("^"+regexp.QuoteMeta(selectPersons)+` AND p.age <= \$1 AND p.age >= \$2$`).WithArgs(26, 20).WillReturnRows(rows)
 
 		result, err := GetPersons(ctx, db, PersonFilter{AgeFrom: 20, AgeTo: 26})
 		if err != nil {
@@ -144,16 +169,10 @@ WHERE 1=1 AND p.age <= \$1 AND p.age >= \$2$`).WithArgs(26, 20).WillReturnRows(r
 	})
 
 	t.Run("FilterByGenderID", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "gender_name", "nationality_id", "nationality_name"})
-		rows.AddRow(persons[0].ID, persons[0].Name, persons[0].Surname, persons[0].Patronymic, persons[0].Age,
-			persons[0].Gender.ID, persons[0].Gender.Name, persons[0].Nationality.ID, persons[0].Nationality.Name)
+		rows := personRows()
+		addPersonRow(rows, persons[0])
 
-		mock.ExpectQuery(`^SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
-FROM persons p
-LEFT JOIN nationalities n ON n.id = p.nationality_id
-LEFT JOIN genders g ON g.id = p.gender_id
-WHERE 1=1 AND p.gender_id = \$1$`).WithArgs(1).WillReturnRows(rows)
+		mock.ExpectQuery("^" + regexp.QuoteMeta(selectPersons) + ` AND p.gender_id = \$1$`).WithArgs(1).WillReturnRows(rows)
 
 		result, err := GetPersons(ctx, db, PersonFilter{GenderID: 1})
 		if err != nil {
@@ -167,16 +186,10 @@ WHERE 1=1 AND p.gender_id = \$1$`).WithArgs(1).WillReturnRows(rows)
 	})
 
 	t.Run("FilterByNationalityID", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "gender_name", "nationality_id", "nationality_name"})
-		rows.AddRow(persons[1].ID, persons[1].Name, persons[1].Surname, persons[1].Patronymic, persons[1].Age,
-			persons[1].Gender.ID, persons[1].Gender.Name, persons[1].Nationality.ID, persons[1].Nationality.Name)
+		rows := personRows()
+		addPersonRow(rows, persons[1])
 
-		mock.ExpectQuery(`^SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
-FROM persons p
-LEFT JOIN nationalities n ON n.id = p.nationality_id
-LEFT JOIN genders g ON g.id = p.gender_id
-WHERE 1=1 AND p.nationality_id = \$1$`).WithArgs(2).WillReturnRows(rows)
+		mock.ExpectQuery("^" + regexp.QuoteMeta(selectPersons) + ` AND p.nationality_id = \$1$`).WithArgs(2).WillReturnRows(rows)
 
 		result, err := GetPersons(ctx, db, PersonFilter{NationalityID: 2})
 		if err != nil {
@@ -189,13 +202,25 @@ WHERE 1=1 AND p.nationality_id = \$1$`).WithArgs(2).WillReturnRows(rows)
 		}
 	})
 
+	t.Run("FilterByMinGenderProbability", func(t *testing.T) {
+		rows := personRows()
+		addPersonRow(rows, persons[0])
+
+		mock.ExpectQuery("^" + regexp.QuoteMeta(selectPersons) + ` AND p.gender_probability >= \$1$`).WithArgs(0.9).WillReturnRows(rows)
+
+		result, err := GetPersons(ctx, db, PersonFilter{MinGenderProbability: 0.9})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := []Person{persons[0]}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
 	t.Run("QueryError", func(t *testing.T) {
-		mock.ExpectQuery(`^SELECT p.id, p.name, p.surname, p.patronymic, p.age, p.gender_id, g.name as gender_name,
-p.nationality_id, n.name as nationality_name
-FROM persons p
-LEFT JOIN nationalities n ON n.id = p.nationality_id
-LEFT JOIN genders g ON g.id = p.gender_id
-WHERE 1=1$`).WillReturnError(errors.New("database connection error"))
+		mock.ExpectQuery("^" + regexp.QuoteMeta(selectPersons) + `$`).WillReturnError(errors.New("database connection error"))
 
 		_, err := GetPersons(ctx, db, PersonFilter{})
 		if err == nil {
@@ -218,25 +243,63 @@ func TestCreatePerson(t *testing.T) {
 			Name:       "Alex",
 			Surname:    "Johnson",
 			Patronymic: "Robert",
-			Age:        35,
-			Gender: Gender{
+			Age:        intPtr(35),
+			AgeCount:   1200,
+			Gender: &Gender{
 				ID: 1,
 			},
-			Nationality: Nationality{
+			GenderProbability: 0.98,
+			Nationality: &Nationality{
 				ID: 1,
 			},
+			NationalityProbability: 0.41,
+			EnrichmentSource: PersonEnrichmentSource{
+				Age:         "offline",
+				Gender:      "genderize",
+				Nationality: "nationalize",
+			},
+			CountryHint: "US",
+			NationalityCandidates: []NationalityCandidate{
+				{CountryID: "US", Probability: 0.41},
+				{CountryID: "IE", Probability: 0.2},
+			},
+			ProviderPredictions: []ProviderPrediction{
+				{Field: "gender", Source: "genderize", Value: "male", Probability: 0.98, Count: 5000},
+			},
 		}
 
 		expectedPerson := person
+		expectedPerson.NationalityCandidates = nil
+		expectedPerson.ProviderPredictions = nil
 		expectedPerson.ID = 3
 
 		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
 			AddRow(expectedPerson.ID, expectedPerson.Name, expectedPerson.Surname, expectedPerson.Patronymic,
-				expectedPerson.Age, expectedPerson.Gender.ID, expectedPerson.Nationality.ID)
+				*expectedPerson.Age, expectedPerson.Gender.ID, expectedPerson.Nationality.ID)
 
+		mock.ExpectBegin()
 		mock.ExpectQuery("INSERT INTO persons").
-			WithArgs(person.Name, person.Surname, person.Patronymic, person.Age, person.Gender.ID, person.Nationality.ID).
+			WithArgs(person.Name, person.Surname, person.Patronymic, *person.Age, person.AgeCount,
+				person.Gender.ID, person.GenderProbability, person.Nationality.ID, person.NationalityProbability,
+				EnrichmentOK, EnrichmentOK, EnrichmentOK, person.CountryHint,
+				person.EnrichmentSource.Age, person.EnrichmentSource.Gender, person.EnrichmentSource.Nationality,
+				NameKey(person.Name), NameKey(person.Surname), nameVariants(person.Name), nameVariants(person.Surname)).
 			WillReturnRows(rows)
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates WHERE person_id = \\$1$").
+			WithArgs(expectedPerson.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		for i, candidate := range person.NationalityCandidates {
+			mock.ExpectExec("^INSERT INTO person_nationality_candidates").
+				WithArgs(expectedPerson.ID, candidate.CountryID, candidate.Probability, i+1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		for _, prediction := range person.ProviderPredictions {
+			mock.ExpectExec("^INSERT INTO person_provider_predictions").
+				WithArgs(expectedPerson.ID, prediction.Field, prediction.Source, prediction.Value, prediction.Probability, prediction.Count).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+		expectPersonReload(mock, expectedPerson)
 
 		result, err := CreatePerson(ctx, person, db)
 		if err != nil {
@@ -244,34 +307,152 @@ func TestCreatePerson(t *testing.T) {
 		}
 
 		if result.ID != expectedPerson.ID || result.Name != expectedPerson.Name ||
-			result.Surname != expectedPerson.Surname || result.Age != expectedPerson.Age {
+			result.Surname != expectedPerson.Surname || *result.Age != *expectedPerson.Age {
 			t.Errorf("Results not matching received: %v, expected: %v", result, expectedPerson)
 		}
 	})
 
+	t.Run("CreateWithUnknownFields", func(t *testing.T) {
+		person := Person{
+			Name:    "Zyx",
+			Surname: "Unknown",
+			Nationality: &Nationality{
+				ID: 4,
+			},
+			NationalityProbability: 0.05,
+			EnrichmentStatus: PersonEnrichment{
+				Age:    EnrichmentUnknown,
+				Gender: EnrichmentFailed,
+			},
+		}
+
+		expectedPerson := person
+		expectedPerson.ID = 4
+		expectedPerson.EnrichmentStatus.Nationality = EnrichmentOK
+
+		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(expectedPerson.ID, expectedPerson.Name, expectedPerson.Surname, expectedPerson.Patronymic,
+				nil, nil, expectedPerson.Nationality.ID)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO persons").
+			WithArgs(person.Name, person.Surname, person.Patronymic, nil, 0,
+				nil, 0.0, person.Nationality.ID, person.NationalityProbability,
+				EnrichmentUnknown, EnrichmentFailed, EnrichmentOK, person.CountryHint, "", "", "", NameKey(person.Name), NameKey(person.Surname), nameVariants(person.Name), nameVariants(person.Surname)).
+			WillReturnRows(rows)
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates WHERE person_id = \\$1$").
+			WithArgs(expectedPerson.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		expectPersonReload(mock, expectedPerson)
+
+		result, err := CreatePerson(ctx, person, db)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, expectedPerson) {
+			t.Errorf("Results not matching received: %+v, expected: %+v", result, expectedPerson)
+		}
+	})
+
 	t.Run("CreateError", func(t *testing.T) {
 		person := Person{
 			Name:       "Error",
 			Surname:    "Test",
 			Patronymic: "",
-			Age:        0,
-			Gender: Gender{
+			Age:        intPtr(0),
+			Gender: &Gender{
 				ID: 0,
 			},
-			Nationality: Nationality{
+			Nationality: &Nationality{
 				ID: 0,
 			},
 		}
 
+		mock.ExpectBegin()
 		mock.ExpectQuery("INSERT INTO persons").
-			WithArgs(person.Name, person.Surname, person.Patronymic, person.Age, person.Gender.ID, person.Nationality.ID).
+			WithArgs(person.Name, person.Surname, person.Patronymic, *person.Age, person.AgeCount,
+				person.Gender.ID, person.GenderProbability, person.Nationality.ID, person.NationalityProbability,
+				EnrichmentOK, EnrichmentOK, EnrichmentOK, person.CountryHint,
+				person.EnrichmentSource.Age, person.EnrichmentSource.Gender, person.EnrichmentSource.Nationality,
+				NameKey(person.Name), NameKey(person.Surname), nameVariants(person.Name), nameVariants(person.Surname)).
 			WillReturnError(errors.New("constraint violation"))
+		mock.ExpectRollback()
 
 		_, err := CreatePerson(ctx, person, db)
 		if err == nil {
 			t.Errorf("Expected error, got nil")
 		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestCreateJobPerson(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	person := Person{
+		Name:    "Alex",
+		Surname: "Johnson",
+		Age:     intPtr(35),
+		EnrichmentStatus: PersonEnrichment{
+			Gender:      EnrichmentUnknown,
+			Nationality: EnrichmentUnknown,
+		},
+	}
+	expectInsert := func(id uint) {
+		rows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(id, person.Name, person.Surname, person.Patronymic, *person.Age, nil, nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO persons").WillReturnRows(rows)
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates WHERE person_id = \\$1$").
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	t.Run("Recorded", func(t *testing.T) {
+		expectInsert(5)
+		mock.ExpectExec("^UPDATE jobs SET person_id = \\$1, updated_at = now\\(\\) WHERE id = \\$2 AND person_id IS NULL$").
+			WithArgs(uint(5), int64(9)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expected := person
+		expected.ID = 5
+		expected.EnrichmentStatus.Age = EnrichmentOK
+		expectPersonReload(mock, expected)
+
+		result, err := CreateJobPerson(ctx, 9, person, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.ID != 5 {
+			t.Errorf("ID = %d, want 5", result.ID)
+		}
 	})
+
+	t.Run("AlreadyCreated", func(t *testing.T) {
+		expectInsert(6)
+		mock.ExpectExec("^UPDATE jobs SET person_id").
+			WithArgs(uint(6), int64(9)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		if _, err := CreateJobPerson(ctx, 9, person, db); !errors.Is(err, ErrJobPersonSet) {
+			t.Errorf("err = %v, want ErrJobPersonSet", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
 }
 
 func TestUpdatePerson(t *testing.T) {
@@ -297,43 +478,206 @@ func TestUpdatePerson(t *testing.T) {
 			Name:       "John",
 			Surname:    "Doe",
 			Patronymic: "Smith",
-			Age:        30,
-			Gender: Gender{
+			Age:        intPtr(30),
+			Gender: &Gender{
 				ID: 1,
 			},
-			Nationality: Nationality{
+			Nationality: &Nationality{
 				ID: 1,
 			},
 		}
 
 		updatedPerson := currentPerson
 		updatedPerson.Name = name
-		updatedPerson.Age = age
+		updatedPerson.Age = &age
 
 		selRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
 			AddRow(currentPerson.ID, currentPerson.Name, currentPerson.Surname, currentPerson.Patronymic,
-				currentPerson.Age, currentPerson.Gender.ID, currentPerson.Nationality.ID)
+				*currentPerson.Age, currentPerson.Gender.ID, currentPerson.Nationality.ID)
 		mock.ExpectQuery("^SELECT id, name, surname, patronymic, age, gender_id, nationality_id FROM persons WHERE id = \\$1$").
 			WithArgs(id).
 			WillReturnRows(selRows)
 
 		updateRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
 			AddRow(updatedPerson.ID, updatedPerson.Name, updatedPerson.Surname, updatedPerson.Patronymic,
-				updatedPerson.Age, updatedPerson.Gender.ID, updatedPerson.Nationality.ID)
-		mock.ExpectQuery("^UPDATE persons SET name = \\$1, age = \\$2 WHERE id = \\$3 RETURNING").
-			WithArgs(name, age, id).
+				*updatedPerson.Age, updatedPerson.Gender.ID, updatedPerson.Nationality.ID)
+		mock.ExpectBegin()
+		mock.ExpectQuery("^UPDATE persons SET name = \\$1, name_key = \\$2, name_variants = \\$3, age = \\$4, age_count = 0, age_status = 'ok', age_source = 'client' WHERE id = \\$5 RETURNING").
+			WithArgs(name, NameKey(name), nameVariants(name), age, id).
 			WillReturnRows(updateRows)
+		mock.ExpectCommit()
+		expectPersonReload(mock, updatedPerson)
 
 		result, err := UpdatePerson(ctx, id, patch, db)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
 
-		if result.ID != updatedPerson.ID || result.Name != updatedPerson.Name || result.Age != updatedPerson.Age {
+		if result.ID != updatedPerson.ID || result.Name != updatedPerson.Name || *result.Age != *updatedPerson.Age {
 			t.Errorf("Results not matching received: %v, expected: %v", result, updatedPerson)
 		}
 	})
 
+	t.Run("EnrichmentUpdate", func(t *testing.T) {
+		id := uint(2)
+		age := 44
+		ageCount := 120
+		known := EnrichmentOK
+		failed := EnrichmentFailed
+		source := "agify"
+		cleared := ""
+		enrichedAt := time.Now()
+		patch := PersonPatch{
+			Age:          &age,
+			AgeCount:     &ageCount,
+			AgeStatus:    &known,
+			GenderStatus: &failed,
+			EnrichedAt:   &enrichedAt,
+			AgeSource:    &source,
+			GenderSource: &cleared,
+		}
+
+		updatedPerson := Person{
+			ID:       id,
+			Name:     "Kai",
+			Surname:  "Doe",
+			Age:      &age,
+			AgeCount: ageCount,
+			EnrichmentStatus: PersonEnrichment{
+				Age:         EnrichmentOK,
+				Gender:      EnrichmentFailed,
+				Nationality: EnrichmentPending,
+			},
+			EnrichmentSource: PersonEnrichmentSource{Age: source},
+		}
+
+		selRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(id, updatedPerson.Name, updatedPerson.Surname, "", nil, nil, nil)
+		mock.ExpectQuery("^SELECT id, name, surname, patronymic, age, gender_id, nationality_id FROM persons WHERE id = \\$1$").
+			WithArgs(id).
+			WillReturnRows(selRows)
+
+		updateRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(id, updatedPerson.Name, updatedPerson.Surname, "", age, nil, nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery("^UPDATE persons SET age = \\$1, age_count = \\$2, age_status = \\$3, gender_status = \\$4, "+
+			"age_source = NULLIF\\(\\$5, ''\\), gender_source = NULLIF\\(\\$6, ''\\), enriched_at = \\$7 WHERE id = \\$8 RETURNING").
+			WithArgs(age, ageCount, EnrichmentOK, EnrichmentFailed, source, cleared, enrichedAt, id).
+			WillReturnRows(updateRows)
+		mock.ExpectCommit()
+		expectPersonReload(mock, updatedPerson)
+
+		result, err := UpdatePerson(ctx, id, patch, db)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, updatedPerson) {
+			t.Errorf("Results not matching received: %+v, expected: %+v", result, updatedPerson)
+		}
+	})
+
+	t.Run("ClientNationality", func(t *testing.T) {
+		id := uint(3)
+		nationalityID := 4
+		patch := PersonPatch{NationalityID: &nationalityID}
+
+		updatedPerson := Person{
+			ID:          id,
+			Name:        "Ivan",
+			Surname:     "Petrov",
+			Nationality: &Nationality{ID: nationalityID, Name: "BG"},
+			EnrichmentStatus: PersonEnrichment{
+				Age:         EnrichmentUnknown,
+				Gender:      EnrichmentUnknown,
+				Nationality: EnrichmentOK,
+			},
+			EnrichmentSource: PersonEnrichmentSource{Nationality: SourceClient},
+		}
+
+		selRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(id, updatedPerson.Name, updatedPerson.Surname, "", nil, nil, 5)
+		mock.ExpectQuery("^SELECT id, name, surname, patronymic, age, gender_id, nationality_id FROM persons WHERE id = \\$1$").
+			WithArgs(id).
+			WillReturnRows(selRows)
+
+		updateRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(id, updatedPerson.Name, updatedPerson.Surname, "", nil, nil, nationalityID)
+		mock.ExpectBegin()
+		mock.ExpectQuery("^UPDATE persons SET nationality_id = \\$1, nationality_probability = 0, nationality_status = 'ok', nationality_source = 'client' WHERE id = \\$2 RETURNING").
+			WithArgs(nationalityID, id).
+			WillReturnRows(updateRows)
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates WHERE person_id = \\$1$").
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("^DELETE FROM person_provider_predictions WHERE person_id = \\$1 AND field = \\$2$").
+			WithArgs(id, "nationality").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		expectPersonReload(mock, updatedPerson)
+
+		result, err := UpdatePerson(ctx, id, patch, db)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, updatedPerson) {
+			t.Errorf("Results not matching received: %+v, expected: %+v", result, updatedPerson)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("ClientGender", func(t *testing.T) {
+		id := uint(5)
+		genderID := 2
+		patch := PersonPatch{GenderID: &genderID}
+
+		updatedPerson := Person{
+			ID:      id,
+			Name:    "Sasha",
+			Surname: "Kim",
+			Gender:  &Gender{ID: genderID, Name: "female"},
+			EnrichmentStatus: PersonEnrichment{
+				Age:         EnrichmentUnknown,
+				Gender:      EnrichmentOK,
+				Nationality: EnrichmentUnknown,
+			},
+			EnrichmentSource: PersonEnrichmentSource{Gender: SourceClient},
+		}
+
+		selRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(id, updatedPerson.Name, updatedPerson.Surname, "", nil, 1, nil)
+		mock.ExpectQuery("^SELECT id, name, surname, patronymic, age, gender_id, nationality_id FROM persons WHERE id = \\$1$").
+			WithArgs(id).
+			WillReturnRows(selRows)
+
+		updateRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
+			AddRow(id, updatedPerson.Name, updatedPerson.Surname, "", nil, genderID, nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery("^UPDATE persons SET gender_id = \\$1, gender_probability = 0, gender_status = 'ok', gender_source = 'client' WHERE id = \\$2 RETURNING").
+			WithArgs(genderID, id).
+			WillReturnRows(updateRows)
+		mock.ExpectExec("^DELETE FROM person_provider_predictions WHERE person_id = \\$1 AND field = \\$2$").
+			WithArgs(id, "gender").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		expectPersonReload(mock, updatedPerson)
+
+		result, err := UpdatePerson(ctx, id, patch, db)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, updatedPerson) {
+			t.Errorf("Results not matching received: %+v, expected: %+v", result, updatedPerson)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
 	t.Run("NoChanges", func(t *testing.T) {
 		id := uint(1)
 		patch := PersonPatch{} // Empty patch
@@ -343,18 +687,18 @@ func TestUpdatePerson(t *testing.T) {
 			Name:       "John",
 			Surname:    "Doe",
 			Patronymic: "Smith",
-			Age:        30,
-			Gender: Gender{
+			Age:        intPtr(30),
+			Gender: &Gender{
 				ID: 1,
 			},
-			Nationality: Nationality{
+			Nationality: &Nationality{
 				ID: 1,
 			},
 		}
 
 		selRows := sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id"}).
 			AddRow(currentPerson.ID, currentPerson.Name, currentPerson.Surname, currentPerson.Patronymic,
-				currentPerson.Age, currentPerson.Gender.ID, currentPerson.Nationality.ID)
+				*currentPerson.Age, currentPerson.Gender.ID, currentPerson.Nationality.ID)
 		mock.ExpectQuery("^SELECT id, name, surname, patronymic, age, gender_id, nationality_id FROM persons WHERE id = \\$1$").
 			WithArgs(id).
 			WillReturnRows(selRows)
@@ -364,7 +708,7 @@ func TestUpdatePerson(t *testing.T) {
 			t.Errorf("Unexpected error: %v", err)
 		}
 
-		if result.ID != currentPerson.ID || result.Name != currentPerson.Name || result.Age != currentPerson.Age {
+		if result.ID != currentPerson.ID || result.Name != currentPerson.Name || *result.Age != *currentPerson.Age {
 			t.Errorf("Results not matching received: %v, expected: %v", result, currentPerson)
 		}
 	})
@@ -398,14 +742,7 @@ func TestDeletePersonByID(t *testing.T) {
 
 	t.Run("SuccessfulDelete", func(t *testing.T) {
 		id := uint(1)
-		deletedPerson := Person{
-			ID:      id,
-			Name:    "John",
-			Surname: "Doe",
-		}
-
-		rows := sqlmock.NewRows([]string{"id", "name", "surname"}).
-			AddRow(deletedPerson.ID, deletedPerson.Name, deletedPerson.Surname)
+		rows := sqlmock.NewRows([]string{"id"}).AddRow(id)
 
 		mock.ExpectQuery("^DELETE FROM persons WHERE id = \\$1").
 			WithArgs(id).
@@ -416,8 +753,8 @@ func TestDeletePersonByID(t *testing.T) {
 			t.Errorf("Unexpected error: %v", err)
 		}
 
-		if result.ID != deletedPerson.ID || result.Name != deletedPerson.Name {
-			t.Errorf("Results not matching received: %v, expected: %v", result, deletedPerson)
+		if uint(result) != id {
+			t.Errorf("Results not matching received: %v, expected: %v", result, id)
 		}
 	})
 
@@ -450,11 +787,11 @@ func TestReplacePerson(t *testing.T) {
 			Name:       "Updated",
 			Surname:    "Person",
 			Patronymic: "Test",
-			Age:        40,
-			Gender: Gender{
+			Age:        intPtr(40),
+			Gender: &Gender{
 				ID: 2,
 			},
-			Nationality: Nationality{
+			Nationality: &Nationality{
 				ID: 3,
 			},
 		}
@@ -468,15 +805,27 @@ func TestReplacePerson(t *testing.T) {
 			"id", "name", "surname", "patronymic", "age", "gender_id", "nationality_id",
 		}).AddRow(
 			person.ID, person.Name, person.Surname, person.Patronymic,
-			person.Age, person.Gender.ID, person.Nationality.ID,
+			*person.Age, person.Gender.ID, person.Nationality.ID,
 		)
 
-		mock.ExpectQuery("UPDATE persons SET").
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE persons SET .*age_count = 0,\s+gender_probability = 0,\s+nationality_probability = 0,`).
 			WithArgs(
 				person.Name, person.Surname, person.Patronymic,
-				person.Age, person.Gender.ID, person.Nationality.ID, person.ID,
+				*person.Age, person.Gender.ID, person.Nationality.ID,
+				EnrichmentOK, EnrichmentOK, EnrichmentOK,
+				SourceClient, SourceClient, SourceClient,
+				NameKey(person.Name), NameKey(person.Surname), nameVariants(person.Name), nameVariants(person.Surname), person.ID,
 			).
 			WillReturnRows(updateRows)
+		mock.ExpectExec("^DELETE FROM person_nationality_candidates WHERE person_id = \\$1$").
+			WithArgs(person.ID).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("^DELETE FROM person_provider_predictions WHERE person_id = \\$1$").
+			WithArgs(person.ID).
+			WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectCommit()
+		expectPersonReload(mock, person)
 
 		result, err := ReplacePerson(ctx, person, db)
 		if err != nil {
@@ -484,7 +833,7 @@ func TestReplacePerson(t *testing.T) {
 		}
 
 		if result.ID != person.ID || result.Name != person.Name ||
-			result.Age != person.Age || result.Gender.ID != person.Gender.ID {
+			*result.Age != *person.Age || result.Gender.ID != person.Gender.ID {
 			t.Errorf("Results not matching - received: %v, expected: %v", result, person)
 		}
 
@@ -545,12 +894,15 @@ func TestReplacePerson(t *testing.T) {
 		mock.ExpectQuery("SELECT EXISTS").WithArgs(person.ID).WillReturnRows(existsRow)
 
 		// Mock update query with error
+		mock.ExpectBegin()
 		mock.ExpectQuery("UPDATE persons SET").
 			WithArgs(
 				person.Name, person.Surname, person.Patronymic,
-				person.Age, person.Gender.ID, person.Nationality.ID, person.ID,
+				nil, nil, nil, EnrichmentUnknown, EnrichmentUnknown, EnrichmentUnknown, "", "", "",
+				NameKey(person.Name), NameKey(person.Surname), nameVariants(person.Name), nameVariants(person.Surname), person.ID,
 			).
 			WillReturnError(errors.New("update error"))
+		mock.ExpectRollback()
 
 		_, err := ReplacePerson(ctx, person, db)
 		if err == nil {
@@ -562,3 +914,67 @@ func TestReplacePerson(t *testing.T) {
 		}
 	})
 }
+
+func personRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "surname", "patronymic", "age", "age_count", "gender_id", "gender_name",
+		"gender_probability", "nationality_id", "nationality_name", "nationality_probability",
+		"age_status", "gender_status", "nationality_status", "country_hint",
+		"age_source", "gender_source", "nationality_source"})
+}
+
+func addPersonRow(rows *sqlmock.Rows, p Person) {
+	var age, genderID, genderName, nationalityID, nationalityName, countryHint interface{}
+	if p.Age != nil {
+		age = *p.Age
+	}
+	if p.Gender != nil {
+		genderID, genderName = p.Gender.ID, p.Gender.Name
+	}
+	if p.Nationality != nil {
+		nationalityID, nationalityName = p.Nationality.ID, p.Nationality.Name
+	}
+	if p.CountryHint != "" {
+		countryHint = p.CountryHint
+	}
+	sources := make([]interface{}, 3)
+	for i, source := range []string{p.EnrichmentSource.Age, p.EnrichmentSource.Gender, p.EnrichmentSource.Nationality} {
+		if source != "" {
+			sources[i] = source
+		}
+	}
+	rows.AddRow(p.ID, p.Name, p.Surname, p.Patronymic, age, p.AgeCount, genderID, genderName,
+		p.GenderProbability, nationalityID, nationalityName, p.NationalityProbability,
+		p.EnrichmentStatus.Age, p.EnrichmentStatus.Gender, p.EnrichmentStatus.Nationality, countryHint,
+		sources[0], sources[1], sources[2])
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// expectPersonReload mocks the GetPersons call that follows every write.
+func expectPersonReload(mock sqlmock.Sqlmock, p Person) {
+	rows := personRows()
+	addPersonRow(rows, p)
+	mock.ExpectQuery(`WHERE 1=1 AND p.id = \$1$`).WithArgs(p.ID).WillReturnRows(rows)
+}
+
+func TestPersonEnrichmentHasPending(t *testing.T) {
+	tests := []struct {
+		name   string
+		status PersonEnrichment
+		want   bool
+	}{
+		{"All ok", PersonEnrichment{Age: EnrichmentOK, Gender: EnrichmentOK, Nationality: EnrichmentOK}, false},
+		{"Unknown and failed", PersonEnrichment{Age: EnrichmentUnknown, Gender: EnrichmentFailed, Nationality: EnrichmentOK}, false},
+		{"Pending nationality", PersonEnrichment{Age: EnrichmentOK, Gender: EnrichmentOK, Nationality: EnrichmentPending}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.status.HasPending(); got != tt.want {
+				t.Errorf("HasPending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ProviderPrediction is what a single provider predicted for a field of a
+// person when an ensemble combined several providers. Value is the gender or
+// a country code; a nationality prediction is stored as one row per country.
+type ProviderPrediction struct {
+	Field       string  `json:"field"`
+	Source      string  `json:"source"`
+	Value       string  `json:"value"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+}
+
+// GetPersonProviderPredictions returns the per-provider predictions of a person
+// ordered by field, source and descending probability.
+func GetPersonProviderPredictions(db *sql.DB, ctx context.Context, personID uint) ([]ProviderPrediction, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT field, source, value, probability, sample_count FROM person_provider_predictions
+WHERE person_id = $1 ORDER BY field, source, probability DESC, value`,
+		personID)
+	if err != nil {
+		return nil, fmt.Errorf("query execution error: %w", err)
+	}
+	defer rows.Close()
+
+	predictions := []ProviderPrediction{}
+	for rows.Next() {
+		var prediction ProviderPrediction
+		if err = rows.Scan(&prediction.Field, &prediction.Source, &prediction.Value, &prediction.Probability, &prediction.Count); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		predictions = append(predictions, prediction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through results: %w", err)
+	}
+
+	return predictions, nil
+}
+
+// Fields of ProviderPrediction, matching the enrichment field names.
+const (
+	predictionFieldGender      = "gender"
+	predictionFieldNationality = "nationality"
+)
+
+// deletePersonProviderPredictions removes the per-provider predictions of a
+// single field of a person inside tx, or of every field when field is empty.
+func deletePersonProviderPredictions(ctx context.Context, tx *sql.Tx, personID uint, field string) error {
+	var err error
+	if field == "" {
+		_, err = tx.ExecContext(ctx, "DELETE FROM person_provider_predictions WHERE person_id = $1", personID)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM person_provider_predictions WHERE person_id = $1 AND field = $2", personID, field)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting provider predictions: %w", err)
+	}
+	return nil
+}
+
+// insertPersonProviderPredictions stores predictions for a person inside tx.
+func insertPersonProviderPredictions(ctx context.Context, tx *sql.Tx, personID uint, predictions []ProviderPrediction) error {
+	for _, prediction := range predictions {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO person_provider_predictions (person_id, field, source, value, probability, sample_count)
+VALUES ($1, $2, $3, $4, $5, $6)`,
+			personID, prediction.Field, prediction.Source, prediction.Value, prediction.Probability, prediction.Count)
+		if err != nil {
+			return fmt.Errorf("error inserting provider prediction: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplacePersonProviderPredictions replaces the per-provider predictions of a
+// single field of a person. An empty predictions slice only removes the old ones.
+func ReplacePersonProviderPredictions(db *sql.DB, ctx context.Context, personID uint, field string, predictions []ProviderPrediction) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = deletePersonProviderPredictions(ctx, tx, personID, field); err != nil {
+		return err
+	}
+	if err = insertPersonProviderPredictions(ctx, tx, personID, predictions); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing provider predictions: %w", err)
+	}
+	return nil
+}
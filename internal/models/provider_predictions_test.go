@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+)
+
+func TestGetPersonProviderPredictions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("Predictions", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"field", "source", "value", "probability", "sample_count"}).
+			AddRow("gender", "genderize", "male", 0.9, 1000).
+			AddRow("gender", "offline", "female", 0.6, 100)
+
+		mock.ExpectQuery("^SELECT field, source, value, probability, sample_count FROM person_provider_predictions").
+			WithArgs(uint(1)).
+			WillReturnRows(rows)
+
+		result, err := GetPersonProviderPredictions(db, ctx, 1)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := []ProviderPrediction{
+			{Field: "gender", Source: "genderize", Value: "male", Probability: 0.9, Count: 1000},
+			{Field: "gender", Source: "offline", Value: "female", Probability: 0.6, Count: 100},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Results not matching received: %v, expected: %v", result, expected)
+		}
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		mock.ExpectQuery("^SELECT field, source, value, probability, sample_count FROM person_provider_predictions").
+			WithArgs(uint(2)).
+			WillReturnError(errors.New("database connection error"))
+
+		_, err := GetPersonProviderPredictions(db, ctx, 2)
+		if err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestReplacePersonProviderPredictions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	predictions := []ProviderPrediction{
+		{Field: "nationality", Source: "nationalize", Value: "US", Probability: 0.6},
+		{Field: "nationality", Source: "offline", Value: "GB", Probability: 0.5},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^DELETE FROM person_provider_predictions WHERE person_id = \\$1 AND field = \\$2$").
+		WithArgs(uint(1), "nationality").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	for _, prediction := range predictions {
+		mock.ExpectExec("^INSERT INTO person_provider_predictions").
+			WithArgs(uint(1), prediction.Field, prediction.Source, prediction.Value, prediction.Probability, prediction.Count).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	if err := ReplacePersonProviderPredictions(db, ctx, 1, "nationality", predictions); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package translit romanizes names written in the Cyrillic script, including
+// the Ukrainian and Kazakh letters. Text in other scripts is left as it is.
+package translit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scheme is a romanization system: a Latin spelling for every Cyrillic
+// letter, with optional spellings for word endings such as "-ий".
+type Scheme struct {
+	Name    string
+	letters map[rune]string
+	endings map[string]string
+}
+
+// Letters shared by the ASCII schemes below; each scheme overrides a few.
+var commonLetters = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// Ukrainian
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+	// Kazakh
+	'ә': "a", 'ғ': "g", 'қ': "k", 'ң': "n", 'ө': "o", 'ұ': "u", 'ү': "u", 'һ': "h",
+}
+
+var (
+	// Popular is the spelling most often seen in practice, close to
+	// BGN/PCGN: "Дмитрий" becomes "Dmitriy", "Юлия" becomes "Yuliya".
+	Popular = Scheme{Name: "popular", letters: with(commonLetters, map[rune]string{'ё': "yo"})}
+
+	// Simple is Popular with the "-ий"/"-ый" endings shortened to "-y", as
+	// in "Dmitry" or "Yury".
+	Simple = Scheme{
+		Name:    "simple",
+		letters: with(commonLetters, map[rune]string{'ё': "yo"}),
+		endings: map[string]string{"ий": "y", "ый": "y"},
+	}
+
+	// GOST is GOST R 52535.1-2006, the ICAO Doc 9303 system used in Russian
+	// passports: "Дмитрий" becomes "Dmitrii", "Юлия" becomes "Iuliia".
+	GOST = Scheme{Name: "gost", letters: with(commonLetters, map[rune]string{
+		'й': "i", 'ъ': "ie", 'ю': "iu", 'я': "ia", 'є': "ie", 'ї': "i",
+	})}
+
+	// ISO9 is ISO 9:1995, identical to GOST 7.79-2000 system A: one Latin
+	// letter, possibly with a diacritic, per Cyrillic letter.
+	ISO9 = Scheme{Name: "iso9", letters: map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "ë", 'ж': "ž", 'з': "z",
+		'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+		'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "c", 'ч': "č", 'ш': "š",
+		'щ': "ŝ", 'ъ': "ʺ", 'ы': "y", 'ь': "ʹ", 'э': "è", 'ю': "û", 'я': "â",
+		'є': "ê", 'і': "ì", 'ї': "ï", 'ґ': "g̀",
+		'ә': "ä", 'ғ': "ġ", 'қ': "ķ", 'ң': "ṇ", 'ө': "ô", 'ұ': "u̇", 'ү': "ù", 'һ': "ḥ",
+	}}
+)
+
+// Schemes lists every scheme, the one used by Latin first.
+var Schemes = []Scheme{Popular, Simple, GOST, ISO9}
+
+// with returns a copy of letters with overrides applied.
+func with(letters, overrides map[rune]string) map[rune]string {
+	merged := make(map[rune]string, len(letters))
+	for r, latin := range letters {
+		merged[r] = latin
+	}
+	for r, latin := range overrides {
+		merged[r] = latin
+	}
+	return merged
+}
+
+// HasCyrillic reports whether text contains a Cyrillic letter.
+func HasCyrillic(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latin romanizes text with the Popular scheme.
+func Latin(text string) string {
+	return Popular.Latin(text)
+}
+
+// Latin romanizes text. The case of every letter is kept: "Жанна" becomes
+// "Zhanna" and "ЖАННА" becomes "ZHANNA". Characters without a spelling in s
+// are copied unchanged.
+func (s Scheme) Latin(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		rest := text[i+size:]
+
+		if latin, n, ok := s.ending(text[i:]); ok {
+			b.WriteString(matchCase(latin, r, rest))
+			i += n
+			continue
+		}
+		latin, ok := s.letters[unicode.ToLower(r)]
+		if !ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteString(matchCase(latin, r, rest))
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// ending returns the spelling of the word ending text starts with, if any,
+// along with its length in bytes.
+func (s Scheme) ending(text string) (string, int, bool) {
+	for cyrillic, latin := range s.endings {
+		if len(text) < len(cyrillic) || !strings.EqualFold(text[:len(cyrillic)], cyrillic) {
+			continue
+		}
+		next, _ := utf8.DecodeRuneInString(text[len(cyrillic):])
+		if len(text) == len(cyrillic) || !unicode.IsLetter(next) {
+			return latin, len(cyrillic), true
+		}
+	}
+	return "", 0, false
+}
+
+// matchCase spells latin in the case of the Cyrillic letter r. A capital
+// followed by another capital is taken for an all-caps word.
+func matchCase(latin string, r rune, rest string) string {
+	if !unicode.IsUpper(r) || latin == "" {
+		return latin
+	}
+	next, _ := utf8.DecodeRuneInString(rest)
+	if unicode.IsUpper(next) {
+		return strings.ToUpper(latin)
+	}
+	first, size := utf8.DecodeRuneInString(latin)
+	return string(unicode.ToUpper(first)) + latin[size:]
+}
+
+// Variants returns the distinct spellings of text in every scheme, or nil
+// when text contains no Cyrillic letter.
+func Variants(text string) []string {
+	if !HasCyrillic(text) {
+		return nil
+	}
+	var variants []string
+	seen := make(map[string]bool)
+	for _, scheme := range Schemes {
+		latin := scheme.Latin(text)
+		if !seen[latin] {
+			seen[latin] = true
+			variants = append(variants, latin)
+		}
+	}
+	return variants
+}
@@ -0,0 +1,48 @@
+package translit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemeLatin(t *testing.T) {
+	tests := []struct {
+		scheme Scheme
+		text   string
+		want   string
+	}{
+		{Popular, "Дмитрий", "Dmitriy"},
+		{Popular, "Юлия Щукина", "Yuliya Shchukina"},
+		{Popular, "ЖАННА", "ZHANNA"},
+		{Popular, "Артём", "Artyom"},
+		{Simple, "Дмитрий", "Dmitry"},
+		{Simple, "Юрий Бельский", "Yury Belsky"},
+		{Simple, "Ийа", "Iya"},
+		{GOST, "Дмитрий", "Dmitrii"},
+		{GOST, "Юлия", "Iuliia"},
+		{ISO9, "Щукина", "Ŝukina"},
+		{ISO9, "Жанна", "Žanna"},
+		{Popular, "Әлихан Құнанбайұлы", "Alikhan Kunanbayuly"},
+		{Popular, "Олексій Їжак", "Oleksiy Yizhak"},
+		{Popular, "John", "John"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme.Name+" "+tt.text, func(t *testing.T) {
+			if got := tt.scheme.Latin(tt.text); got != tt.want {
+				t.Errorf("Latin(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariants(t *testing.T) {
+	want := []string{"Dmitriy", "Dmitry", "Dmitrii", "Dmitrij"}
+	if got := Variants("Дмитрий"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variants() = %q, want %q", got, want)
+	}
+
+	if got := Variants("Dmitry"); got != nil {
+		t.Errorf("Variants() of a Latin name = %q, want nil", got)
+	}
+}
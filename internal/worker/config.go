@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config controls how jobs are claimed, retried and scheduled.
+type Config struct {
+	// Concurrency is the number of pollers claiming jobs on this replica.
+	Concurrency int
+	// BatchSize is the number of jobs claimed at once. Jobs of the same kind
+	// are handled together, so provider lookups can be batched.
+	BatchSize int
+	// PollInterval is how long an idle poller waits before looking for jobs again.
+	PollInterval time.Duration
+	// LockTimeout is how long a running job may go without finishing before
+	// another worker assumes it was abandoned and claims it.
+	LockTimeout time.Duration
+	// JobTimeout bounds a single job attempt.
+	JobTimeout time.Duration
+	// BackoffBase and BackoffMax bound the exponential retry delay.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// SweepInterval is how often persons needing re-enrichment are queued.
+	SweepInterval time.Duration
+	// StaleAfter is the age after which an enrichment is refreshed. Zero disables it.
+	StaleAfter time.Duration
+	// RetryFailedAfter is how long fields whose retries gave up wait before
+	// being tried again.
+	RetryFailedAfter time.Duration
+}
+
+// DefaultConfig returns the settings used when nothing is configured.
+func DefaultConfig() Config {
+	return Config{
+		Concurrency:      2,
+		BatchSize:        10, // the providers' name[] limit
+		PollInterval:     time.Second,
+		LockTimeout:      5 * time.Minute,
+		JobTimeout:       time.Minute,
+		BackoffBase:      30 * time.Second,
+		BackoffMax:       time.Hour,
+		SweepInterval:    time.Minute,
+		StaleAfter:       90 * 24 * time.Hour,
+		RetryFailedAfter: 24 * time.Hour,
+	}
+}
+
+// LoadConfig reads the worker settings from the environment: WORKER_CONCURRENCY
+// (0 disables the worker), WORKER_BATCH_SIZE, WORKER_POLL_INTERVAL,
+// WORKER_BACKOFF_BASE, WORKER_BACKOFF_MAX, WORKER_SWEEP_INTERVAL,
+// ENRICH_STALE_AFTER and ENRICH_RETRY_FAILED_AFTER. Durations use Go syntax such as "30s".
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	if concurrencyStr := os.Getenv("WORKER_CONCURRENCY"); concurrencyStr != "" {
+		concurrency, err := strconv.Atoi(concurrencyStr)
+		if err != nil || concurrency < 0 {
+			return Config{}, fmt.Errorf("invalid WORKER_CONCURRENCY %q", concurrencyStr)
+		}
+		cfg.Concurrency = concurrency
+	}
+
+	if batchSizeStr := os.Getenv("WORKER_BATCH_SIZE"); batchSizeStr != "" {
+		batchSize, err := strconv.Atoi(batchSizeStr)
+		if err != nil || batchSize <= 0 {
+			return Config{}, fmt.Errorf("invalid WORKER_BATCH_SIZE %q", batchSizeStr)
+		}
+		cfg.BatchSize = batchSize
+	}
+
+	durations := []struct {
+		env       string
+		value     *time.Duration
+		allowZero bool
+	}{
+		{"WORKER_POLL_INTERVAL", &cfg.PollInterval, false},
+		{"WORKER_BACKOFF_BASE", &cfg.BackoffBase, false},
+		{"WORKER_BACKOFF_MAX", &cfg.BackoffMax, false},
+		{"WORKER_SWEEP_INTERVAL", &cfg.SweepInterval, false},
+		{"ENRICH_STALE_AFTER", &cfg.StaleAfter, true},
+		{"ENRICH_RETRY_FAILED_AFTER", &cfg.RetryFailedAfter, false},
+	}
+	for _, d := range durations {
+		durationStr := os.Getenv(d.env)
+		if durationStr == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil || duration < 0 || (duration == 0 && !d.allowZero) {
+			return Config{}, fmt.Errorf("invalid %s %q", d.env, durationStr)
+		}
+		*d.value = duration
+	}
+
+	return cfg, nil
+}
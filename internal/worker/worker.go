@@ -0,0 +1,247 @@
+// Package worker runs background jobs stored in the jobs table.
+package worker
+
+import (
+	"NameEnricher/internal/models"
+	"NameEnricher/pkg/logger"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Handler processes a single claimed job. Returning an error schedules a
+// retry with exponential backoff until the job runs out of attempts, unless
+// the error is marked Permanent.
+type Handler func(ctx context.Context, job models.Job) error
+
+// BatchHandler processes several claimed jobs of the same kind at once, so
+// their provider lookups can share requests. It returns one error per job.
+type BatchHandler func(ctx context.Context, jobs []models.Job) []error
+
+// Worker claims jobs from the database and dispatches them to the handler
+// registered for their kind.
+type Worker struct {
+	DB       *sql.DB
+	Config   Config
+	handlers map[string]BatchHandler
+	tasks    []task
+}
+
+type task struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+func New(db *sql.DB, cfg Config) *Worker {
+	return &Worker{DB: db, Config: cfg, handlers: make(map[string]BatchHandler)}
+}
+
+// Handle registers the handler for jobs of kind, called once per job.
+func (w *Worker) Handle(kind string, handler Handler) {
+	w.handlers[kind] = func(ctx context.Context, jobs []models.Job) []error {
+		errs := make([]error, len(jobs))
+		for i, job := range jobs {
+			errs[i] = handler(ctx, job)
+		}
+		return errs
+	}
+}
+
+// HandleBatch registers the handler for jobs of kind, called with every job
+// of that kind claimed together.
+func (w *Worker) HandleBatch(kind string, handler BatchHandler) {
+	w.handlers[kind] = handler
+}
+
+// Every runs fn every interval while the worker is running, for example to
+// enqueue jobs. Failures are logged and retried on the next tick.
+func (w *Worker) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	w.tasks = append(w.tasks, task{name: name, interval: interval, run: fn})
+}
+
+// Run processes jobs until ctx is cancelled. It then stops claiming new jobs,
+// waits for the jobs in progress to finish and returns.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < w.Config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.poll(ctx)
+		}()
+	}
+
+	for _, t := range w.tasks {
+		wg.Add(1)
+		go func(t task) {
+			defer wg.Done()
+			w.repeat(ctx, t)
+		}(t)
+	}
+
+	logger.Log.Infof("Worker started with %d pollers", w.Config.Concurrency)
+	wg.Wait()
+	logger.Log.Info("Worker stopped")
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	for {
+		processed, err := w.ProcessNext(ctx)
+		if err != nil {
+			logger.Log.Errorf("Failed to process jobs: %v", err)
+		}
+		if processed > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.Config.PollInterval):
+		}
+	}
+}
+
+func (w *Worker) repeat(ctx context.Context, t task) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.run(ctx); err != nil && ctx.Err() == nil {
+			logger.Log.Errorf("Worker task %s failed: %v", t.name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessNext claims up to Config.BatchSize due jobs, runs them grouped by
+// kind and returns how many were claimed. Claimed jobs run to completion even
+// if ctx is cancelled meanwhile, bounded by Config.JobTimeout.
+func (w *Worker) ProcessNext(ctx context.Context) (int, error) {
+	if ctx.Err() != nil {
+		return 0, nil
+	}
+
+	jobs, err := models.ClaimJobs(w.DB, ctx, w.Config.LockTimeout, w.Config.BatchSize)
+	if err != nil || len(jobs) == 0 {
+		return 0, err
+	}
+	logger.Log.Debugf("Claimed %d jobs", len(jobs))
+
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.Config.JobTimeout)
+	defer cancel()
+
+	var kinds []string
+	byKind := make(map[string][]models.Job)
+	for _, job := range jobs {
+		if _, ok := byKind[job.Kind]; !ok {
+			kinds = append(kinds, job.Kind)
+		}
+		byKind[job.Kind] = append(byKind[job.Kind], job)
+	}
+
+	var finishErrs []error
+	for _, kind := range kinds {
+		kindJobs := byKind[kind]
+
+		handler, ok := w.handlers[kind]
+		if !ok {
+			finishCtx, cancelFinish := finishContext(ctx)
+			for _, job := range kindJobs {
+				if err := models.FailJob(w.DB, finishCtx, job.ID, fmt.Sprintf("no handler for job kind %q", job.Kind)); err != nil {
+					finishErrs = append(finishErrs, err)
+				}
+			}
+			cancelFinish()
+			continue
+		}
+
+		errs := handler(jobCtx, kindJobs)
+		finishCtx, cancelFinish := finishContext(ctx)
+		for i, job := range kindJobs {
+			if err := w.finish(finishCtx, job, errs[i]); err != nil {
+				finishErrs = append(finishErrs, err)
+			}
+		}
+		cancelFinish()
+	}
+
+	return len(jobs), errors.Join(finishErrs...)
+}
+
+// finishTimeout bounds recording the outcome of the jobs of one kind.
+const finishTimeout = 10 * time.Second
+
+// finishContext returns the context for recording job outcomes. It is not
+// derived from the job context, so a handler that used up Config.JobTimeout
+// still gets its jobs finished instead of leaving them running until
+// Config.LockTimeout.
+func finishContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+}
+
+// permanentError is an error that retrying the job cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that retrying cannot fix, such as a name the
+// providers rejected, so the job fails at once instead of being retried.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// finish records the outcome of a job attempt.
+func (w *Worker) finish(ctx context.Context, job models.Job, err error) error {
+	if err == nil {
+		logger.Log.Debugf("Job %d done", job.ID)
+		return models.CompleteJob(w.DB, ctx, job.ID)
+	}
+
+	if job.LastAttempt() {
+		logger.Log.Errorf("Job %d failed after %d attempts: %v", job.ID, job.Attempts, err)
+		return models.FailJob(w.DB, ctx, job.ID, err.Error())
+	}
+	if errors.As(err, new(permanentError)) {
+		logger.Log.Errorf("Job %d failed permanently: %v", job.ID, err)
+		return models.FailJob(w.DB, ctx, job.ID, err.Error())
+	}
+
+	delay := Backoff(job.Attempts, w.Config.BackoffBase, w.Config.BackoffMax)
+	// Errors such as an exhausted provider quota know when a retry can succeed.
+	var retryAfter interface{ RetryAfter() time.Duration }
+	if errors.As(err, &retryAfter) {
+		delay = max(delay, retryAfter.RetryAfter())
+	}
+	logger.Log.Warnf("Job %d failed, retrying in %s: %v", job.ID, delay, err)
+	return models.RetryJob(w.DB, ctx, job.ID, time.Now().Add(delay), err.Error())
+}
+
+// Backoff returns the delay before retrying after the given attempt:
+// base, 2*base, 4*base, ... capped at max.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
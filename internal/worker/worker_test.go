@@ -0,0 +1,217 @@
+package worker
+
+import (
+	"NameEnricher/internal/models"
+	"NameEnricher/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init()
+
+	exitCode := m.Run()
+
+	os.Exit(exitCode)
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 10 * time.Second},
+		{40, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt, time.Second, 10*time.Second); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("FromEnvironment", func(t *testing.T) {
+		t.Setenv("WORKER_CONCURRENCY", "4")
+		t.Setenv("WORKER_BACKOFF_BASE", "5s")
+		t.Setenv("ENRICH_STALE_AFTER", "0")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.Concurrency != 4 || cfg.BackoffBase != 5*time.Second || cfg.StaleAfter != 0 {
+			t.Errorf("Unexpected config: %+v", cfg)
+		}
+		if cfg.PollInterval != DefaultConfig().PollInterval {
+			t.Errorf("PollInterval = %v, want default", cfg.PollInterval)
+		}
+	})
+
+	t.Run("InvalidDuration", func(t *testing.T) {
+		t.Setenv("WORKER_POLL_INTERVAL", "0")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestProcessNext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer db.Close()
+
+	cfg := DefaultConfig()
+	cfg.BackoffBase = time.Minute
+	w := New(db, cfg)
+
+	failing := true
+	w.Handle(models.JobKindReEnrich, func(ctx context.Context, job models.Job) error {
+		if job.PersonID != 7 {
+			t.Errorf("PersonID = %d, want 7", job.PersonID)
+		}
+		if failing {
+			return errors.New("provider down")
+		}
+		return nil
+	})
+
+	columns := []string{"id", "kind", "person_id", "status", "attempts", "max_attempts", "run_at", "last_error", "created_at", "updated_at", "payload"}
+	expectClaim := func(attempts int) {
+		now := time.Now()
+		mock.ExpectQuery("^UPDATE jobs SET status = 'running'").
+			WithArgs(sqlmock.AnyArg(), cfg.BatchSize).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, models.JobKindReEnrich, 7, models.JobRunning, attempts, 3, now, nil, now, now, nil))
+	}
+
+	t.Run("Retry", func(t *testing.T) {
+		expectClaim(1)
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(models.JobQueued, sqlmock.AnyArg(), "provider down", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := w.ProcessNext(context.Background())
+		if err != nil || processed != 1 {
+			t.Errorf("ProcessNext() = %v, %v; want 1, nil", processed, err)
+		}
+	})
+
+	t.Run("LastAttemptFails", func(t *testing.T) {
+		expectClaim(3)
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(models.JobFailed, sqlmock.AnyArg(), "provider down", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := w.ProcessNext(context.Background())
+		if err != nil || processed != 1 {
+			t.Errorf("ProcessNext() = %v, %v; want 1, nil", processed, err)
+		}
+	})
+
+	t.Run("PermanentFails", func(t *testing.T) {
+		permanent := New(db, cfg)
+		permanent.Handle(models.JobKindReEnrich, func(ctx context.Context, job models.Job) error {
+			return fmt.Errorf("creating person: %w", Permanent(errors.New("name rejected")))
+		})
+
+		expectClaim(1)
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(models.JobFailed, sqlmock.AnyArg(), "creating person: name rejected", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := permanent.ProcessNext(context.Background())
+		if err != nil || processed != 1 {
+			t.Errorf("ProcessNext() = %v, %v; want 1, nil", processed, err)
+		}
+	})
+
+	t.Run("Done", func(t *testing.T) {
+		failing = false
+		expectClaim(2)
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(models.JobDone, sqlmock.AnyArg(), "", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := w.ProcessNext(context.Background())
+		if err != nil || processed != 1 {
+			t.Errorf("ProcessNext() = %v, %v; want 1, nil", processed, err)
+		}
+	})
+
+	t.Run("Idle", func(t *testing.T) {
+		mock.ExpectQuery("^UPDATE jobs SET status = 'running'").
+			WithArgs(sqlmock.AnyArg(), cfg.BatchSize).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		processed, err := w.ProcessNext(context.Background())
+		if err != nil || processed != 0 {
+			t.Errorf("ProcessNext() = %v, %v; want 0, nil", processed, err)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		var batchSizes []int
+		w.HandleBatch("batched", func(ctx context.Context, jobs []models.Job) []error {
+			batchSizes = append(batchSizes, len(jobs))
+			return []error{nil, errors.New("provider down")}
+		})
+
+		now := time.Now()
+		mock.ExpectQuery("^UPDATE jobs SET status = 'running'").
+			WithArgs(sqlmock.AnyArg(), cfg.BatchSize).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(2, "batched", 8, models.JobRunning, 1, 3, now, nil, now, now, nil).
+				AddRow(3, "batched", 9, models.JobRunning, 1, 3, now, nil, now, now, nil))
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(models.JobDone, sqlmock.AnyArg(), "", int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(models.JobQueued, sqlmock.AnyArg(), "provider down", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := w.ProcessNext(context.Background())
+		if err != nil || processed != 2 {
+			t.Errorf("ProcessNext() = %v, %v; want 2, nil", processed, err)
+		}
+		if len(batchSizes) != 1 || batchSizes[0] != 2 {
+			t.Errorf("Batch handler calls = %v, want one call with 2 jobs", batchSizes)
+		}
+	})
+
+	t.Run("FinishAfterTimeout", func(t *testing.T) {
+		slowCfg := cfg
+		slowCfg.JobTimeout = 10 * time.Millisecond
+		slow := New(db, slowCfg)
+		slow.Handle(models.JobKindReEnrich, func(ctx context.Context, job models.Job) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		expectClaim(1)
+		mock.ExpectExec("^UPDATE jobs SET status").
+			WithArgs(models.JobQueued, sqlmock.AnyArg(), context.DeadlineExceeded.Error(), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := slow.ProcessNext(context.Background())
+		if err != nil || processed != 1 {
+			t.Errorf("ProcessNext() = %v, %v; want 1, nil", processed, err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
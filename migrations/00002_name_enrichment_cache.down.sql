@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_name_enrichment_cache_expires_at;
+
+DROP TABLE IF EXISTS name_enrichment_cache;
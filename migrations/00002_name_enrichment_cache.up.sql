@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS name_enrichment_cache
+(
+    name        TEXT PRIMARY KEY,
+    age         JSONB       NOT NULL,
+    gender      JSONB       NOT NULL,
+    nationality JSONB       NOT NULL,
+    fetched_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_name_enrichment_cache_expires_at ON name_enrichment_cache (expires_at);
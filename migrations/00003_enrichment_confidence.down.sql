@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_persons_nationality_probability;
+DROP INDEX IF EXISTS idx_persons_gender_probability;
+
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS nationality_probability,
+    DROP COLUMN IF EXISTS gender_probability,
+    DROP COLUMN IF EXISTS age_count;
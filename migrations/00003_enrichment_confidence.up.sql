@@ -0,0 +1,10 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS age_count               INT              NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS gender_probability      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS nationality_probability DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_persons_gender_probability ON persons (gender_probability);
+CREATE INDEX idx_persons_nationality_probability ON persons (nationality_probability);
+
+-- Cached results were stored without probabilities and sample counts.
+DELETE FROM name_enrichment_cache;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_person_nationality_candidates_country_id;
+
+DROP TABLE IF EXISTS person_nationality_candidates;
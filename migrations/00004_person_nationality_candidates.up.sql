@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS person_nationality_candidates
+(
+    person_id   INT              NOT NULL REFERENCES persons (id) ON DELETE CASCADE,
+    country_id  TEXT             NOT NULL,
+    probability DOUBLE PRECISION NOT NULL,
+    rank        INT              NOT NULL,
+    PRIMARY KEY (person_id, country_id)
+);
+
+CREATE INDEX idx_person_nationality_candidates_country_id ON person_nationality_candidates (country_id);
+
+-- Cached results were stored without the full country distribution.
+DELETE FROM name_enrichment_cache;
@@ -0,0 +1,26 @@
+INSERT INTO genders (name)
+SELECT ''
+WHERE EXISTS (SELECT 1 FROM persons WHERE gender_id IS NULL)
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE persons SET gender_id = (SELECT id FROM genders WHERE name = '') WHERE gender_id IS NULL;
+UPDATE persons SET age = 0 WHERE age IS NULL;
+INSERT INTO nationalities (name)
+SELECT ''
+WHERE EXISTS (SELECT 1 FROM persons WHERE nationality_id IS NULL)
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE persons SET nationality_id = (SELECT id FROM nationalities WHERE name = '') WHERE nationality_id IS NULL;
+
+ALTER TABLE persons
+    DROP CONSTRAINT IF EXISTS persons_nationality_status_check,
+    DROP CONSTRAINT IF EXISTS persons_gender_status_check,
+    DROP CONSTRAINT IF EXISTS persons_age_status_check,
+    DROP COLUMN IF EXISTS nationality_status,
+    DROP COLUMN IF EXISTS gender_status,
+    DROP COLUMN IF EXISTS age_status;
+
+ALTER TABLE persons
+    ALTER COLUMN age SET NOT NULL,
+    ALTER COLUMN gender_id SET NOT NULL,
+    ALTER COLUMN nationality_id SET NOT NULL;
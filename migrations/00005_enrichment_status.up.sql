@@ -0,0 +1,33 @@
+ALTER TABLE persons
+    ALTER COLUMN age DROP NOT NULL,
+    ALTER COLUMN gender_id DROP NOT NULL,
+    ALTER COLUMN nationality_id DROP NOT NULL;
+
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS age_status         TEXT NOT NULL DEFAULT 'ok',
+    ADD COLUMN IF NOT EXISTS gender_status      TEXT NOT NULL DEFAULT 'ok',
+    ADD COLUMN IF NOT EXISTS nationality_status TEXT NOT NULL DEFAULT 'ok',
+    ADD CONSTRAINT persons_age_status_check CHECK (age_status IN ('ok', 'unknown', 'failed')),
+    ADD CONSTRAINT persons_gender_status_check CHECK (gender_status IN ('ok', 'unknown', 'failed')),
+    ADD CONSTRAINT persons_nationality_status_check CHECK (nationality_status IN ('ok', 'unknown', 'failed'));
+
+-- Null provider results used to be stored as age 0 and an empty-named gender.
+-- Rolling back also stores unknown nationalities as an empty-named one.
+UPDATE persons
+SET age        = NULL,
+    age_status = 'unknown'
+WHERE age = 0
+  AND age_count = 0;
+
+UPDATE persons
+SET gender_id     = NULL,
+    gender_status = 'unknown'
+WHERE gender_id IN (SELECT id FROM genders WHERE name = '');
+
+UPDATE persons
+SET nationality_id     = NULL,
+    nationality_status = 'unknown'
+WHERE nationality_id IN (SELECT id FROM nationalities WHERE name = '');
+
+DELETE FROM genders WHERE name = '';
+DELETE FROM nationalities WHERE name = '';
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_persons_enrichment_pending;
+
+UPDATE persons SET age_status = 'failed' WHERE age_status = 'pending';
+UPDATE persons SET gender_status = 'failed' WHERE gender_status = 'pending';
+UPDATE persons SET nationality_status = 'failed' WHERE nationality_status = 'pending';
+
+ALTER TABLE persons
+    DROP CONSTRAINT IF EXISTS persons_age_status_check,
+    DROP CONSTRAINT IF EXISTS persons_gender_status_check,
+    DROP CONSTRAINT IF EXISTS persons_nationality_status_check,
+    ADD CONSTRAINT persons_age_status_check CHECK (age_status IN ('ok', 'unknown', 'failed')),
+    ADD CONSTRAINT persons_gender_status_check CHECK (gender_status IN ('ok', 'unknown', 'failed')),
+    ADD CONSTRAINT persons_nationality_status_check CHECK (nationality_status IN ('ok', 'unknown', 'failed'));
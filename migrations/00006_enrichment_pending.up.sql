@@ -0,0 +1,10 @@
+ALTER TABLE persons
+    DROP CONSTRAINT IF EXISTS persons_age_status_check,
+    DROP CONSTRAINT IF EXISTS persons_gender_status_check,
+    DROP CONSTRAINT IF EXISTS persons_nationality_status_check,
+    ADD CONSTRAINT persons_age_status_check CHECK (age_status IN ('ok', 'unknown', 'pending', 'failed')),
+    ADD CONSTRAINT persons_gender_status_check CHECK (gender_status IN ('ok', 'unknown', 'pending', 'failed')),
+    ADD CONSTRAINT persons_nationality_status_check CHECK (nationality_status IN ('ok', 'unknown', 'pending', 'failed'));
+
+CREATE INDEX IF NOT EXISTS idx_persons_enrichment_pending ON persons (id)
+    WHERE age_status = 'pending' OR gender_status = 'pending' OR nationality_status = 'pending';
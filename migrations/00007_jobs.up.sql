@@ -0,0 +1,24 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS enriched_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_persons_enriched_at ON persons (enriched_at);
+
+CREATE TABLE IF NOT EXISTS jobs
+(
+    id           BIGSERIAL PRIMARY KEY,
+    kind         TEXT        NOT NULL,
+    person_id    INT         NOT NULL REFERENCES persons (id) ON DELETE CASCADE,
+    status       TEXT        NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'done', 'failed')),
+    attempts     INT         NOT NULL DEFAULT 0,
+    max_attempts INT         NOT NULL DEFAULT 5,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_at    TIMESTAMPTZ,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- At most one open job of a kind per person.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_open ON jobs (kind, person_id) WHERE status IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS idx_jobs_queued_run_at ON jobs (run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_jobs_person_id ON jobs (person_id);
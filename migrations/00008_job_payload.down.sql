@@ -0,0 +1,5 @@
+DELETE FROM jobs WHERE person_id IS NULL;
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS payload,
+    ALTER COLUMN person_id SET NOT NULL;
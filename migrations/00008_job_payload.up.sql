@@ -0,0 +1,3 @@
+ALTER TABLE jobs
+    ALTER COLUMN person_id DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS payload JSONB;
@@ -0,0 +1,2 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS country_hint TEXT CHECK (country_hint ~ '^[A-Z]{2}$');
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS person_provider_predictions
+(
+    person_id    INT              NOT NULL REFERENCES persons (id) ON DELETE CASCADE,
+    field        TEXT             NOT NULL,
+    source       TEXT             NOT NULL,
+    value        TEXT             NOT NULL,
+    probability  DOUBLE PRECISION NOT NULL,
+    sample_count INT              NOT NULL DEFAULT 0,
+    PRIMARY KEY (person_id, field, source, value)
+);
+
+-- Cached results were stored without the per-provider breakdown.
+DELETE FROM name_enrichment_cache;
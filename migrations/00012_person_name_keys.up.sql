@@ -0,0 +1,6 @@
+-- The Unicode-normalized, case-folded names, see models.NameKey. NULL marks
+-- rows written before this migration; SQL cannot fold names the way the
+-- service does, so the service fills them in at startup.
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS name_key TEXT,
+    ADD COLUMN IF NOT EXISTS surname_key TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS name_variants,
+    DROP COLUMN IF EXISTS surname_variants;
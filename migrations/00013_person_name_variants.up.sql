@@ -0,0 +1,6 @@
+-- The keys of the name and of its transliterations, separated by newlines.
+-- NULL marks rows written before this migration; the service fills them in
+-- at startup.
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS name_variants TEXT,
+    ADD COLUMN IF NOT EXISTS surname_variants TEXT;
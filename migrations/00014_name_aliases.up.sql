@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS name_aliases
+(
+    id            SERIAL PRIMARY KEY,
+    alias         TEXT NOT NULL,
+    canonical     TEXT NOT NULL,
+    -- Keys as computed by the service, see models.NameKey.
+    alias_key     TEXT NOT NULL UNIQUE,
+    canonical_key TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_name_aliases_canonical_key ON name_aliases (canonical_key);